@@ -0,0 +1,41 @@
+package console
+
+import (
+	"app/app/modules/audit"
+	"app/config"
+	"app/internal/cmd"
+	"app/internal/logger"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func purgeAuditLogsCmd() *cobra.Command {
+	var days int
+	cmd := &cobra.Command{
+		Use:   "purge-audit-logs",
+		Short: "Delete audit log entries older than the retention period",
+		Args:  cmd.NotReqArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if days == 0 {
+				days = viper.GetInt("AUDIT_RETENTION_DAYS")
+			}
+			if days < 1 {
+				logger.Errf("retention must be at least one day")
+				os.Exit(1)
+			}
+			before := time.Now().AddDate(0, 0, -days)
+			svc := audit.NewService(config.GetDB())
+			deleted, err := svc.Purge(cmd.Context(), before)
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("%d audit log entries before %s deleted", deleted, before.Format(time.DateOnly))
+		},
+	}
+	cmd.Flags().IntVar(&days, "days", 0, "retention in days (default AUDIT_RETENTION_DAYS)")
+	return cmd
+}
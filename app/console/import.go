@@ -0,0 +1,120 @@
+package console
+
+import (
+	"app/app/model"
+	"app/app/modules/importer"
+	importerdto "app/app/modules/importer/dto"
+	"app/app/modules/mpi"
+	"app/app/modules/patient"
+	"app/app/util/fieldcrypt"
+	"app/app/util/hn"
+	"app/config"
+	"app/internal/logger"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func importPatientsCmd() *cobra.Command {
+	var hospital, report string
+	req := new(importerdto.CreateImportRequest)
+	cmd := &cobra.Command{
+		Use:   "import-patients [file]",
+		Short: "Import patients of a hospital from a CSV file or a FHIR bundle",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			file := args[0]
+			if hospital == "" {
+				logger.Errf("hospital is required")
+				os.Exit(1)
+			}
+			if req.Format == "" {
+				req.Format = importerdto.FormatCSV
+				if ext := strings.ToLower(filepath.Ext(file)); ext == ".json" || ext == ".ndjson" {
+					req.Format = importerdto.FormatFHIR
+				}
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			hnFormat, err := hn.Load()
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			keyring, err := fieldcrypt.Load()
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			fieldcrypt.Use(keyring)
+
+			db := config.GetDB()
+			patients := patient.NewService(db, nil, hnFormat)
+			patients.Links = mpi.NewService(db, mpi.DenyAll{})
+			svc := importer.NewService(db, patients)
+			job, rows, err := svc.Create(cmd.Context(), hospital, "", req, filepath.Base(file), data)
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("Import %s: %d rows", job.ID, job.Total)
+			if err := svc.Run(cmd.Context(), job, rows); err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("Import %s: %d inserted, %d updated, %d failed", job.ID, job.Inserted, job.Updated, job.Failed)
+
+			if report != "" && job.Failed > 0 {
+				if err := writeImportReport(cmd, svc, job, report); err != nil {
+					logger.Errf("%s", err)
+					os.Exit(1)
+				}
+				logger.Infof("Error report written to %s", report)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&hospital, "hospital", "", "hospital the patients belong to")
+	cmd.Flags().StringVar(&req.Format, "format", "", "csv or fhir, guessed from the file extension when empty")
+	cmd.Flags().StringVar(&req.Mapping, "mapping", "", `CSV column mapping, e.g. {"national_id": "CID"}`)
+	cmd.Flags().StringVar(&report, "report", "", "write the rows that failed to this CSV file")
+	return cmd
+}
+
+// writeImportReport writes the error report of the job as a CSV
+func writeImportReport(cmd *cobra.Command, svc *importer.Service, job *model.ImportJob, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	writer := csv.NewWriter(out)
+	if err := writer.Write([]string{"row", "identifier", "message"}); err != nil {
+		return err
+	}
+	req := &importerdto.ListImportErrorRequest{Page: 1, Size: 1000}
+	for {
+		failures, total, err := svc.Errors(cmd.Context(), job.ID, job.Hospital, req)
+		if err != nil {
+			return err
+		}
+		for _, failure := range failures {
+			record := []string{strconv.Itoa(failure.Row), string(failure.Identifier), failure.Message}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		if req.Page*req.Size >= total {
+			break
+		}
+		req.Page++
+	}
+	writer.Flush()
+	return writer.Error()
+}
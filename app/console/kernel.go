@@ -7,5 +7,13 @@ func Commands() []*cobra.Command {
 	return []*cobra.Command{
 		helloCmd(),
 		testCmd(),
+		bootstrapAdminCmd(),
+		purgeTokensCmd(),
+		purgeAuditLogsCmd(),
+		importPatientsCmd(),
+		reencryptPatientsCmd(),
+		linkPatientsCmd(),
+		pdpaReportCmd(),
+		pdpaEraseCmd(),
 	}
 }
@@ -0,0 +1,28 @@
+package console
+
+import (
+	"app/app/modules/consent"
+	"app/app/modules/mpi"
+	"app/config"
+	"app/internal/cmd"
+	"app/internal/logger"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func linkPatientsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "link-patients",
+		Short: "Link the records of the same person across hospitals in the master patient index",
+		Args:  cmd.NotReqArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			linked, err := mpi.NewService(config.GetDB(), consent.NewService(config.GetDB())).LinkAll(cmd.Context())
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("%d patients linked", linked)
+		},
+	}
+}
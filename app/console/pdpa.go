@@ -0,0 +1,97 @@
+package console
+
+import (
+	"app/app/modules/mpi"
+	"app/app/modules/pdpa"
+	pdpadto "app/app/modules/pdpa/dto"
+	"app/app/util/fieldcrypt"
+	"app/config"
+	"app/internal/logger"
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func pdpaReportCmd() *cobra.Command {
+	var hospital, out string
+	cmd := &cobra.Command{
+		Use:   "pdpa-report [patient-id]",
+		Short: "Write every piece of data held about a patient as JSON, for a PDPA access request",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if hospital == "" {
+				logger.Errf("hospital is required")
+				os.Exit(1)
+			}
+			keyring, err := fieldcrypt.Load()
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			fieldcrypt.Use(keyring)
+
+			report, err := pdpa.NewService(config.GetDB()).Report(cmd.Context(), args[0], hospital, "")
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			if out == "" {
+				_, _ = os.Stdout.Write(append(data, '\n'))
+				return
+			}
+			// the report holds everything about the patient, readable by the owner only
+			if err := os.WriteFile(out, data, 0o600); err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("Report of patient %s written to %s", args[0], out)
+		},
+	}
+	cmd.Flags().StringVar(&hospital, "hospital", "", "hospital the patient belongs to")
+	cmd.Flags().StringVar(&out, "out", "", "file to write the report to (default stdout)")
+	return cmd
+}
+
+func pdpaEraseCmd() *cobra.Command {
+	var hospital string
+	req := new(pdpadto.ErasePatientRequest)
+	cmd := &cobra.Command{
+		Use:   "pdpa-erase [patient-id]",
+		Short: "Anonymize a patient and the patients merged into it, for a PDPA erasure request",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if hospital == "" {
+				logger.Errf("hospital is required")
+				os.Exit(1)
+			}
+			if len(req.Reason) < 10 {
+				logger.Errf("a reason of at least 10 characters is required")
+				os.Exit(1)
+			}
+			keyring, err := fieldcrypt.Load()
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			fieldcrypt.Use(keyring)
+
+			svc := pdpa.NewService(config.GetDB())
+			svc.Links = mpi.NewService(config.GetDB(), mpi.DenyAll{})
+			request, err := svc.Erase(cmd.Context(), args[0], req, hospital, "")
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("%d patients erased, request %s", len(request.PatientIDs), request.ID)
+		},
+	}
+	cmd.Flags().StringVar(&hospital, "hospital", "", "hospital the patient belongs to")
+	cmd.Flags().StringVar(&req.Reason, "reason", "", "why the patient is erased, kept with the request")
+	return cmd
+}
@@ -0,0 +1,56 @@
+package console
+
+import (
+	"app/app/modules/hospital"
+	"app/app/modules/importer"
+	"app/app/modules/patient"
+	"app/app/util/fieldcrypt"
+	"app/config"
+	"app/internal/cmd"
+	"app/internal/logger"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func reencryptPatientsCmd() *cobra.Command {
+	var all bool
+	cmd := &cobra.Command{
+		Use:   "reencrypt-patients",
+		Short: "Re-encrypt patient identifiers, contact details and hospital integrations with the active key",
+		Args:  cmd.NotReqArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			keyring, err := fieldcrypt.Load()
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			fieldcrypt.Use(keyring)
+
+			db := config.GetDB()
+			patients := patient.NewService(db, nil, nil)
+			rewritten, err := patients.Reencrypt(cmd.Context(), all)
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("%d patients re-encrypted with key version %d", rewritten, keyring.Active())
+
+			rewritten, err = importer.NewService(db, patients).ReencryptErrors(cmd.Context())
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("%d import error rows re-encrypted with key version %d", rewritten, keyring.Active())
+
+			rewritten, err = hospital.NewService(db, nil).Reencrypt(cmd.Context())
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("%d hospital integrations re-encrypted with key version %d", rewritten, keyring.Active())
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "rewrite every patient, needed after BLIND_INDEX_KEY changes")
+	return cmd
+}
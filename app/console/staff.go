@@ -0,0 +1,48 @@
+package console
+
+import (
+	"app/app/modules/staff"
+	staffdto "app/app/modules/staff/dto"
+	"app/config"
+	"app/internal/cmd"
+	"app/internal/logger"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func bootstrapAdminCmd() *cobra.Command {
+	req := new(staffdto.BootstrapAdminRequest)
+	cmd := &cobra.Command{
+		Use:   "bootstrap-admin",
+		Short: "Create the first admin of a hospital",
+		Args:  cmd.NotReqArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if req.Password == "" {
+				// keep the password out of the shell history when it is not given
+				fmt.Fprint(cmd.OutOrStdout(), "Password: ")
+				line, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				req.Password = strings.TrimSpace(line)
+			}
+			if req.Hospital == "" || req.Username == "" || req.Password == "" {
+				logger.Errf("hospital, username and password are required")
+				os.Exit(1)
+			}
+
+			svc := staff.NewService(config.GetDB())
+			if err := svc.BootstrapAdmin(cmd.Context(), req); err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("Admin %s created for %s", req.Username, req.Hospital)
+		},
+	}
+	cmd.Flags().StringVar(&req.Hospital, "hospital", "", "hospital of the admin")
+	cmd.Flags().StringVar(&req.Username, "username", "", "admin username")
+	cmd.Flags().StringVar(&req.Password, "password", "", "admin password, read from stdin when empty")
+	cmd.Flags().BoolVar(&req.System, "system", false, "create the system admin that manages hospitals")
+	return cmd
+}
@@ -0,0 +1,29 @@
+package console
+
+import (
+	"app/app/modules/staff"
+	"app/config"
+	"app/internal/cmd"
+	"app/internal/logger"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func purgeTokensCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "purge-tokens",
+		Short: "Delete expired refresh and revoked tokens",
+		Args:  cmd.NotReqArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			svc := staff.NewService(config.GetDB())
+			deleted, err := svc.PurgeExpiredTokens(cmd.Context())
+			if err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+			logger.Infof("%d expired tokens deleted", deleted)
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,34 @@
+package enum
+
+// AuditAction is what an audited request did
+type AuditAction string
+
+const (
+	AUDIT_PATIENT_LOOKUP     AuditAction = "patient.lookup"
+	AUDIT_PATIENT_LIST       AuditAction = "patient.list"
+	AUDIT_PATIENT_READ       AuditAction = "patient.read"
+	AUDIT_PATIENT_CREATE     AuditAction = "patient.create"
+	AUDIT_PATIENT_UPDATE     AuditAction = "patient.update"
+	AUDIT_PATIENT_DELETE     AuditAction = "patient.delete"
+	AUDIT_PATIENT_EXPORT     AuditAction = "patient.export"
+	AUDIT_PATIENT_IMPORT     AuditAction = "patient.import"
+	AUDIT_PATIENT_DUPLICATES AuditAction = "patient.duplicates"
+	AUDIT_PATIENT_MERGE      AuditAction = "patient.merge"
+	// AUDIT_PATIENT_UNMASK is break-glass access to the unmasked patient, the
+	// entry keeps the reason given
+	AUDIT_PATIENT_UNMASK AuditAction = "patient.unmask"
+	// the master patient index: links seen, records requested, requests
+	// decided and records received
+	AUDIT_MPI_LINKS   AuditAction = "mpi.links"
+	AUDIT_MPI_REQUEST AuditAction = "mpi.request"
+	AUDIT_MPI_RESPOND AuditAction = "mpi.respond"
+	AUDIT_MPI_RECORD  AuditAction = "mpi.record"
+	// consents read, captured and withdrawn
+	AUDIT_CONSENT_READ     AuditAction = "consent.read"
+	AUDIT_CONSENT_CAPTURE  AuditAction = "consent.capture"
+	AUDIT_CONSENT_WITHDRAW AuditAction = "consent.withdraw"
+	// PDPA data subject requests: access reports and erasures
+	AUDIT_PDPA_ACCESS AuditAction = "pdpa.access"
+	AUDIT_PDPA_ERASE  AuditAction = "pdpa.erase"
+	AUDIT_LOG_READ    AuditAction = "audit.read"
+)
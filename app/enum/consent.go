@@ -0,0 +1,16 @@
+package enum
+
+// ConsentType is what a patient agreed to
+type ConsentType string
+
+const (
+	// CONSENT_DATA_SHARING discloses the record to other hospitals
+	CONSENT_DATA_SHARING ConsentType = "data_sharing"
+	// CONSENT_RESEARCH allows the record to be used for research
+	CONSENT_RESEARCH ConsentType = "research"
+	// CONSENT_SMS_CONTACT allows the patient to be contacted by SMS
+	CONSENT_SMS_CONTACT ConsentType = "sms_contact"
+)
+
+// ConsentScopeAll is the scope of a consent given to every hospital
+const ConsentScopeAll = "*"
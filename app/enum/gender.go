@@ -0,0 +1,20 @@
+package enum
+
+// Gender follows the ISO/IEC 5218 sex codes
+type Gender string
+
+const (
+	GENDER_UNKNOWN        Gender = "0"
+	GENDER_MALE           Gender = "1"
+	GENDER_FEMALE         Gender = "2"
+	GENDER_NOT_APPLICABLE Gender = "9"
+)
+
+func IsGender(t Gender) bool {
+	switch t {
+	case GENDER_UNKNOWN, GENDER_MALE, GENDER_FEMALE, GENDER_NOT_APPLICABLE:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,11 @@
+package enum
+
+// JobStatus is the state of a background job
+type JobStatus string
+
+const (
+	JOB_PENDING   JobStatus = "pending"
+	JOB_RUNNING   JobStatus = "running"
+	JOB_COMPLETED JobStatus = "completed"
+	JOB_FAILED    JobStatus = "failed"
+)
@@ -0,0 +1,11 @@
+package enum
+
+// PDPARequestType is the data subject right a PDPA request exercises
+type PDPARequestType string
+
+const (
+	// PDPA_ACCESS reports every piece of data held about the patient
+	PDPA_ACCESS PDPARequestType = "access"
+	// PDPA_ERASURE anonymizes the patient
+	PDPA_ERASURE PDPARequestType = "erasure"
+)
@@ -0,0 +1,21 @@
+package enum
+
+// Purpose is why staff look at patient data, sent in the X-Purpose-Of-Use header.
+// The masking policy can show or hide fields by it.
+type Purpose string
+
+const (
+	PURPOSE_TREATMENT  Purpose = "treatment"
+	PURPOSE_PAYMENT    Purpose = "payment"
+	PURPOSE_OPERATIONS Purpose = "operations"
+	PURPOSE_RESEARCH   Purpose = "research"
+)
+
+func IsPurpose(p Purpose) bool {
+	switch p {
+	case PURPOSE_TREATMENT, PURPOSE_PAYMENT, PURPOSE_OPERATIONS, PURPOSE_RESEARCH:
+		return true
+	default:
+		return false
+	}
+}
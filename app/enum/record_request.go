@@ -0,0 +1,11 @@
+package enum
+
+// RecordRequestStatus is where a request for a patient record of another
+// hospital stands
+type RecordRequestStatus string
+
+const (
+	RECORD_REQUEST_PENDING  RecordRequestStatus = "pending"
+	RECORD_REQUEST_APPROVED RecordRequestStatus = "approved"
+	RECORD_REQUEST_DENIED   RecordRequestStatus = "denied"
+)
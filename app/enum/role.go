@@ -0,0 +1,79 @@
+package enum
+
+type Role string
+
+const (
+	ROLE_ADMIN     Role = "admin"
+	ROLE_DOCTOR    Role = "doctor"
+	ROLE_NURSE     Role = "nurse"
+	ROLE_REGISTRAR Role = "registrar"
+	ROLE_READ_ONLY Role = "read_only"
+	// ROLE_SYSTEM_ADMIN manages the hospitals themselves, across tenants
+	ROLE_SYSTEM_ADMIN Role = "system_admin"
+)
+
+type Permission string
+
+const (
+	PERMISSION_PATIENT_READ   Permission = "patient:read"
+	PERMISSION_PATIENT_LOOKUP Permission = "patient:lookup"
+	PERMISSION_PATIENT_CREATE Permission = "patient:create"
+	PERMISSION_PATIENT_UPDATE Permission = "patient:update"
+	PERMISSION_PATIENT_DELETE Permission = "patient:delete"
+	PERMISSION_PATIENT_EXPORT Permission = "patient:export"
+	// PERMISSION_PATIENT_READ_SENSITIVE shows identifiers, contact details and
+	// birth dates unmasked where no rule of the masking policy says otherwise
+	PERMISSION_PATIENT_READ_SENSITIVE Permission = "patient:read_sensitive"
+	// PERMISSION_PATIENT_BREAK_GLASS unmasks a single patient whatever the
+	// masking policy says, with a reason that is audited
+	PERMISSION_PATIENT_BREAK_GLASS Permission = "patient:break_glass"
+	// PERMISSION_PATIENT_MERGE lists suspected duplicates and merges them
+	PERMISSION_PATIENT_MERGE Permission = "patient:merge"
+	// PERMISSION_MPI_REQUEST sees the records of a patient linked at other
+	// hospitals and requests them, PERMISSION_MPI_RESPOND approves or denies the
+	// requests of other hospitals
+	PERMISSION_MPI_REQUEST Permission = "mpi:request"
+	PERMISSION_MPI_RESPOND Permission = "mpi:respond"
+	// PERMISSION_CONSENT_MANAGE captures and withdraws the consents of patients
+	PERMISSION_CONSENT_MANAGE Permission = "consent:manage"
+	// PERMISSION_PATIENT_PDPA reports the data held about a patient and erases it
+	PERMISSION_PATIENT_PDPA    Permission = "patient:pdpa"
+	PERMISSION_AUDIT_READ      Permission = "audit:read"
+	PERMISSION_STAFF_MANAGE    Permission = "staff:manage"
+	PERMISSION_HOSPITAL_MANAGE Permission = "hospital:manage"
+)
+
+// DefaultRolePermissions is the permission matrix seeded into the database
+func DefaultRolePermissions() map[Role][]Permission {
+	return map[Role][]Permission{
+		ROLE_ADMIN: {
+			PERMISSION_PATIENT_READ, PERMISSION_PATIENT_LOOKUP, PERMISSION_PATIENT_CREATE,
+			PERMISSION_PATIENT_UPDATE, PERMISSION_PATIENT_DELETE, PERMISSION_PATIENT_EXPORT,
+			PERMISSION_PATIENT_READ_SENSITIVE, PERMISSION_PATIENT_BREAK_GLASS, PERMISSION_PATIENT_MERGE,
+			PERMISSION_MPI_REQUEST, PERMISSION_MPI_RESPOND, PERMISSION_CONSENT_MANAGE, PERMISSION_PATIENT_PDPA,
+			PERMISSION_AUDIT_READ, PERMISSION_STAFF_MANAGE,
+		},
+		ROLE_DOCTOR: {
+			PERMISSION_PATIENT_READ, PERMISSION_PATIENT_LOOKUP, PERMISSION_PATIENT_UPDATE,
+			PERMISSION_PATIENT_READ_SENSITIVE, PERMISSION_PATIENT_BREAK_GLASS, PERMISSION_MPI_REQUEST,
+			PERMISSION_CONSENT_MANAGE,
+		},
+		ROLE_NURSE: {
+			PERMISSION_PATIENT_READ, PERMISSION_PATIENT_LOOKUP, PERMISSION_PATIENT_UPDATE,
+			PERMISSION_PATIENT_READ_SENSITIVE, PERMISSION_PATIENT_BREAK_GLASS, PERMISSION_MPI_REQUEST,
+			PERMISSION_CONSENT_MANAGE,
+		},
+		ROLE_REGISTRAR: {
+			PERMISSION_PATIENT_READ, PERMISSION_PATIENT_LOOKUP, PERMISSION_PATIENT_CREATE,
+			PERMISSION_PATIENT_UPDATE, PERMISSION_PATIENT_DELETE, PERMISSION_PATIENT_EXPORT,
+			PERMISSION_PATIENT_READ_SENSITIVE, PERMISSION_PATIENT_MERGE, PERMISSION_MPI_REQUEST,
+			PERMISSION_MPI_RESPOND, PERMISSION_CONSENT_MANAGE,
+		},
+		ROLE_READ_ONLY: {
+			PERMISSION_PATIENT_READ, PERMISSION_PATIENT_EXPORT,
+		},
+		ROLE_SYSTEM_ADMIN: {
+			PERMISSION_HOSPITAL_MANAGE,
+		},
+	}
+}
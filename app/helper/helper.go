@@ -1,7 +1,12 @@
 package helper
 
 import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/response"
 	"app/app/util/jwt"
+	"app/app/util/pii"
+	"errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +23,17 @@ func GetUserByToken(ctx *gin.Context) (*jwt.Claims, error) {
 	return nil, nil
 }
 
+// CurrentStaff returns the claims of the staff making the request, answering
+// 401 when there are none
+func CurrentStaff(ctx *gin.Context) (*jwt.Claims, bool) {
+	user, _ := GetUserByToken(ctx)
+	if user == nil {
+		response.Unauthorized(ctx, message.Unauthorized, nil)
+		return nil, false
+	}
+	return user, true
+}
+
 func SetUserInClaims(ctx *gin.Context, user *jwt.Claims) {
 	if user != nil {
 		ctx.Set("claims", user)
@@ -25,3 +41,59 @@ func SetUserInClaims(ctx *gin.Context, user *jwt.Claims) {
 		ctx.Set("claims", nil)
 	}
 }
+
+// AuditPatients adds patients the request touched to its audit log entry
+func AuditPatients(ctx *gin.Context, ids ...string) {
+	patients := ctx.GetStringSlice("audit_patients")
+	ctx.Set("audit_patients", append(patients, ids...))
+}
+
+// GetAuditPatients returns the patients AuditPatients recorded for the request
+func GetAuditPatients(ctx *gin.Context) []string {
+	return ctx.GetStringSlice("audit_patients")
+}
+
+// FlushAuditPatients writes the patients added so far in an audit log entry of
+// their own and starts the next one empty, so a long export is logged a batch
+// at a time instead of holding every patient until the response ends
+func FlushAuditPatients(ctx *gin.Context) {
+	if flush, ok := ctx.Value("audit_flush").(func()); ok {
+		flush()
+	}
+}
+
+// AuditReason keeps the reason the caller gave for the request, e.g. break-glass
+// access, in its audit log entry
+func AuditReason(ctx *gin.Context, reason string) {
+	ctx.Set("audit_reason", reason)
+}
+
+// GetAuditReason returns the reason AuditReason recorded for the request
+func GetAuditReason(ctx *gin.Context) string {
+	return ctx.GetString("audit_reason")
+}
+
+// GetRequestID returns the id middleware.RequestID gave the request
+func GetRequestID(ctx *gin.Context) string {
+	return ctx.GetString("request_id")
+}
+
+// GetPurpose returns the purpose of use the caller sent in X-Purpose-Of-Use,
+// empty without one. An unknown purpose fails with InvalidPurposeOfUse.
+func GetPurpose(ctx *gin.Context) (enum.Purpose, error) {
+	purpose := enum.Purpose(ctx.GetHeader(pii.PurposeHeader))
+	if purpose != "" && !enum.IsPurpose(purpose) {
+		return "", errors.New(message.InvalidPurposeOfUse)
+	}
+	return purpose, nil
+}
+
+// GetViewer returns the viewer the patient fields of the response are shaped
+// for, the caller with the purpose of use of the request
+func GetViewer(ctx *gin.Context, user *jwt.Claims) (pii.Viewer, error) {
+	purpose, err := GetPurpose(ctx)
+	if err != nil {
+		return pii.Viewer{}, err
+	}
+	return pii.ViewerOf(user.Data, purpose), nil
+}
@@ -0,0 +1,48 @@
+// Package testhelper holds the fixtures the module tests share
+package testhelper
+
+import (
+	"app/app/helper"
+	"app/app/util/jwt"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// UseJSONNaming configures the response naming so responses render with their
+// real status code instead of the test env quirk
+func UseJSONNaming(t testing.TB) {
+	viper.Set("HTTP_JSON_NAMING", "snake_case")
+	t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+}
+
+// NewContext builds a gin context for a request, a non-nil body is sent as JSON
+func NewContext(method, target string, body any) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	c.Request = httptest.NewRequest(method, target, &buf)
+	if body != nil {
+		c.Request.Header.Set("Content-Type", "application/json")
+	}
+	return c, w
+}
+
+// NewContextWithClaims is NewContext for a request signed in with the claims,
+// nil claims leaves the request anonymous
+func NewContextWithClaims(method, target string, body any, claims *jwt.Claims) (*gin.Context, *httptest.ResponseRecorder) {
+	c, w := NewContext(method, target, body)
+	if claims != nil {
+		helper.SetUserInClaims(c, claims)
+	}
+	return c, w
+}
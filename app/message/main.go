@@ -7,12 +7,74 @@ var (
 	Unauthorized        = "unauthorized"
 	InvalidRequest      = "invalid-request-form"
 
-	StaffAlreadyExists = "staff-already-exists"
-	StaffNotFound      = "staff-not-found"
-	StaffIsInUse       = "staff-is-in-use"
+	StaffAlreadyExists    = "staff-already-exists"
+	StaffNotFound         = "staff-not-found"
+	StaffIsInUse          = "staff-is-in-use"
+	StaffHospitalMismatch = "staff-hospital-mismatch"
+	HospitalAdminExists   = "hospital-admin-already-exists"
+	StaffRoleForbidden    = "staff-role-forbidden"
+
+	RoleNotFound = "role-not-found"
 
 	PasswordIncorrect = "password-incorrect"
 	PasswordNotMatch  = "password-not-match"
 
 	InvalidCredentials = "username-or-password-incorrect"
+
+	InvalidRefreshToken = "invalid-refresh-token"
+	TokenRevoked        = "token-revoked"
+
+	HospitalNotFound       = "hospital-not-found"
+	HospitalInactive       = "hospital-inactive"
+	HospitalAlreadyExists  = "hospital-already-exists"
+	InvalidHospitalAPI     = "invalid-hospital-api"
+	InvalidHospitalStatus  = "invalid-hospital-status"
+	HospitalNotIntegrated  = "hospital-not-integrated"
+	HospitalAPIUnavailable = "hospital-api-unavailable"
+	HospitalAPITimeout     = "hospital-api-timeout"
+	HospitalAPIError       = "hospital-api-error"
+
+	PatientNotFound         = "patient-not-found"
+	PatientHospitalMismatch = "patient-hospital-mismatch"
+	PatientAlreadyExists    = "patient-already-exists"
+	PatientIdentityConflict = "patient-identity-conflict"
+	InvalidSortField        = "invalid-sort-field"
+	InvalidCursor           = "invalid-cursor"
+	CursorWithSearch        = "cursor-pagination-not-supported-with-q"
+	InvalidPurposeOfUse     = "invalid-purpose-of-use"
+	UnmaskReasonRequired    = "unmask-reason-required"
+	PatientMergeSelf        = "patient-merge-same-patient"
+	PatientMergeConflict    = "patient-merge-identity-conflict"
+	PatientNotLinked        = "patient-not-linked"
+	PatientConsentRequired  = "patient-consent-required"
+	PatientAlreadyErased    = "patient-already-erased"
+	PatientDeleted          = "patient-deleted"
+
+	RecordRequestNotFound    = "record-request-not-found"
+	RecordRequestDecided     = "record-request-already-decided"
+	RecordRequestNotApproved = "record-request-not-approved"
+
+	ConsentNotFound      = "consent-not-found"
+	ConsentWithdrawn     = "consent-already-withdrawn"
+	ConsentInvalidPeriod = "consent-invalid-period"
+
+	InvalidNationalID       = "invalid-national-id"
+	InvalidPassportID       = "invalid-passport-id"
+	InvalidGender           = "invalid-gender"
+	InvalidDateOfBirth      = "invalid-date-of-birth"
+	InvalidEmail            = "invalid-email"
+	PatientNameRequired     = "patient-name-required"
+	PatientIdentityRequired = "patient-national-id-or-passport-id-required"
+	MaskedValue             = "masked-value-not-allowed"
+
+	InvalidImportFile  = "invalid-import-file"
+	ImportFileRequired = "import-file-required"
+	ImportFileTooLarge = "import-file-too-large"
+	ImportJobNotFound  = "import-job-not-found"
+	ImportInterrupted  = "import-interrupted"
+	ImportRowFailed    = "import-row-failed"
+	ImportDuplicateRow = "duplicate-of-row"
+
+	InvalidSearchParameter     = "invalid-search-parameter"
+	UnsupportedSearchParameter = "unsupported-search-parameter"
 )
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/model"
+	"app/internal/logger"
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditRecorder stores audit log entries, the audit service implements it
+type AuditRecorder interface {
+	Record(ctx context.Context, entry *model.AuditLog) error
+}
+
+// auditedParams are the query parameters kept as they are in the log, the
+// others can name or identify a patient (national_id, name, q, a cursor holding
+// a last name, ...) and only their presence is kept
+var auditedParams = map[string]bool{
+	"page": true, "size": true, "paginate": true, "with_total": true,
+	"sort": true, "sort_by": true, "order_by": true, "format": true, "fields": true,
+	"status": true, "action": true, "from": true, "to": true, "type": true, "active": true,
+	"min_score": true, "patient_id": true, "staff_id": true,
+	"_count": true, "_offset": true, "_format": true, "_pretty": true,
+}
+
+// redacted replaces the values of the query parameters that are not audited
+const redacted = "[redacted]"
+
+// Audit returns the middleware recording requests of an action. It goes right
+// after AuthMiddleware so requests refused by RequirePermission are recorded
+// too; handlers add the patients they touched with helper.AuditPatients. The
+// path is the route, e.g. /patient/:id, so the log holds no identifier the
+// caller typed into the URL.
+func Audit(recorder AuditRecorder) func(action enum.AuditAction) gin.HandlerFunc {
+	return func(action enum.AuditAction) gin.HandlerFunc {
+		return func(ctx *gin.Context) {
+			ctx.Set("audit_flush", func() {
+				record(ctx, recorder, action)
+				ctx.Set("audit_patients", []string(nil))
+			})
+			ctx.Next()
+			record(ctx, recorder, action)
+		}
+	}
+}
+
+func record(ctx *gin.Context, recorder AuditRecorder, action enum.AuditAction) {
+	user, _ := helper.GetUserByToken(ctx)
+	if user == nil {
+		return
+	}
+	// an unknown purpose is refused by the handler, it is not recorded
+	purpose, _ := helper.GetPurpose(ctx)
+	entry := &model.AuditLog{
+		RequestID:  helper.GetRequestID(ctx),
+		StaffID:    user.Data.ID,
+		Hospital:   user.Data.Hospital,
+		Action:     action,
+		PatientIDs: helper.GetAuditPatients(ctx),
+		Query:      auditQuery(ctx),
+		Method:     ctx.Request.Method,
+		Path:       ctx.FullPath(),
+		Status:     ctx.Writer.Status(),
+		ClientIP:   ctx.ClientIP(),
+		Purpose:    purpose,
+		Reason:     helper.GetAuditReason(ctx),
+	}
+	// the entry is written even when the client went away meanwhile
+	if err := recorder.Record(context.WithoutCancel(ctx.Request.Context()), entry); err != nil {
+		logger.Errf("audit log of request %s: %s", entry.RequestID, err)
+	}
+}
+
+// auditQuery is the query of the request with the values of the parameters
+// that are not audited redacted, the form holds the query and, for form posts,
+// the body
+func auditQuery(ctx *gin.Context) map[string][]string {
+	query := ctx.Request.Form
+	if query == nil {
+		query = ctx.Request.URL.Query()
+	}
+	if len(query) == 0 {
+		return nil
+	}
+	resp := make(map[string][]string, len(query))
+	for key, values := range query {
+		if auditedParams[key] {
+			resp[key] = values
+			continue
+		}
+		resp[key] = []string{redacted}
+	}
+	return resp
+}
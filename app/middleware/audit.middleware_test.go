@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/model"
+	"app/app/util/jwt"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	entries []*model.AuditLog
+	err     error
+}
+
+func (r *recorder) Record(ctx context.Context, entry *model.AuditLog) error {
+	r.entries = append(r.entries, entry)
+	return r.err
+}
+
+func serveAudited(rec *recorder, claims *jwt.Claims, target string, permissions ...enum.Permission) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/patient/:id", func(ctx *gin.Context) {
+		if claims != nil {
+			helper.SetUserInClaims(ctx, claims)
+		}
+		ctx.Next()
+	}, Audit(rec)(enum.AUDIT_PATIENT_READ), RequirePermission(permissions...), func(ctx *gin.Context) {
+		helper.AuditPatients(ctx, ctx.Param("id"))
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAudit(t *testing.T) {
+	staff := &jwt.Claims{Data: jwt.ClaimData{
+		ID:          "0b4ba3ff-2f57-4ab0-9b1b-2f6e1d0d7a53",
+		Hospital:    "hospital-a",
+		Permissions: []string{string(enum.PERMISSION_PATIENT_READ)},
+	}}
+	const patientID = "65e08e33-9f57-45fe-b725-82242e3581ad"
+
+	t.Run("Success - Records the request and its patients", func(t *testing.T) {
+		rec := &recorder{}
+		w := serveAudited(rec, staff, "/patient/"+patientID+"?fields=name&national_id=1103702071811&q=Somchai", enum.PERMISSION_PATIENT_READ)
+		assert.Equal(t, 200, w.Code)
+		require.Len(t, rec.entries, 1)
+		entry := rec.entries[0]
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.Equal(t, staff.Data.ID, entry.StaffID)
+		assert.Equal(t, "hospital-a", entry.Hospital)
+		assert.Equal(t, enum.AUDIT_PATIENT_READ, entry.Action)
+		assert.Equal(t, []string{patientID}, entry.PatientIDs)
+		assert.Equal(t, map[string][]string{
+			"fields":      {"name"},
+			"national_id": {"[redacted]"},
+			"q":           {"[redacted]"},
+		}, entry.Query)
+		assert.Equal(t, "/patient/:id", entry.Path, "the route, not the ID in the URL")
+		assert.Equal(t, 200, entry.Status)
+	})
+
+	t.Run("Success - Flushed patients get an entry of their own", func(t *testing.T) {
+		rec := &recorder{}
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/patient/export", func(ctx *gin.Context) {
+			helper.SetUserInClaims(ctx, staff)
+			ctx.Next()
+		}, Audit(rec)(enum.AUDIT_PATIENT_EXPORT), func(ctx *gin.Context) {
+			helper.AuditPatients(ctx, "p1", "p2")
+			helper.FlushAuditPatients(ctx)
+			helper.AuditPatients(ctx, "p3")
+			ctx.Status(http.StatusOK)
+		})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/patient/export", nil))
+
+		require.Len(t, rec.entries, 2)
+		assert.Equal(t, []string{"p1", "p2"}, rec.entries[0].PatientIDs)
+		assert.Equal(t, []string{"p3"}, rec.entries[1].PatientIDs)
+	})
+
+	t.Run("Success - Refused requests are recorded", func(t *testing.T) {
+		rec := &recorder{}
+		w := serveAudited(rec, staff, "/patient/"+patientID, enum.PERMISSION_PATIENT_DELETE)
+		assert.Equal(t, 403, w.Code)
+		require.Len(t, rec.entries, 1)
+		assert.Equal(t, 403, rec.entries[0].Status)
+		assert.Empty(t, rec.entries[0].PatientIDs)
+		assert.Nil(t, rec.entries[0].Query)
+	})
+
+	t.Run("Success - A failing log does not fail the request", func(t *testing.T) {
+		rec := &recorder{err: errors.New("db down")}
+		w := serveAudited(rec, staff, "/patient/"+patientID, enum.PERMISSION_PATIENT_READ)
+		assert.Equal(t, 200, w.Code)
+	})
+
+	t.Run("Success - Records the purpose of use and reason", func(t *testing.T) {
+		rec := &recorder{}
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/patient/:id/unmask", func(ctx *gin.Context) {
+			helper.SetUserInClaims(ctx, staff)
+			ctx.Next()
+		}, Audit(rec)(enum.AUDIT_PATIENT_UNMASK), func(ctx *gin.Context) {
+			helper.AuditReason(ctx, "Emergency admission")
+			ctx.Status(http.StatusOK)
+		})
+		serve := func(purpose string) *model.AuditLog {
+			req := httptest.NewRequest("POST", "/patient/"+patientID+"/unmask", nil)
+			req.Header.Set("X-Purpose-Of-Use", purpose)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+			return rec.entries[len(rec.entries)-1]
+		}
+
+		entry := serve("treatment")
+		assert.Equal(t, enum.PURPOSE_TREATMENT, entry.Purpose)
+		assert.Equal(t, "Emergency admission", entry.Reason)
+		assert.Empty(t, serve("marketing").Purpose, "unknown purposes are not recorded")
+	})
+
+	t.Run("Fail - Anonymous requests are not recorded", func(t *testing.T) {
+		rec := &recorder{}
+		serveAudited(rec, nil, "/patient/"+patientID, enum.PERMISSION_PATIENT_READ)
+		assert.Empty(t, rec.entries)
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, helper.GetRequestID(ctx))
+	})
+	serve := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("nginx-4f2a")
+	assert.Equal(t, "nginx-4f2a", w.Body.String())
+	assert.Equal(t, "nginx-4f2a", w.Header().Get(RequestIDHeader))
+
+	w = serve("")
+	assert.Len(t, w.Body.String(), 36)
+	assert.Equal(t, w.Body.String(), w.Header().Get(RequestIDHeader))
+
+	w = serve("forged\nline")
+	assert.NotEqual(t, "forged\nline", w.Body.String())
+	assert.Len(t, w.Body.String(), 36)
+}
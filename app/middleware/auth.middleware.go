@@ -1,14 +1,23 @@
 package middleware
 
 import (
+	"app/app/message"
 	"app/app/response"
 	"app/app/util/jwt"
+	"app/internal/logger"
+	"context"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// TokenRevocations tells whether an access token was revoked, the staff service
+// implements it
+type TokenRevocations interface {
+	IsRevoked(ctx context.Context, uuid string) (bool, error)
+}
+
+func AuthMiddleware(revocations TokenRevocations) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
@@ -32,6 +41,20 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// a logged out or compromised token stays blocked until it expires
+		revoked, err := revocations.IsRevoked(ctx, claims.Uuid)
+		if err != nil {
+			logger.Err(err)
+			response.InternalError(ctx, message.InternalServerError, nil)
+			ctx.Abort()
+			return
+		}
+		if revoked {
+			response.Unauthorized(ctx, message.TokenRevoked, nil)
+			ctx.Abort()
+			return
+		}
+
 		ctx.Set("claims", claims)
 
 		ctx.Next()
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"app/app/helper"
+	"app/app/util/jwt"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// revocations answers IsRevoked from a set of revoked token uuids
+type revocations struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (r revocations) IsRevoked(ctx context.Context, uuid string) (bool, error) {
+	return r.revoked[uuid], r.err
+}
+
+func serveAuthenticated(checker TokenRevocations, header string) (*httptest.ResponseRecorder, *jwt.Claims) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("HTTP_JSON_NAMING", "snake_case")
+	router := gin.New()
+	var claims *jwt.Claims
+	router.GET("/", AuthMiddleware(checker), func(ctx *gin.Context) {
+		claims, _ = helper.GetUserByToken(ctx)
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	router.ServeHTTP(w, req)
+	return w, claims
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	viper.Set("JWT_SECRET", "test-secret")
+	viper.Set("JWT_ACCESS_DURATION", 15)
+	t.Cleanup(func() {
+		viper.Set("JWT_SECRET", nil)
+		viper.Set("JWT_ACCESS_DURATION", nil)
+	})
+	token, issued, err := jwt.CreateToken(jwt.ClaimData{ID: "staff-1", Hospital: "hospital-a"})
+	require.NoError(t, err)
+
+	t.Run("Success - Valid token", func(t *testing.T) {
+		w, claims := serveAuthenticated(revocations{}, "Bearer "+token)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, claims)
+		assert.Equal(t, "staff-1", claims.Data.ID)
+	})
+
+	t.Run("Fail - Missing header", func(t *testing.T) {
+		w, _ := serveAuthenticated(revocations{}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Fail - Revoked token", func(t *testing.T) {
+		w, claims := serveAuthenticated(revocations{revoked: map[string]bool{issued.Uuid: true}}, "Bearer "+token)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("Fail - Revocations unavailable", func(t *testing.T) {
+		w, _ := serveAuthenticated(revocations{err: errors.New("db down")}, "Bearer "+token)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/message"
+	"app/app/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission lets the request through only when the token of
+// AuthMiddleware grants every one of the permissions
+func RequirePermission(permissions ...enum.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, _ := helper.GetUserByToken(ctx)
+		if user == nil {
+			response.Unauthorized(ctx, message.Unauthorized, nil)
+			ctx.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !user.Data.HasPermission(string(permission)) {
+				response.Forbidden(ctx, message.Forbidden, nil)
+				ctx.Abort()
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/util/jwt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveWithClaims(claims *jwt.Claims, permissions ...enum.Permission) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	viper.Set("HTTP_JSON_NAMING", "snake_case")
+	router := gin.New()
+	router.GET("/", func(ctx *gin.Context) {
+		if claims != nil {
+			helper.SetUserInClaims(ctx, claims)
+		}
+		ctx.Next()
+	}, RequirePermission(permissions...), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	return w
+}
+
+func TestRequirePermission(t *testing.T) {
+	nurse := &jwt.Claims{Data: jwt.ClaimData{
+		Role:        string(enum.ROLE_NURSE),
+		Permissions: []string{string(enum.PERMISSION_PATIENT_READ), string(enum.PERMISSION_PATIENT_UPDATE)},
+	}}
+
+	t.Run("Success - Permission Granted", func(t *testing.T) {
+		w := serveWithClaims(nurse, enum.PERMISSION_PATIENT_READ)
+		assert.Equal(t, 200, w.Code)
+	})
+
+	t.Run("Fail - Missing One Permission", func(t *testing.T) {
+		w := serveWithClaims(nurse, enum.PERMISSION_PATIENT_READ, enum.PERMISSION_PATIENT_DELETE)
+		assert.Equal(t, 403, w.Code)
+	})
+
+	t.Run("Fail - No Token", func(t *testing.T) {
+		w := serveWithClaims(nil, enum.PERMISSION_PATIENT_READ)
+		assert.Equal(t, 401, w.Code)
+	})
+}
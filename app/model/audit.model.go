@@ -0,0 +1,30 @@
+package model
+
+import (
+	"app/app/enum"
+
+	"github.com/uptrace/bun"
+)
+
+// AuditLog records one request to the patient endpoints, who made it and which
+// patients it touched. The table is append-only, see the audit_logs migration.
+type AuditLog struct {
+	bun.BaseModel `bun:"table:audit_logs"`
+
+	ID         int64               `bun:",pk,autoincrement" json:"id"`
+	RequestID  string              `bun:"request_id,notnull" json:"request_id"`
+	StaffID    string              `bun:"staff_id,type:uuid,nullzero" json:"staff_id"`
+	Hospital   string              `bun:"hospital,notnull" json:"hospital"`
+	Action     enum.AuditAction    `bun:"action,notnull" json:"action"`
+	PatientIDs []string            `bun:"patient_ids,type:uuid[],array" json:"patient_ids"`
+	Query      map[string][]string `bun:"query,type:jsonb,nullzero" json:"query"`
+	Method     string              `bun:"method,notnull" json:"method"`
+	Path       string              `bun:"path,notnull" json:"path"`
+	Status     int                 `bun:"status,notnull" json:"status"`
+	ClientIP   string              `bun:"client_ip" json:"client_ip"`
+	Purpose    enum.Purpose        `bun:"purpose,nullzero" json:"purpose"`
+	// Reason is why the staff member needed the access, given for break-glass access
+	Reason string `bun:"reason,nullzero" json:"reason"`
+
+	CreateUnixTimestamp
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"app/app/enum"
+
+	"github.com/uptrace/bun"
+)
+
+// PatientConsent is what a patient agreed to, to whom, for which purposes and
+// for how long. A withdrawn consent is kept with RevokedAt set.
+type PatientConsent struct {
+	bun.BaseModel `bun:"table:patient_consents"`
+
+	ID        string           `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Hospital  string           `bun:"hospital,notnull" json:"hospital"`
+	PatientID string           `bun:"patient_id,type:uuid,notnull" json:"patient_id"`
+	Type      enum.ConsentType `bun:"type,notnull" json:"type"`
+	// Scope is the hospital the consent is given to, enum.ConsentScopeAll for every hospital
+	Scope string `bun:"scope,notnull,default:'*'" json:"scope"`
+	// Purposes are the purposes of use the consent covers, every purpose when empty
+	Purposes   []enum.Purpose `bun:"purposes,array" json:"purposes"`
+	ValidFrom  int64          `bun:"valid_from,notnull" json:"valid_from"`
+	ValidUntil int64          `bun:"valid_until,nullzero" json:"valid_until"`
+	// Evidence points at what the consent was given with, e.g. a signed form
+	Evidence     string `bun:"evidence,nullzero" json:"evidence"`
+	RecordedBy   string `bun:"recorded_by,type:uuid,nullzero" json:"recorded_by"`
+	RevokedAt    int64  `bun:"revoked_at,nullzero" json:"revoked_at"`
+	RevokedBy    string `bun:"revoked_by,type:uuid,nullzero" json:"revoked_by"`
+	RevokeReason string `bun:"revoke_reason,nullzero" json:"revoke_reason"`
+
+	_ struct{} `bun:"index:(patient_id, type)"`
+
+	CreateUpdateUnixTimestamp
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"github.com/uptrace/bun"
+)
+
+// HNSequence is the last hospital number counter allocated per hospital and period
+type HNSequence struct {
+	bun.BaseModel `bun:"table:hn_sequences"`
+
+	Hospital string `bun:"hospital,pk" json:"hospital"`
+	Period   string `bun:"period,pk" json:"period"`
+	Value    int64  `bun:"value,notnull" json:"value"`
+
+	UpdateUnixTimestamp
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"app/app/enum"
+	"app/app/util/fieldcrypt"
+
+	"github.com/uptrace/bun"
+)
+
+type Hospital struct {
+	bun.BaseModel `bun:"table:hospitals"`
+
+	Code   string      `bun:"code,pk" json:"code"`
+	NameTH string      `bun:"name_th,notnull" json:"name_th"`
+	NameEN string      `bun:"name_en,notnull" json:"name_en"`
+	Status enum.Status `bun:"status,notnull,default:'active'" json:"status"`
+	// API is the his.AdapterConfig JSON of the upstream HIS, empty when the hospital
+	// is not integrated. It is encrypted as it holds the HIS credentials.
+	API fieldcrypt.EncryptedString `bun:"api,nullzero" json:"-"`
+
+	CreateUpdateUnixTimestamp
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"app/app/enum"
+	"app/app/util/fieldcrypt"
+
+	"github.com/uptrace/bun"
+)
+
+// ImportJob tracks one bulk patient import. The counters are updated after every
+// batch, so UpdatedAt doubles as the heartbeat of a running import.
+type ImportJob struct {
+	bun.BaseModel `bun:"table:import_jobs"`
+
+	ID         string         `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Hospital   string         `bun:"hospital,notnull" json:"hospital"`
+	Format     string         `bun:"format,notnull" json:"format"`
+	FileName   string         `bun:"file_name" json:"file_name"`
+	Status     enum.JobStatus `bun:"status,notnull,default:'pending'" json:"status"`
+	Total      int            `bun:"total,notnull" json:"total"`
+	Processed  int            `bun:"processed,notnull" json:"processed"`
+	Inserted   int            `bun:"inserted,notnull" json:"inserted"`
+	Updated    int            `bun:"updated,notnull" json:"updated"`
+	Failed     int            `bun:"failed,notnull" json:"failed"`
+	Error      string         `bun:"error,nullzero" json:"error"`
+	CreatedBy  string         `bun:"created_by,type:uuid,nullzero" json:"created_by"`
+	StartedAt  int64          `bun:"started_at,nullzero" json:"started_at"`
+	FinishedAt int64          `bun:"finished_at,nullzero" json:"finished_at"`
+
+	CreateUpdateUnixTimestamp
+}
+
+// ImportJobError is a row of an import that was not saved
+type ImportJobError struct {
+	bun.BaseModel `bun:"table:import_job_errors"`
+
+	ID         int64                      `bun:",pk,autoincrement" json:"-"`
+	JobID      string                     `bun:"job_id,type:uuid,notnull" json:"-"`
+	Row        int                        `bun:"row,notnull" json:"row"`
+	Identifier fieldcrypt.EncryptedString `bun:"identifier,nullzero" json:"identifier"`
+	Message    string                     `bun:"message,notnull" json:"message"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"app/app/util/fieldcrypt"
+
+	"github.com/uptrace/bun"
+)
+
+// PatientMerge records a duplicate patient merged into the survivor. The merged
+// patient is soft-deleted and points at the survivor with merged_into.
+type PatientMerge struct {
+	bun.BaseModel `bun:"table:patient_merges"`
+
+	ID         string `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Hospital   string `bun:"hospital,notnull" json:"hospital"`
+	SurvivorID string `bun:"survivor_id,type:uuid,notnull" json:"survivor_id"`
+	MergedID   string `bun:"merged_id,type:uuid,notnull" json:"merged_id"`
+	// Fields are the fields the survivor took over from the merged patient
+	Fields   []string `bun:"fields,array" json:"fields"`
+	Reason   string   `bun:"reason,nullzero" json:"reason"`
+	MergedBy string   `bun:"merged_by,type:uuid,nullzero" json:"merged_by"`
+	// Snapshot is the merged patient as it was before the merge, as JSON
+	Snapshot fieldcrypt.EncryptedString `bun:"snapshot,nullzero" json:"-"`
+
+	CreateUnixTimestamp
+}
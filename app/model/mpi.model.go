@@ -0,0 +1,46 @@
+package model
+
+import (
+	"app/app/enum"
+
+	"github.com/uptrace/bun"
+)
+
+// PatientLink puts a patient record in the group of records of one person
+// across hospitals, the master patient index. Records are linked by the same
+// national ID or passport and the same birth date.
+type PatientLink struct {
+	bun.BaseModel `bun:"table:patient_links"`
+
+	PatientID string `bun:"patient_id,pk,type:uuid" json:"patient_id"`
+	PersonID  string `bun:"person_id,type:uuid,notnull" json:"-"`
+	Hospital  string `bun:"hospital,notnull" json:"hospital"`
+	// MatchedOn is the identifier that linked the record, national_id or passport_id
+	MatchedOn string `bun:"matched_on,notnull" json:"matched_on"`
+
+	_ struct{} `bun:"index:person_id"`
+
+	CreateUpdateUnixTimestamp
+}
+
+// RecordRequest asks the hospital of a linked record for it on behalf of a
+// patient of the requesting hospital
+type RecordRequest struct {
+	bun.BaseModel `bun:"table:record_requests"`
+
+	ID              string                   `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Hospital        string                   `bun:"hospital,notnull" json:"hospital"`
+	PatientID       string                   `bun:"patient_id,type:uuid,notnull" json:"patient_id"`
+	SourceHospital  string                   `bun:"source_hospital,notnull" json:"source_hospital"`
+	SourcePatientID string                   `bun:"source_patient_id,type:uuid,notnull" json:"source_patient_id"`
+	Purpose         enum.Purpose             `bun:"purpose,nullzero" json:"purpose"`
+	Reason          string                   `bun:"reason,notnull" json:"reason"`
+	Status          enum.RecordRequestStatus `bun:"status,notnull,default:'pending'" json:"status"`
+	RequestedBy     string                   `bun:"requested_by,type:uuid,nullzero" json:"requested_by"`
+	DecidedBy       string                   `bun:"decided_by,type:uuid,nullzero" json:"decided_by"`
+	DecidedAt       int64                    `bun:"decided_at,nullzero" json:"decided_at"`
+
+	_ struct{} `bun:"index:(hospital, created_at),index:(source_hospital, created_at)"`
+
+	CreateUpdateUnixTimestamp
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"app/app/util/fieldcrypt"
+	"context"
+	"strings"
 	"time"
 
 	"github.com/uptrace/bun"
@@ -9,28 +12,78 @@ import (
 type Patient struct {
 	bun.BaseModel `bun:"table:patients"`
 
-	ID           string    `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
-	FirstNameTH  string    `bun:"first_name_th" json:"first_name_th"`
-	MiddleNameTH string    `bun:"middle_name_th" json:"middle_name_th"`
-	LastNameTH   string    `bun:"last_name_th" json:"last_name_th"`
-	FirstNameEN  string    `bun:"first_name_en" json:"first_name_en"`
-	MiddleNameEN string    `bun:"middle_name_en" json:"middle_name_en"`
-	LastNameEN   string    `bun:"last_name_en" json:"last_name_en"`
-	DateOfBirth  time.Time `bun:"date_of_birth,type:date" json:"date_of_birth"`
-	PatientHN    string    `bun:"patient_hn" json:"patient_hn"`
-	NationalID   string    `bun:"national_id,unique,nullzero" json:"national_id"`
-	PassportID   string    `bun:"passport_id,unique,nullzero" json:"passport_id"`
-	PhoneNumber  string    `bun:"phone_number" json:"phone_number"`
-	Email        string    `bun:"email" json:"email"`
-	Gender       string    `bun:"gender,type:char(1)" json:"gender"`
-	Hospital     string    `bun:"hospital,notnull" json:"hospital"`
+	ID           string                     `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	FirstNameTH  string                     `bun:"first_name_th" json:"first_name_th"`
+	MiddleNameTH string                     `bun:"middle_name_th" json:"middle_name_th"`
+	LastNameTH   string                     `bun:"last_name_th" json:"last_name_th"`
+	FirstNameEN  string                     `bun:"first_name_en" json:"first_name_en"`
+	MiddleNameEN string                     `bun:"middle_name_en" json:"middle_name_en"`
+	LastNameEN   string                     `bun:"last_name_en" json:"last_name_en"`
+	DateOfBirth  time.Time                  `bun:"date_of_birth,type:date" json:"date_of_birth"`
+	PatientHN    string                     `bun:"patient_hn,nullzero,unique:patients_hospital_patient_hn_key" json:"patient_hn"`
+	NationalID   fieldcrypt.EncryptedString `bun:"national_id,nullzero" json:"national_id"`
+	PassportID   fieldcrypt.EncryptedString `bun:"passport_id,nullzero" json:"passport_id"`
+	PhoneNumber  fieldcrypt.EncryptedString `bun:"phone_number,nullzero" json:"phone_number"`
+	Email        fieldcrypt.EncryptedString `bun:"email,nullzero" json:"email"`
+	Gender       string                     `bun:"gender,type:char(1)" json:"gender"`
+	Hospital     string                     `bun:"hospital,notnull,unique:patients_national_id_bidx_hospital_key,unique:patients_passport_id_bidx_hospital_key,unique:patients_hospital_patient_hn_key" json:"hospital"`
+	SyncedAt     int64                      `bun:"synced_at,nullzero" json:"synced_at"`
+	// MergedInto is the patient this one was merged into, see PatientMerge
+	MergedInto string `bun:"merged_into,type:uuid,nullzero" json:"-"`
+	// AnonymizedAt is when the patient was erased on a PDPA request, its
+	// identifiers and contact details are gone
+	AnonymizedAt int64 `bun:"anonymized_at,nullzero" json:"-"`
+	// Consents are loaded with Relation("Consents") only
+	Consents []*PatientConsent `bun:"rel:has-many,join:id=patient_id" json:"-"`
+
+	// blind indexes of the encrypted columns, kept by BeforeAppendModel
+	NationalIDIndex  string `bun:"national_id_bidx,unique:patients_national_id_bidx_hospital_key,nullzero" json:"-"`
+	PassportIDIndex  string `bun:"passport_id_bidx,unique:patients_passport_id_bidx_hospital_key,nullzero" json:"-"`
+	PhoneNumberIndex string `bun:"phone_number_bidx,nullzero" json:"-"`
+	EmailIndex       string `bun:"email_bidx,nullzero" json:"-"`
 
 	_ struct{} `bun:"index:(first_name_th, first_name_en),index:(middle_name_th, middle_name_en),index:(last_name_th, last_name_en)"`
 	_ struct{} `bun:"index:date_of_birth"`
-	_ struct{} `bun:"index:email"`
-	_ struct{} `bun:"index:phone_number"`
+	_ struct{} `bun:"index:(hospital, date_of_birth)"`
+	_ struct{} `bun:"index:(hospital, email_bidx)"`
+	_ struct{} `bun:"index:(hospital, phone_number_bidx)"`
 	_ struct{} `bun:"index:hospital"`
 
 	CreateUpdateUnixTimestamp
 	SoftDelete
 }
+
+var _ bun.BeforeAppendModelHook = (*Patient)(nil)
+
+// BeforeAppendModel keeps the blind indexes in step with the encrypted columns
+func (p *Patient) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery, *bun.UpdateQuery:
+		p.NationalIDIndex = PatientBlindIndex("national_id", string(p.NationalID))
+		p.PassportIDIndex = PatientBlindIndex("passport_id", string(p.PassportID))
+		p.PhoneNumberIndex = PatientBlindIndex("phone_number", string(p.PhoneNumber))
+		p.EmailIndex = PatientBlindIndex("email", string(p.Email))
+	}
+	return nil
+}
+
+// PatientBlindIndex is the blind index of a value of the encrypted patient
+// column, which is in the <column>_bidx column. The value is normalized first
+// so it matches however it was typed: identifiers and phone numbers without
+// spaces and dashes, passports in upper case and emails in lower case.
+func PatientBlindIndex(column, value string) string {
+	switch column {
+	case "national_id", "phone_number":
+		value = strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, value)
+	case "passport_id":
+		value = strings.ToUpper(strings.Join(strings.Fields(value), ""))
+	case "email":
+		value = strings.ToLower(strings.TrimSpace(value))
+	}
+	return fieldcrypt.BlindIndex(column, value)
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"app/app/enum"
+
+	"github.com/uptrace/bun"
+)
+
+// PDPARequest records a data subject request served for a patient, an access
+// report or an erasure
+type PDPARequest struct {
+	bun.BaseModel `bun:"table:pdpa_requests"`
+
+	ID        string               `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Hospital  string               `bun:"hospital,notnull" json:"hospital"`
+	PatientID string               `bun:"patient_id,type:uuid,notnull" json:"patient_id"`
+	Type      enum.PDPARequestType `bun:"type,notnull" json:"type"`
+	// PatientIDs are the records the request covered, the patient and the
+	// patients merged into it
+	PatientIDs []string `bun:"patient_ids,type:uuid[],array" json:"patient_ids"`
+	Reason     string   `bun:"reason,nullzero" json:"reason"`
+	// RequestedBy is the staff member who served the request, empty from the CLI
+	RequestedBy string `bun:"requested_by,type:uuid,nullzero" json:"requested_by"`
+
+	_ struct{} `bun:"index:patient_id"`
+
+	CreateUnixTimestamp
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"github.com/uptrace/bun"
+)
+
+type Role struct {
+	bun.BaseModel `bun:"table:roles"`
+
+	Code        string `bun:"code,pk" json:"code"`
+	Name        string `bun:"name,notnull" json:"name"`
+	Description string `bun:"description" json:"description"`
+
+	CreateUpdateUnixTimestamp
+}
+
+type Permission struct {
+	bun.BaseModel `bun:"table:permissions"`
+
+	Code        string `bun:"code,pk" json:"code"`
+	Description string `bun:"description" json:"description"`
+
+	CreateUnixTimestamp
+}
+
+type RolePermission struct {
+	bun.BaseModel `bun:"table:role_permissions"`
+
+	Role       string `bun:"role,pk" json:"role"`
+	Permission string `bun:"permission,pk" json:"permission"`
+
+	CreateUnixTimestamp
+}
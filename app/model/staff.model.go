@@ -11,6 +11,7 @@ type Staff struct {
 	Username string `bun:"username,unique,notnull" json:"username"`
 	Password string `bun:"password,notnull" json:"password"`
 	Hospital string `bun:"hospital,notnull" json:"hospital"`
+	Role     string `bun:"role,notnull,default:'read_only'" json:"role"`
 
 	CreateUpdateUnixTimestamp
 	SoftDelete
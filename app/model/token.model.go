@@ -0,0 +1,35 @@
+package model
+
+import (
+	"github.com/uptrace/bun"
+)
+
+// RefreshToken is one refresh token of a login. Every refresh rotates it into a
+// new token of the same family, so replaying a rotated token revokes the family.
+type RefreshToken struct {
+	bun.BaseModel `bun:"table:refresh_tokens"`
+
+	ID         string `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	StaffID    string `bun:"staff_id,type:uuid,notnull" json:"staff_id"`
+	FamilyID   string `bun:"family_id,type:uuid,notnull" json:"family_id"`
+	TokenHash  string `bun:"token_hash,unique,notnull" json:"-"`
+	AccessUuid string `bun:"access_uuid,notnull" json:"access_uuid"`
+	ExpiresAt  int64  `bun:"expires_at,notnull" json:"expires_at"`
+	RevokedAt  int64  `bun:"revoked_at,nullzero" json:"revoked_at"`
+	ReplacedBy string `bun:"replaced_by,type:uuid,nullzero" json:"replaced_by"`
+
+	_ struct{} `bun:"index:staff_id,index:family_id"`
+
+	CreateUpdateUnixTimestamp
+}
+
+// RevokedToken blocks an access token by its uuid until it expires
+type RevokedToken struct {
+	bun.BaseModel `bun:"table:revoked_tokens"`
+
+	Uuid      string `bun:"uuid,pk" json:"uuid"`
+	StaffID   string `bun:"staff_id,type:uuid,notnull" json:"staff_id"`
+	ExpiresAt int64  `bun:"expires_at,notnull" json:"expires_at"`
+
+	CreateUnixTimestamp
+}
@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"app/app/enum"
+	"app/app/helper/testhelper"
+	"app/app/model"
+	auditdto "app/app/modules/audit/dto"
+	"app/app/util/jwt"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// AuditMockService for testing
+type AuditMockService struct {
+	mock.Mock
+}
+
+func (m *AuditMockService) Record(ctx context.Context, entry *model.AuditLog) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *AuditMockService) List(ctx context.Context, req *auditdto.ListAuditLogRequest, hospital string) ([]*model.AuditLog, int, error) {
+	args := m.Called(ctx, req, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*model.AuditLog), args.Int(1), args.Error(2)
+}
+
+const patientID = "65e08e33-9f57-45fe-b725-82242e3581ad"
+
+var claims = &jwt.Claims{Data: jwt.ClaimData{
+	ID:       "staff-1",
+	Username: "admin01",
+	Hospital: "hospital-a",
+}}
+
+// Helper functions
+func TestAuditController_List(t *testing.T) {
+	t.Run("Success - Filtered by patient in the caller's hospital", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(AuditMockService)
+		mockService.On("List", mock.Anything, mock.MatchedBy(func(req *auditdto.ListAuditLogRequest) bool {
+			return req.PatientID == patientID && req.Action == enum.AUDIT_PATIENT_READ && req.Page == 1 && req.Size == 50
+		}), "hospital-a").Return([]*model.AuditLog{{ID: 1, PatientIDs: []string{patientID}}}, 1, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/audit?patient_id="+patientID+"&action=patient.read", nil, claims)
+		controller.List(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), patientID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Patient id is not a uuid", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(AuditMockService)
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/audit?patient_id=1103702071811", nil, claims)
+		controller.List(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "List")
+	})
+
+	t.Run("Fail - Service error", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(AuditMockService)
+		mockService.On("List", mock.Anything, mock.Anything, "hospital-a").Return(nil, 0, errors.New("db down"))
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/audit", nil, claims)
+		controller.List(c)
+
+		assert.Equal(t, 500, w.Code)
+	})
+}
+
+func TestFilter(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	query := db.NewSelect().Model((*model.AuditLog)(nil))
+	filter(query, &auditdto.ListAuditLogRequest{
+		PatientID: patientID,
+		StaffID:   "0b4ba3ff-2f57-4ab0-9b1b-2f6e1d0d7a53",
+		From:      1700000000,
+	})
+	sql := query.String()
+	assert.Contains(t, sql, `(patient_ids @> '{"`+patientID+`"}')`)
+	assert.Contains(t, sql, `(staff_id = '0b4ba3ff-2f57-4ab0-9b1b-2f6e1d0d7a53')`)
+	assert.Contains(t, sql, `(created_at >= 1700000000)`)
+	assert.NotContains(t, sql, "action =")
+}
@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"app/app/helper"
+	"app/app/message"
+	auditdto "app/app/modules/audit/dto"
+	"app/app/response"
+	"app/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller struct {
+	Service ServiceInterface
+}
+
+func NewController(svc ServiceInterface) *Controller {
+	return &Controller{
+		Service: svc,
+	}
+}
+
+func (c *Controller) List(ctx *gin.Context) {
+	req := auditdto.ListAuditLogRequest{
+		Page: 1,
+		Size: 50,
+	}
+	if err := ctx.BindQuery(&req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	req.Size = response.PageSize(req.Size)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, total, err := c.Service.List(ctx, &req, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	response.SuccessWithPaginate(ctx, data, req.Page, req.Size, total)
+}
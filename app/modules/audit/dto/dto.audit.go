@@ -0,0 +1,15 @@
+package auditdto
+
+import "app/app/enum"
+
+// ListAuditLogRequest filters the audit log of the caller's hospital, From and
+// To are unix seconds
+type ListAuditLogRequest struct {
+	Page      int              `form:"page"`
+	Size      int              `form:"size"`
+	PatientID string           `form:"patient_id" binding:"omitempty,uuid"`
+	StaffID   string           `form:"staff_id" binding:"omitempty,uuid"`
+	Action    enum.AuditAction `form:"action"`
+	From      int64            `form:"from"`
+	To        int64            `form:"to"`
+}
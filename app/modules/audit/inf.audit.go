@@ -0,0 +1,14 @@
+package audit
+
+import (
+	"app/app/model"
+	auditdto "app/app/modules/audit/dto"
+	"context"
+)
+
+type ServiceInterface interface {
+	Record(ctx context.Context, entry *model.AuditLog) error
+	List(ctx context.Context, req *auditdto.ListAuditLogRequest, hospital string) ([]*model.AuditLog, int, error)
+}
+
+var _ ServiceInterface = (*Service)(nil)
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"app/app/model"
+	auditdto "app/app/modules/audit/dto"
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// purgeBatchSize is how many entries Purge deletes per transaction
+const purgeBatchSize = 10000
+
+type Service struct {
+	db *bun.DB
+}
+
+func NewService(db *bun.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+func (s *Service) Record(ctx context.Context, entry *model.AuditLog) error {
+	_, err := s.db.NewInsert().Model(entry).Exec(ctx)
+	return err
+}
+
+// List returns the hospital's audit log, newest first
+func (s *Service) List(ctx context.Context, req *auditdto.ListAuditLogRequest, hospital string) ([]*model.AuditLog, int, error) {
+	resp := []*model.AuditLog{}
+	query := s.db.NewSelect().
+		Model(&resp).
+		Where("hospital = ?", hospital)
+	filter(query, req)
+
+	total, err := query.
+		Order("created_at DESC", "id DESC").
+		Offset((req.Page - 1) * req.Size).
+		Limit(req.Size).
+		ScanAndCount(ctx)
+	if err != nil {
+		return resp, 0, err
+	}
+	return resp, total, nil
+}
+
+func filter(query *bun.SelectQuery, req *auditdto.ListAuditLogRequest) {
+	if req.PatientID != "" {
+		// containment rather than ANY() so audit_logs_patient_ids_idx is used
+		query.Where("patient_ids @> ?", pgdialect.Array([]string{req.PatientID}))
+	}
+	if req.StaffID != "" {
+		query.Where("staff_id = ?", req.StaffID)
+	}
+	if req.Action != "" {
+		query.Where("action = ?", req.Action)
+	}
+	if req.From > 0 {
+		query.Where("created_at >= ?", req.From)
+	}
+	if req.To > 0 {
+		query.Where("created_at < ?", req.To)
+	}
+}
+
+// Purge deletes the entries older than before, a batch per transaction. Only
+// these transactions set app.audit_purge, which the append-only trigger of
+// audit_logs requires for a delete.
+func (s *Service) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		var deleted int64
+		err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			if _, err := tx.ExecContext(ctx, "SET LOCAL app.audit_purge = 'on'"); err != nil {
+				return err
+			}
+			batch := tx.NewSelect().
+				Model((*model.AuditLog)(nil)).
+				Column("id").
+				Where("created_at < ?", before.Unix()).
+				Limit(purgeBatchSize)
+			res, err := tx.NewDelete().
+				Model((*model.AuditLog)(nil)).
+				Where("id IN (?)", batch).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+			deleted, err = res.RowsAffected()
+			return err
+		})
+		total += deleted
+		if err != nil || deleted < purgeBatchSize {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package consent
+
+import (
+	"app/app/enum"
+	"app/app/model"
+	consentdto "app/app/modules/consent/dto"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestGranted(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	query := Granted(db, "p-1", enum.CONSENT_DATA_SHARING, "hospital-b", enum.PURPOSE_TREATMENT).String()
+	assert.Contains(t, query, "patient_consent.patient_id = 'p-1'")
+	assert.Contains(t, query, "patient_consent.type = 'data_sharing'")
+	assert.Contains(t, query, "patient_consent.scope IN ('*', 'hospital-b')")
+	assert.Contains(t, query, "'treatment' = ANY(patient_consent.purposes)")
+	assert.Contains(t, query, "patient_consent.revoked_at IS NULL")
+	assert.Contains(t, query, "patient_consent.valid_until > EXTRACT(EPOCH FROM NOW())")
+}
+
+func TestTypeOf(t *testing.T) {
+	assert.Equal(t, enum.CONSENT_RESEARCH, TypeOf(enum.PURPOSE_RESEARCH))
+	assert.Equal(t, enum.CONSENT_DATA_SHARING, TypeOf(enum.PURPOSE_TREATMENT))
+	assert.Equal(t, enum.CONSENT_DATA_SHARING, TypeOf(""))
+}
+
+func TestToConsentResponse(t *testing.T) {
+	cases := map[string]struct {
+		consent *model.PatientConsent
+		status  string
+	}{
+		"Without an end":   {&model.PatientConsent{ValidFrom: 100}, consentdto.StatusActive},
+		"Not started yet":  {&model.PatientConsent{ValidFrom: 300}, consentdto.StatusScheduled},
+		"Past its end":     {&model.PatientConsent{ValidFrom: 100, ValidUntil: 200}, consentdto.StatusExpired},
+		"Withdrawn before": {&model.PatientConsent{ValidFrom: 100, ValidUntil: 200, RevokedAt: 150}, consentdto.StatusWithdrawn},
+	}
+	for name, tc := range cases {
+		assert.Equal(t, tc.status, ToConsentResponse(tc.consent, 250).Status, name)
+	}
+}
+
+func TestService_Allowed(t *testing.T) {
+	// the patient's own hospital needs no consent, nothing is queried
+	s := NewService(nil)
+	ok, err := s.Allowed(context.Background(), &model.Patient{ID: "p-1", Hospital: "hospital-a"}, "hospital-a", enum.PURPOSE_RESEARCH)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,193 @@
+package consent
+
+import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	consentdto "app/app/modules/consent/dto"
+	"app/app/util/jwt"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ConsentMockService for testing
+type ConsentMockService struct {
+	mock.Mock
+}
+
+func (m *ConsentMockService) List(ctx context.Context, id string, req *consentdto.ListConsentRequest, hospital string) ([]*model.PatientConsent, error) {
+	args := m.Called(ctx, id, req, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.PatientConsent), args.Error(1)
+}
+
+func (m *ConsentMockService) Capture(ctx context.Context, id string, req *consentdto.CreateConsentRequest, hospital, staffID string) (*model.PatientConsent, error) {
+	args := m.Called(ctx, id, req, hospital, staffID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PatientConsent), args.Error(1)
+}
+
+func (m *ConsentMockService) Withdraw(ctx context.Context, id, consentID string, req *consentdto.WithdrawConsentRequest, hospital, staffID string) (*model.PatientConsent, error) {
+	args := m.Called(ctx, id, consentID, req, hospital, staffID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PatientConsent), args.Error(1)
+}
+
+func (m *ConsentMockService) Check(ctx context.Context, patientID string, consentType enum.ConsentType, recipient string, purpose enum.Purpose) (bool, error) {
+	args := m.Called(ctx, patientID, consentType, recipient, purpose)
+	return args.Bool(0), args.Error(1)
+}
+
+const (
+	patientID = "0b9e8f3a-4c2d-4f51-9a47-6f1d2c3b4a59"
+	consentID = "65e08e33-9f57-45fe-b725-82242e3581ad"
+)
+
+var claims = &jwt.Claims{Data: jwt.ClaimData{
+	ID:          "staff-1",
+	Hospital:    "hospital-a",
+	Role:        "registrar",
+	Permissions: []string{"patient:read", "consent:manage"},
+}}
+
+func TestConsentController_List(t *testing.T) {
+	t.Run("Success - Consents with their status", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(ConsentMockService)
+		mockService.On("List", mock.Anything, patientID, &consentdto.ListConsentRequest{Type: enum.CONSENT_RESEARCH}, "hospital-a").
+			Return([]*model.PatientConsent{{ID: consentID, PatientID: patientID, Type: enum.CONSENT_RESEARCH, Scope: "*", ValidFrom: 1, RevokedAt: 2}}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/"+patientID+"/consents?type=research", nil, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.List(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"withdrawn"`)
+		assert.Equal(t, []string{patientID}, helper.GetAuditPatients(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Unknown type", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(ConsentMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/"+patientID+"/consents?type=marketing", nil, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.List(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestConsentController_Capture(t *testing.T) {
+	req := &consentdto.CreateConsentRequest{
+		Type:     enum.CONSENT_DATA_SHARING,
+		Scope:    "hospital-b",
+		Purposes: []enum.Purpose{enum.PURPOSE_TREATMENT},
+		Evidence: "Signed form F-12",
+	}
+
+	t.Run("Success - Consent captured", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(ConsentMockService)
+		mockService.On("Capture", mock.Anything, patientID, req, "hospital-a", "staff-1").
+			Return(&model.PatientConsent{ID: consentID, PatientID: patientID, Type: req.Type, Scope: req.Scope, Purposes: req.Purposes, ValidFrom: 1}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/consents", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Capture(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"active"`)
+		assert.Contains(t, w.Body.String(), `"purposes":["treatment"]`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Period ends before it starts", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(ConsentMockService)
+		mockService.On("Capture", mock.Anything, patientID, req, "hospital-a", "staff-1").Return(nil, errors.New(message.ConsentInvalidPeriod))
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/consents", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Capture(c)
+
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), message.ConsentInvalidPeriod)
+	})
+
+	t.Run("Fail - Unknown purpose", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(ConsentMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/consents", map[string]any{"type": "research", "purposes": []string{"marketing"}}, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Capture(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "Capture", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestConsentController_Withdraw(t *testing.T) {
+	req := &consentdto.WithdrawConsentRequest{Reason: "Patient asked by phone"}
+
+	t.Run("Success - Consent withdrawn", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(ConsentMockService)
+		mockService.On("Withdraw", mock.Anything, patientID, consentID, req, "hospital-a", "staff-1").
+			Return(&model.PatientConsent{ID: consentID, PatientID: patientID, ValidFrom: 1, RevokedAt: 2, RevokeReason: req.Reason}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/consents/"+consentID+"/withdraw", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}, {Key: "consent_id", Value: consentID}}
+		controller.Withdraw(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"withdrawn"`)
+		assert.Equal(t, req.Reason, helper.GetAuditReason(c))
+		mockService.AssertExpectations(t)
+	})
+
+	cases := map[string]struct {
+		err  string
+		code int
+	}{
+		"Fail - Already withdrawn": {message.ConsentWithdrawn, 400},
+		"Fail - Consent not found": {message.ConsentNotFound, 404},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			testhelper.UseJSONNaming(t)
+			mockService := new(ConsentMockService)
+			mockService.On("Withdraw", mock.Anything, patientID, consentID, req, "hospital-a", "staff-1").Return(nil, errors.New(tc.err))
+
+			controller := NewController(mockService)
+			c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/consents/"+consentID+"/withdraw", req, claims)
+			c.Params = gin.Params{{Key: "id", Value: patientID}, {Key: "consent_id", Value: consentID}}
+			controller.Withdraw(c)
+
+			assert.Equal(t, tc.code, w.Code)
+			assert.Contains(t, w.Body.String(), tc.err)
+		})
+	}
+}
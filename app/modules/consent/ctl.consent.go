@@ -0,0 +1,126 @@
+package consent
+
+import (
+	"app/app/helper"
+	"app/app/message"
+	consentdto "app/app/modules/consent/dto"
+	"app/app/response"
+	"app/internal/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller struct {
+	Service ServiceInterface
+}
+
+func NewController(svc ServiceInterface) *Controller {
+	return &Controller{
+		Service: svc,
+	}
+}
+
+// List lists the consents of the patient of the uri with where they stand
+func (c *Controller) List(ctx *gin.Context) {
+	id := new(consentdto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := new(consentdto.ListConsentRequest)
+	if err := ctx.BindQuery(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, err := c.Service.List(ctx, id.ID, req, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	now := time.Now().Unix()
+	resp := make([]consentdto.ConsentResponse, 0, len(data))
+	for _, consent := range data {
+		resp = append(resp, ToConsentResponse(consent, now))
+	}
+	response.Success(ctx, resp)
+}
+
+// Capture records a consent the patient of the uri gave
+func (c *Controller) Capture(ctx *gin.Context) {
+	id := new(consentdto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := new(consentdto.CreateConsentRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, err := c.Service.Capture(ctx, id.ID, req, user.Data.Hospital, user.Data.ID)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, ToConsentResponse(data, time.Now().Unix()))
+}
+
+// Withdraw revokes a consent of the patient of the uri
+func (c *Controller) Withdraw(ctx *gin.Context) {
+	id := new(consentdto.ConsentIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := new(consentdto.WithdrawConsentRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	if req.Reason != "" {
+		helper.AuditReason(ctx, req.Reason)
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, err := c.Service.Withdraw(ctx, id.ID, id.ConsentID, req, user.Data.Hospital, user.Data.ID)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, ToConsentResponse(data, time.Now().Unix()))
+}
+
+// respondError maps the message of a service error onto its status code
+func respondError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case message.PatientNotFound, message.ConsentNotFound:
+		response.NotFound(ctx, err.Error(), nil)
+	case message.ConsentWithdrawn, message.ConsentInvalidPeriod, message.HospitalNotFound:
+		response.BadRequest(ctx, err.Error(), nil)
+	default:
+		response.InternalError(ctx, err.Error(), nil)
+	}
+}
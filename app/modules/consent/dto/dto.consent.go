@@ -0,0 +1,52 @@
+package consentdto
+
+import (
+	"app/app/enum"
+	"app/app/model"
+)
+
+type PatientIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type ConsentIDRequest struct {
+	ID        string `uri:"id" binding:"required,uuid"`
+	ConsentID string `uri:"consent_id" binding:"required,uuid"`
+}
+
+// ListConsentRequest lists the consents of a patient, Active leaves out the
+// ones not in force
+type ListConsentRequest struct {
+	Type   enum.ConsentType `form:"type" binding:"omitempty,oneof=data_sharing research sms_contact"`
+	Active bool             `form:"active"`
+}
+
+// CreateConsentRequest captures a consent. Scope defaults to every hospital,
+// ValidFrom to now and ValidUntil to no end.
+type CreateConsentRequest struct {
+	Type       enum.ConsentType `json:"type" binding:"required,oneof=data_sharing research sms_contact"`
+	Scope      string           `json:"scope" binding:"max=100"`
+	Purposes   []enum.Purpose   `json:"purposes" binding:"max=4,dive,oneof=treatment payment operations research"`
+	ValidFrom  int64            `json:"valid_from" binding:"gte=0"`
+	ValidUntil int64            `json:"valid_until" binding:"gte=0"`
+	Evidence   string           `json:"evidence" binding:"max=500"`
+}
+
+// WithdrawConsentRequest withdraws a consent, the reason is kept with it
+type WithdrawConsentRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// The statuses of a consent
+const (
+	StatusActive    = "active"
+	StatusScheduled = "scheduled"
+	StatusExpired   = "expired"
+	StatusWithdrawn = "withdrawn"
+)
+
+// ConsentResponse is a consent with where it stands now
+type ConsentResponse struct {
+	*model.PatientConsent
+	Status string `json:"status"`
+}
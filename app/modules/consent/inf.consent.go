@@ -0,0 +1,17 @@
+package consent
+
+import (
+	"app/app/enum"
+	"app/app/model"
+	consentdto "app/app/modules/consent/dto"
+	"context"
+)
+
+type ServiceInterface interface {
+	List(ctx context.Context, id string, req *consentdto.ListConsentRequest, hospital string) ([]*model.PatientConsent, error)
+	Capture(ctx context.Context, id string, req *consentdto.CreateConsentRequest, hospital, staffID string) (*model.PatientConsent, error)
+	Withdraw(ctx context.Context, id, consentID string, req *consentdto.WithdrawConsentRequest, hospital, staffID string) (*model.PatientConsent, error)
+	Check(ctx context.Context, patientID string, consentType enum.ConsentType, recipient string, purpose enum.Purpose) (bool, error)
+}
+
+var _ ServiceInterface = (*Service)(nil)
@@ -0,0 +1,219 @@
+package consent
+
+import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/model"
+	consentdto "app/app/modules/consent/dto"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+type Service struct {
+	db *bun.DB
+}
+
+func NewService(db *bun.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// inForce narrows a query on patient_consents to the consents not withdrawn
+// whose period covers now
+func inForce(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.
+		Where("patient_consent.revoked_at IS NULL").
+		Where("patient_consent.valid_from <= EXTRACT(EPOCH FROM NOW())").
+		Where("(patient_consent.valid_until IS NULL OR patient_consent.valid_until > EXTRACT(EPOCH FROM NOW()))")
+}
+
+// Granted selects the consents in force of the type the patient gave to the
+// recipient hospital for the purpose, for use in EXISTS. The patient and
+// recipient are values or columns of the outer query, e.g. bun.Ident("patient.id").
+// A consent without purposes covers every purpose, one with purposes does not
+// cover an unknown purpose.
+func Granted(db bun.IDB, patient any, consentType enum.ConsentType, recipient any, purpose enum.Purpose) *bun.SelectQuery {
+	return inForce(db.NewSelect().
+		Model((*model.PatientConsent)(nil)).
+		ColumnExpr("1").
+		Where("patient_consent.patient_id = ?", patient).
+		Where("patient_consent.type = ?", consentType).
+		Where("patient_consent.scope IN (?, ?)", enum.ConsentScopeAll, recipient).
+		Where("(COALESCE(CARDINALITY(patient_consent.purposes), 0) = 0 OR ? = ANY(patient_consent.purposes))", purpose))
+}
+
+// Check tells whether the patient consents to the use of the type by the
+// recipient hospital for the purpose, the hook other operations consult
+// before sharing data
+func (s *Service) Check(ctx context.Context, patientID string, consentType enum.ConsentType, recipient string, purpose enum.Purpose) (bool, error) {
+	return Granted(s.db, patientID, consentType, recipient, purpose).Exists(ctx)
+}
+
+// Allowed tells whether the patient agreed to their record being disclosed to
+// the hospital for the purpose: research needs a research consent, any other
+// purpose a data sharing one. The patient's own hospital needs none.
+func (s *Service) Allowed(ctx context.Context, patient *model.Patient, hospital string, purpose enum.Purpose) (bool, error) {
+	if patient.Hospital == hospital {
+		return true, nil
+	}
+	return s.Check(ctx, patient.ID, TypeOf(purpose), hospital, purpose)
+}
+
+// TypeOf is the consent disclosing a record for the purpose needs
+func TypeOf(purpose enum.Purpose) enum.ConsentType {
+	if purpose == enum.PURPOSE_RESEARCH {
+		return enum.CONSENT_RESEARCH
+	}
+	return enum.CONSENT_DATA_SHARING
+}
+
+// patient makes sure the live patient is one of the hospital's
+func (s *Service) patient(ctx context.Context, id string, hospital string) error {
+	ok, err := s.db.NewSelect().
+		Model((*model.Patient)(nil)).
+		Where("id = ?", id).
+		Where("hospital = ?", hospital).
+		Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New(message.PatientNotFound)
+	}
+	return nil
+}
+
+// List lists the consents of the patient of the hospital, newest first
+func (s *Service) List(ctx context.Context, id string, req *consentdto.ListConsentRequest, hospital string) ([]*model.PatientConsent, error) {
+	if err := s.patient(ctx, id, hospital); err != nil {
+		return nil, err
+	}
+	resp := []*model.PatientConsent{}
+	query := s.db.NewSelect().
+		Model(&resp).
+		Where("patient_consent.patient_id = ?", id).
+		Where("patient_consent.hospital = ?", hospital)
+	if req.Type != "" {
+		query.Where("patient_consent.type = ?", req.Type)
+	}
+	if req.Active {
+		inForce(query)
+	}
+	err := query.
+		Order("patient_consent.created_at DESC", "patient_consent.id DESC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Capture records a consent the patient of the hospital gave
+func (s *Service) Capture(ctx context.Context, id string, req *consentdto.CreateConsentRequest, hospital, staffID string) (*model.PatientConsent, error) {
+	if err := s.patient(ctx, id, hospital); err != nil {
+		return nil, err
+	}
+	consent := &model.PatientConsent{
+		Hospital:   hospital,
+		PatientID:  id,
+		Type:       req.Type,
+		Scope:      req.Scope,
+		Purposes:   req.Purposes,
+		ValidFrom:  req.ValidFrom,
+		ValidUntil: req.ValidUntil,
+		Evidence:   req.Evidence,
+		RecordedBy: staffID,
+	}
+	if consent.Scope == "" {
+		consent.Scope = enum.ConsentScopeAll
+	}
+	if consent.ValidFrom == 0 {
+		consent.ValidFrom = time.Now().Unix()
+	}
+	if consent.ValidUntil != 0 && consent.ValidUntil <= consent.ValidFrom {
+		return nil, errors.New(message.ConsentInvalidPeriod)
+	}
+	if consent.Scope != enum.ConsentScopeAll {
+		ok, err := s.db.NewSelect().
+			Model((*model.Hospital)(nil)).
+			Where("code = ?", consent.Scope).
+			Exists(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New(message.HospitalNotFound)
+		}
+	}
+
+	_, err := s.db.NewInsert().
+		Model(consent).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// Withdraw revokes a consent of the patient of the hospital, it is kept with
+// the time, staff member and reason
+func (s *Service) Withdraw(ctx context.Context, id, consentID string, req *consentdto.WithdrawConsentRequest, hospital, staffID string) (*model.PatientConsent, error) {
+	consent := new(model.PatientConsent)
+	err := s.db.NewSelect().
+		Model(consent).
+		Where("id = ?", consentID).
+		Where("patient_id = ?", id).
+		Where("hospital = ?", hospital).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.ConsentNotFound)
+		}
+		return nil, err
+	}
+	if consent.RevokedAt != 0 {
+		return nil, errors.New(message.ConsentWithdrawn)
+	}
+
+	consent.RevokedAt = time.Now().Unix()
+	consent.RevokedBy = staffID
+	consent.RevokeReason = req.Reason
+	consent.SetUpdateNow()
+	res, err := s.db.NewUpdate().
+		Model(consent).
+		Column("revoked_at", "revoked_by", "revoke_reason", "updated_at").
+		WherePK().
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// withdrawn by someone else in the meantime
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return nil, errors.New(message.ConsentWithdrawn)
+	}
+	return consent, nil
+}
+
+// ToConsentResponse is the consent with where it stands at the unix time
+func ToConsentResponse(consent *model.PatientConsent, at int64) consentdto.ConsentResponse {
+	status := consentdto.StatusActive
+	switch {
+	case consent.RevokedAt != 0:
+		status = consentdto.StatusWithdrawn
+	case consent.ValidUntil != 0 && at >= consent.ValidUntil:
+		status = consentdto.StatusExpired
+	case at < consent.ValidFrom:
+		status = consentdto.StatusScheduled
+	}
+	return consentdto.ConsentResponse{
+		PatientConsent: consent,
+		Status:         status,
+	}
+}
@@ -0,0 +1,241 @@
+package fhirapi
+
+import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/message"
+	"app/app/model"
+	fhirapidto "app/app/modules/fhirapi/dto"
+	"app/app/util/fhir"
+	"app/app/util/jwt"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// FHIRMockService for testing
+type FHIRMockService struct {
+	mock.Mock
+}
+
+func (m *FHIRMockService) Read(ctx context.Context, id string, hospital string, purpose enum.Purpose) (*model.Patient, error) {
+	args := m.Called(ctx, id, hospital, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Patient), args.Error(1)
+}
+
+func (m *FHIRMockService) Search(ctx context.Context, req *fhirapidto.SearchPatientRequest, hospital string) ([]*model.Patient, int, error) {
+	args := m.Called(ctx, req, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*model.Patient), args.Int(1), args.Error(2)
+}
+
+const patientID = "65e08e33-9f57-45fe-b725-82242e3581ad"
+
+var claims = &jwt.Claims{Data: jwt.ClaimData{
+	Hospital:    "hospital-a",
+	Permissions: []string{"patient:read", "patient:read_sensitive"},
+}}
+
+func mockPatient() *model.Patient {
+	patient := &model.Patient{
+		ID:          patientID,
+		PatientHN:   "HN2024000001",
+		NationalID:  "1103702071811",
+		FirstNameTH: "สมชาย",
+		LastNameTH:  "ใจดี",
+		FirstNameEN: "Somchai",
+		LastNameEN:  "Jaidee",
+		DateOfBirth: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC),
+		Gender:      "1",
+		PhoneNumber: "0812345678",
+	}
+	patient.UpdatedAt = 1700000000
+	return patient
+}
+
+// Helper functions
+func createFHIRContext(method, target string, claims *jwt.Claims) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, nil)
+	if claims != nil {
+		helper.SetUserInClaims(c, claims)
+	}
+	return c, w
+}
+
+func outcome(t *testing.T, w *httptest.ResponseRecorder) fhir.OperationOutcome {
+	resp := fhir.OperationOutcome{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "OperationOutcome", resp.ResourceType)
+	require.Len(t, resp.Issue, 1)
+	return resp
+}
+
+func TestFHIRController_ReadPatient(t *testing.T) {
+	t.Run("Success - Patient resource", func(t *testing.T) {
+		mockService := new(FHIRMockService)
+		mockService.On("Read", mock.Anything, patientID, "hospital-a", mock.Anything).Return(mockPatient(), nil)
+
+		controller := NewController(mockService)
+		c, w := createFHIRContext("GET", "/api/v1/fhir/Patient/"+patientID, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.ReadPatient(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/fhir+json; charset=utf-8", w.Header().Get("Content-Type"))
+		resp := fhir.Patient{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "Patient", resp.ResourceType)
+		assert.Equal(t, patientID, resp.ID)
+		assert.Equal(t, "male", resp.Gender)
+		assert.Equal(t, "1990-05-17", resp.BirthDate)
+		assert.Equal(t, "2023-11-14T22:13:20Z", resp.Meta.LastUpdated)
+		assert.Len(t, resp.Identifier, 2)
+		assert.Len(t, resp.Name, 2)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Success - Masked without patient:read_sensitive", func(t *testing.T) {
+		mockService := new(FHIRMockService)
+		mockService.On("Read", mock.Anything, patientID, "hospital-a", mock.Anything).Return(mockPatient(), nil)
+
+		controller := NewController(mockService)
+		c, w := createFHIRContext("GET", "/api/v1/fhir/Patient/"+patientID, &jwt.Claims{Data: jwt.ClaimData{
+			Hospital:    "hospital-a",
+			Permissions: []string{"patient:read"},
+		}})
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.ReadPatient(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.NotContains(t, w.Body.String(), "1103702071811")
+		assert.Contains(t, w.Body.String(), `"birthDate":"1990"`)
+	})
+
+	t.Run("Fail - Not found is an OperationOutcome", func(t *testing.T) {
+		mockService := new(FHIRMockService)
+		mockService.On("Read", mock.Anything, "missing", "hospital-a", mock.Anything).Return(nil, errors.New(message.PatientNotFound))
+
+		controller := NewController(mockService)
+		c, w := createFHIRContext("GET", "/api/v1/fhir/Patient/missing", claims)
+		c.Params = gin.Params{{Key: "id", Value: "missing"}}
+		controller.ReadPatient(c)
+
+		assert.Equal(t, 404, w.Code)
+		resp := outcome(t, w)
+		assert.Equal(t, fhir.IssueNotFound, resp.Issue[0].Code)
+		assert.Equal(t, message.PatientNotFound, resp.Issue[0].Details.Text)
+	})
+
+	t.Run("Fail - No token", func(t *testing.T) {
+		controller := NewController(new(FHIRMockService))
+		c, w := createFHIRContext("GET", "/api/v1/fhir/Patient/"+patientID, nil)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.ReadPatient(c)
+
+		assert.Equal(t, 401, w.Code)
+		assert.Equal(t, fhir.IssueLogin, outcome(t, w).Issue[0].Code)
+	})
+}
+
+func TestFHIRController_SearchPatient(t *testing.T) {
+	t.Run("Success - Searchset Bundle with paging links", func(t *testing.T) {
+		mockService := new(FHIRMockService)
+		mockService.On("Search", mock.Anything, mock.MatchedBy(func(req *fhirapidto.SearchPatientRequest) bool {
+			return req.Count == 1 && req.Offset == 1 && len(req.Criteria) == 1 && req.Criteria[0].Name == "family"
+		}), "hospital-a").Return([]*model.Patient{mockPatient()}, 3, nil)
+
+		controller := NewController(mockService)
+		c, w := createFHIRContext("GET", "/api/v1/fhir/Patient?family=jai&_count=1&_offset=1", claims)
+		c.Request.Header.Set("X-Forwarded-Proto", "https")
+		controller.SearchPatient(c)
+
+		assert.Equal(t, 200, w.Code)
+		bundle := fhir.Bundle{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bundle))
+		assert.Equal(t, "searchset", bundle.Type)
+		assert.Equal(t, 3, *bundle.Total)
+		require.Len(t, bundle.Entry, 1)
+		assert.Equal(t, "https://example.com/api/v1/fhir/Patient/"+patientID, bundle.Entry[0].FullURL)
+		assert.Equal(t, "match", bundle.Entry[0].Search.Mode)
+
+		links := map[string]string{}
+		for _, link := range bundle.Link {
+			links[link.Relation] = link.URL
+		}
+		assert.Equal(t, "https://example.com/api/v1/fhir/Patient?_count=1&_offset=1&family=jai", links["self"])
+		assert.Equal(t, "https://example.com/api/v1/fhir/Patient?_count=1&_offset=2&family=jai", links["next"])
+		assert.Equal(t, "https://example.com/api/v1/fhir/Patient?_count=1&_offset=0&family=jai", links["previous"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Success - POST _search reads the form", func(t *testing.T) {
+		mockService := new(FHIRMockService)
+		mockService.On("Search", mock.Anything, mock.MatchedBy(func(req *fhirapidto.SearchPatientRequest) bool {
+			return len(req.Criteria) == 1 && req.Criteria[0].Values[0] == "1103702071811"
+		}), "hospital-a").Return([]*model.Patient{}, 0, nil)
+
+		controller := NewController(mockService)
+		c, w := createFHIRContext("POST", "/api/v1/fhir/Patient/_search", claims)
+		c.Request = httptest.NewRequest("POST", "/api/v1/fhir/Patient/_search", strings.NewReader(url.Values{"identifier": {"1103702071811"}}.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		helper.SetUserInClaims(c, claims)
+		controller.SearchPatient(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.NotContains(t, w.Body.String(), `"entry"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Unknown parameter", func(t *testing.T) {
+		mockService := new(FHIRMockService)
+		controller := NewController(mockService)
+		c, w := createFHIRContext("GET", "/api/v1/fhir/Patient?adress=bangkok", claims)
+		controller.SearchPatient(c)
+
+		assert.Equal(t, 400, w.Code)
+		assert.Equal(t, fhir.IssueNotSupported, outcome(t, w).Issue[0].Code)
+		mockService.AssertNotCalled(t, "Search")
+	})
+
+	t.Run("Fail - Invalid birthdate", func(t *testing.T) {
+		controller := NewController(new(FHIRMockService))
+		c, w := createFHIRContext("GET", "/api/v1/fhir/Patient?birthdate=17/05/1990", claims)
+		controller.SearchPatient(c)
+
+		assert.Equal(t, 400, w.Code)
+		resp := outcome(t, w)
+		assert.Equal(t, fhir.IssueInvalid, resp.Issue[0].Code)
+		assert.Contains(t, resp.Issue[0].Diagnostics, "birthdate")
+	})
+
+	t.Run("Fail - Service error", func(t *testing.T) {
+		mockService := new(FHIRMockService)
+		mockService.On("Search", mock.Anything, mock.Anything, "hospital-a").Return(nil, 0, errors.New("db down"))
+
+		controller := NewController(mockService)
+		c, w := createFHIRContext("GET", "/api/v1/fhir/Patient", claims)
+		controller.SearchPatient(c)
+
+		assert.Equal(t, 500, w.Code)
+		assert.Equal(t, fhir.IssueException, outcome(t, w).Issue[0].Code)
+		assert.NotContains(t, w.Body.String(), "db down")
+	})
+}
@@ -0,0 +1,186 @@
+package fhirapi
+
+import (
+	"app/app/helper"
+	"app/app/message"
+	"app/app/model"
+	fhirapidto "app/app/modules/fhirapi/dto"
+	"app/app/modules/patient"
+	"app/app/response"
+	"app/app/util/fhir"
+	"app/app/util/pii"
+	"app/internal/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contentType is the FHIR JSON media type
+const contentType = "application/fhir+json; charset=utf-8"
+
+type Controller struct {
+	Service ServiceInterface
+}
+
+func NewController(svc ServiceInterface) *Controller {
+	return &Controller{
+		Service: svc,
+	}
+}
+
+// ReadPatient answers GET [base]/Patient/:id with the Patient resource
+func (c *Controller) ReadPatient(ctx *gin.Context) {
+	req := new(fhirapidto.ReadPatientRequest)
+	if err := ctx.BindUri(req); err != nil {
+		respondOutcome(ctx, http.StatusBadRequest, fhir.IssueInvalid, message.InvalidRequest, err.Error())
+		return
+	}
+	user, _ := helper.GetUserByToken(ctx)
+	if user == nil {
+		respondOutcome(ctx, http.StatusUnauthorized, fhir.IssueLogin, message.Unauthorized, "")
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		respondOutcome(ctx, http.StatusBadRequest, fhir.IssueInvalid, err.Error(), "")
+		return
+	}
+	data, err := c.Service.Read(ctx, req.ID, user.Data.Hospital, viewer.Purpose)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	helper.AuditPatients(ctx, data.ID)
+	respond(ctx, http.StatusOK, toResource(data, viewer))
+}
+
+// SearchPatient answers GET [base]/Patient?... and POST [base]/Patient/_search
+// with a searchset Bundle, paged by _count and _offset
+func (c *Controller) SearchPatient(ctx *gin.Context) {
+	if err := ctx.Request.ParseForm(); err != nil {
+		respondOutcome(ctx, http.StatusBadRequest, fhir.IssueInvalid, message.InvalidRequest, err.Error())
+		return
+	}
+	params := ctx.Request.Form
+	req, err := fhirapidto.ParseSearch(params)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	req.Count = pageCount(req.Count)
+	user, _ := helper.GetUserByToken(ctx)
+	if user == nil {
+		respondOutcome(ctx, http.StatusUnauthorized, fhir.IssueLogin, message.Unauthorized, "")
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		respondOutcome(ctx, http.StatusBadRequest, fhir.IssueInvalid, err.Error(), "")
+		return
+	}
+	req.Purpose = viewer.Purpose
+	data, total, err := c.Service.Search(ctx, req, user.Data.Hospital)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	base := baseURL(ctx)
+	bundle := fhir.Bundle{
+		ResourceType: "Bundle",
+		Type:         "searchset",
+		Total:        &total,
+		Link:         []fhir.BundleLink{{Relation: "self", URL: pageURL(base, params, req.Count, req.Offset)}},
+	}
+	if req.Count > 0 && req.Offset+req.Count < total {
+		bundle.Link = append(bundle.Link, fhir.BundleLink{Relation: "next", URL: pageURL(base, params, req.Count, req.Offset+req.Count)})
+	}
+	if req.Count > 0 && req.Offset > 0 {
+		bundle.Link = append(bundle.Link, fhir.BundleLink{Relation: "previous", URL: pageURL(base, params, req.Count, max(req.Offset-req.Count, 0))})
+	}
+	for _, p := range data {
+		helper.AuditPatients(ctx, p.ID)
+		resource, err := json.Marshal(toResource(p, viewer))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		bundle.Entry = append(bundle.Entry, fhir.BundleEntry{
+			FullURL:  base + "/Patient/" + p.ID,
+			Resource: resource,
+			Search:   &fhir.BundleSearch{Mode: "match"},
+		})
+	}
+	respond(ctx, http.StatusOK, bundle)
+}
+
+func toResource(p *model.Patient, viewer pii.Viewer) fhir.Patient {
+	return fhir.FromRecord(patient.ToPatientRecord(p).Shape(viewer))
+}
+
+// pageCount keeps _count between 0 (only the total) and PAGINATION_MAX_SIZE
+func pageCount(count int) int {
+	if count == 0 {
+		return 0
+	}
+	return response.PageSize(count)
+}
+
+// baseURL is the FHIR base the request came through, e.g.
+// https://api.example.com/api/v1/fhir, honoring the proxy's X-Forwarded-Proto
+func baseURL(ctx *gin.Context) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	path := ctx.Request.URL.Path
+	if i := strings.LastIndex(path, "/Patient"); i >= 0 {
+		path = path[:i]
+	}
+	return scheme + "://" + ctx.Request.Host + path
+}
+
+// pageURL is the search URL of a page, the search parameters kept as they came
+func pageURL(base string, params url.Values, count, offset int) string {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("_count", strconv.Itoa(count))
+	query.Set("_offset", strconv.Itoa(offset))
+	return base + "/Patient?" + query.Encode()
+}
+
+func respond(ctx *gin.Context, status int, resource any) {
+	ctx.Header("Content-Type", contentType)
+	ctx.JSON(status, resource)
+}
+
+func respondOutcome(ctx *gin.Context, status int, code, text, diagnostics string) {
+	respond(ctx, status, fhir.NewOperationOutcome(code, text, diagnostics))
+}
+
+// respondError answers an OperationOutcome for the error of a service
+func respondError(ctx *gin.Context, err error) {
+	logger.Err(err)
+	switch {
+	case errors.Is(err, fhirapidto.ErrInvalidSearch):
+		respondOutcome(ctx, http.StatusBadRequest, fhir.IssueInvalid, message.InvalidSearchParameter, err.Error())
+	case errors.Is(err, fhirapidto.ErrUnsupportedSearch):
+		respondOutcome(ctx, http.StatusBadRequest, fhir.IssueNotSupported, message.UnsupportedSearchParameter, err.Error())
+	case err.Error() == message.PatientNotFound:
+		respondOutcome(ctx, http.StatusNotFound, fhir.IssueNotFound, message.PatientNotFound, "")
+	case err.Error() == message.PatientConsentRequired:
+		respondOutcome(ctx, http.StatusForbidden, fhir.IssueForbidden, message.PatientConsentRequired, "")
+	default:
+		respondOutcome(ctx, http.StatusInternalServerError, fhir.IssueException, message.InternalServerError, "")
+	}
+}
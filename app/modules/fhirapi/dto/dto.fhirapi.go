@@ -0,0 +1,173 @@
+package fhirapidto
+
+import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/util/fhir"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidSearch     = errors.New(message.InvalidSearchParameter)
+	ErrUnsupportedSearch = errors.New(message.UnsupportedSearchParameter)
+)
+
+// DefaultCount is the page size of a search without _count
+const DefaultCount = 20
+
+// The Patient search parameters and the modifiers each accepts
+var PatientSearchParams = map[string][]string{
+	"_id":        {""},
+	"identifier": {"", "of-type"},
+	"name":       {"", "exact", "contains"},
+	"family":     {"", "exact", "contains"},
+	"given":      {"", "exact", "contains"},
+	"birthdate":  {""},
+	"gender":     {""},
+	"phone":      {""},
+	"email":      {""},
+}
+
+// ignoredParams change how the response is rendered, which the API does not support
+var ignoredParams = []string{"_format", "_pretty"}
+
+// identifierTypes are the v2-0203 codes the patient identifiers are searched by
+var identifierTypes = []string{fhir.IdentifierHN, fhir.IdentifierNational, fhir.IdentifierThai, fhir.IdentifierPassport}
+
+// Criterion is one search parameter, a patient matches when it matches any of
+// Values (comma separated in the query). Repeated parameters all have to match.
+type Criterion struct {
+	Name     string
+	Modifier string
+	Values   []string
+}
+
+type SearchPatientRequest struct {
+	Criteria []Criterion
+	Count    int
+	Offset   int
+	// Purpose is the purpose of use of the caller, set by the controller
+	Purpose enum.Purpose
+}
+
+type ReadPatientRequest struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// ParseSearch reads the search parameters of a query. Unknown parameters are
+// rejected rather than ignored, so a typo cannot turn into a search of every patient.
+func ParseSearch(query url.Values) (*SearchPatientRequest, error) {
+	req := &SearchPatientRequest{Count: DefaultCount}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		raw := query[key]
+		switch {
+		case key == "_count" || key == "_offset":
+			n, err := strconv.Atoi(raw[len(raw)-1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%w: %s must be a positive number", ErrInvalidSearch, key)
+			}
+			if key == "_count" {
+				req.Count = n
+			} else {
+				req.Offset = n
+			}
+			continue
+		case slices.Contains(ignoredParams, key):
+			continue
+		}
+
+		name, modifier, _ := strings.Cut(key, ":")
+		modifiers, ok := PatientSearchParams[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedSearch, name)
+		}
+		if !slices.Contains(modifiers, modifier) {
+			return nil, fmt.Errorf("%w: %s:%s", ErrUnsupportedSearch, name, modifier)
+		}
+		for _, value := range raw {
+			criterion := Criterion{Name: name, Modifier: modifier}
+			for _, v := range splitValues(value) {
+				if err := checkValue(name, modifier, v); err != nil {
+					return nil, err
+				}
+				criterion.Values = append(criterion.Values, v)
+			}
+			if len(criterion.Values) == 0 {
+				return nil, fmt.Errorf("%w: %s has no value", ErrInvalidSearch, key)
+			}
+			req.Criteria = append(req.Criteria, criterion)
+		}
+	}
+	return req, nil
+}
+
+// splitValues splits a parameter on the commas that are not escaped as \,
+func splitValues(value string) []string {
+	values := []string{}
+	current := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && (value[i+1] == ',' || value[i+1] == '\\' || value[i+1] == '|'):
+			current.WriteByte(value[i+1])
+			i++
+		case value[i] == ',':
+			if s := strings.TrimSpace(current.String()); s != "" {
+				values = append(values, s)
+			}
+			current.Reset()
+		default:
+			current.WriteByte(value[i])
+		}
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		values = append(values, s)
+	}
+	return values
+}
+
+func checkValue(name, modifier, value string) error {
+	switch name {
+	case "birthdate":
+		if _, err := fhir.ParseDate(value); err != nil {
+			return fmt.Errorf("%w: birthdate: %s", ErrInvalidSearch, err)
+		}
+	case "gender":
+		if !slices.Contains([]string{"male", "female", "other", "unknown"}, value) {
+			return fmt.Errorf("%w: gender must be male, female, other or unknown", ErrInvalidSearch)
+		}
+	case "identifier":
+		if modifier == "of-type" {
+			_, _, err := OfType(value)
+			return err
+		}
+		// identifiers are stored without a system, only "value" and "|value" can match
+		if system, _, _ := fhir.Token(value); system != "" {
+			return fmt.Errorf("%w: identifier system %s, search by value or with identifier:of-type", ErrUnsupportedSearch, system)
+		}
+	}
+	return nil
+}
+
+// OfType reads an identifier:of-type value, [type system]|[type code]|[value]
+func OfType(value string) (code, identifier string, err error) {
+	parts := strings.SplitN(value, "|", 3)
+	if len(parts) != 3 || parts[2] == "" || (parts[0] != "" && parts[0] != fhir.IdentifierTypeSystem) {
+		return "", "", fmt.Errorf("%w: identifier:of-type must be %s|<code>|<value>", ErrInvalidSearch, fhir.IdentifierTypeSystem)
+	}
+	if !slices.Contains(identifierTypes, parts[1]) {
+		return "", "", fmt.Errorf("%w: identifier type %s, expected one of %s", ErrUnsupportedSearch, parts[1], strings.Join(identifierTypes, ", "))
+	}
+	return parts[1], parts[2], nil
+}
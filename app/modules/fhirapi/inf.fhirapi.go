@@ -0,0 +1,15 @@
+package fhirapi
+
+import (
+	"app/app/enum"
+	"app/app/model"
+	fhirapidto "app/app/modules/fhirapi/dto"
+	"context"
+)
+
+type ServiceInterface interface {
+	Read(ctx context.Context, id string, hospital string, purpose enum.Purpose) (*model.Patient, error)
+	Search(ctx context.Context, req *fhirapidto.SearchPatientRequest, hospital string) ([]*model.Patient, int, error)
+}
+
+var _ ServiceInterface = (*Service)(nil)
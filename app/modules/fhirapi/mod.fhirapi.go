@@ -0,0 +1,18 @@
+package fhirapi
+
+import (
+	"github.com/uptrace/bun"
+)
+
+type Module struct {
+	Ctl *Controller
+	Svc *Service
+}
+
+func NewModule(db *bun.DB, patients PatientReader) *Module {
+	svc := NewService(db, patients)
+	return &Module{
+		Ctl: NewController(svc),
+		Svc: svc,
+	}
+}
@@ -0,0 +1,102 @@
+package fhirapi
+
+import (
+	"app/app/model"
+	fhirapidto "app/app/modules/fhirapi/dto"
+	"app/app/util/fieldcrypt"
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// useKeyring loads a keyring for the blind indexes of the identifiers
+func useKeyring(t *testing.T) {
+	keyring, err := fieldcrypt.New(map[int][]byte{1: bytes.Repeat([]byte{1}, 32)}, 0, bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	fieldcrypt.Use(keyring)
+	t.Cleanup(func() { fieldcrypt.Use(nil) })
+}
+
+func searchQuery(t *testing.T, raw string) string {
+	params, err := url.ParseQuery(raw)
+	require.NoError(t, err)
+	req, err := fhirapidto.ParseSearch(params)
+	require.NoError(t, err)
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	query := db.NewSelect().Model((*model.Patient)(nil))
+	for _, criterion := range req.Criteria {
+		match(query, criterion)
+	}
+	return query.String()
+}
+
+func TestMatch(t *testing.T) {
+	t.Run("Comma separated values are ORed, repeated parameters ANDed", func(t *testing.T) {
+		query := searchQuery(t, "gender=male,female&family=jai&family=ใจ")
+		assert.Contains(t, query, `((gender = '1') OR (gender = '2'))`)
+		assert.Contains(t, query, `(lower("last_name_th") LIKE 'jai%') OR (lower("last_name_en") LIKE 'jai%')) AND (`)
+		assert.Contains(t, query, `lower("last_name_en") LIKE 'ใจ%'`)
+	})
+
+	t.Run("Birthdate ranges", func(t *testing.T) {
+		assert.Contains(t, searchQuery(t, "birthdate=1990"), `date_of_birth >= '1990-01-01 00:00:00+00:00' AND date_of_birth < '1991-01-01 00:00:00+00:00'`)
+		assert.Contains(t, searchQuery(t, "birthdate=gt1990-05"), `date_of_birth >= '1990-06-01 00:00:00+00:00'`)
+		assert.Contains(t, searchQuery(t, "birthdate=le1990-05-17"), `date_of_birth < '1990-05-18 00:00:00+00:00'`)
+	})
+
+	t.Run("Identifiers", func(t *testing.T) {
+		useKeyring(t)
+		assert.Contains(t, searchQuery(t, "identifier=|HN2024000001"), "patient_hn = 'HN2024000001' OR national_id_bidx = '"+
+			model.PatientBlindIndex("national_id", "HN2024000001")+"' OR passport_id_bidx = '"+model.PatientBlindIndex("passport_id", "HN2024000001")+"'")
+		assert.Contains(t, searchQuery(t, "identifier:of-type=http://terminology.hl7.org/CodeSystem/v2-0203|PPN|AA1234567"),
+			`"passport_id_bidx" = '`+model.PatientBlindIndex("passport_id", "AA1234567")+"'")
+		assert.Contains(t, searchQuery(t, "identifier:of-type=http://terminology.hl7.org/CodeSystem/v2-0203|MR|HN2024000001"), `patient_hn = 'HN2024000001'`)
+	})
+
+	t.Run("Contact details match on their blind index", func(t *testing.T) {
+		useKeyring(t)
+		query := searchQuery(t, "email=Somchai@Example.com&phone=081-234-5678")
+		assert.Contains(t, query, "email_bidx = '"+model.PatientBlindIndex("email", "somchai@example.com")+"'")
+		assert.Contains(t, query, "phone_number_bidx = '"+model.PatientBlindIndex("phone_number", "0812345678")+"'")
+		assert.NotContains(t, query, "Somchai")
+	})
+
+	t.Run("Name wildcards are escaped", func(t *testing.T) {
+		assert.Contains(t, searchQuery(t, "name:contains=50%25_x"), `LIKE '%50\%\_x%'`)
+	})
+
+	t.Run("Ids that are not uuids match nothing", func(t *testing.T) {
+		assert.Contains(t, searchQuery(t, "_id=1 OR 1=1"), `(FALSE)`)
+	})
+}
+
+func TestParseSearch(t *testing.T) {
+	req, err := fhirapidto.ParseSearch(url.Values{"_count": {"5"}, "_format": {"json"}, "name": {`a\,b`}})
+	require.NoError(t, err)
+	assert.Equal(t, 5, req.Count)
+	assert.Equal(t, []string{"a,b"}, req.Criteria[0].Values)
+
+	failures := map[string]error{
+		"address=bangkok":            fhirapidto.ErrUnsupportedSearch,
+		"name:missing=true":          fhirapidto.ErrUnsupportedSearch,
+		"identifier=urn:oid:1.2|123": fhirapidto.ErrUnsupportedSearch,
+		"identifier:of-type=|DL|123": fhirapidto.ErrUnsupportedSearch,
+		"identifier:of-type=123":     fhirapidto.ErrInvalidSearch,
+		"gender=M":                   fhirapidto.ErrInvalidSearch,
+		"birthdate=sa1990":           fhirapidto.ErrInvalidSearch,
+		"_count=-1":                  fhirapidto.ErrInvalidSearch,
+		"family=,":                   fhirapidto.ErrInvalidSearch,
+	}
+	for raw, want := range failures {
+		params, _ := url.ParseQuery(raw)
+		_, err := fhirapidto.ParseSearch(params)
+		assert.True(t, errors.Is(err, want), "%s: %v", raw, err)
+	}
+}
@@ -0,0 +1,170 @@
+package fhirapi
+
+import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/model"
+	fhirapidto "app/app/modules/fhirapi/dto"
+	"app/app/modules/patient"
+	"app/app/util/fhir"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// PatientReader reads a patient of a hospital for the purpose, the patient
+// service implements it
+type PatientReader interface {
+	Get(ctx context.Context, id string, hospital string, purpose enum.Purpose) (*model.Patient, error)
+}
+
+type Service struct {
+	db       *bun.DB
+	patients PatientReader
+}
+
+func NewService(db *bun.DB, patients PatientReader) *Service {
+	return &Service{
+		db:       db,
+		patients: patients,
+	}
+}
+
+// Read returns the patient of the hospital when it may be read for the
+// purpose, ids that are not patient ids are not found
+func (s *Service) Read(ctx context.Context, id string, hospital string, purpose enum.Purpose) (*model.Patient, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errors.New(message.PatientNotFound)
+	}
+	return s.patients.Get(ctx, id, hospital, purpose)
+}
+
+// Search returns a page of the hospital's patients matching every criterion
+// that may be read for the purpose of the request, oldest first, and how many
+// match in total
+func (s *Service) Search(ctx context.Context, req *fhirapidto.SearchPatientRequest, hospital string) ([]*model.Patient, int, error) {
+	resp := []*model.Patient{}
+	query := s.db.NewSelect().
+		Model(&resp).
+		Where("hospital = ?", hospital)
+	patient.Consented(query, req.Purpose)
+	for _, criterion := range req.Criteria {
+		match(query, criterion)
+	}
+
+	total, err := query.Count(ctx)
+	if err != nil {
+		return resp, 0, err
+	}
+	if total == 0 || req.Count == 0 {
+		return resp, total, nil
+	}
+
+	err = query.
+		Order("created_at ASC", "id ASC").
+		Offset(req.Offset).
+		Limit(req.Count).
+		Scan(ctx)
+	if err != nil {
+		return resp, 0, err
+	}
+	return resp, total, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// nameColumns are the columns of the name parameters
+var nameColumns = map[string][]string{
+	"name":   {"first_name_th", "middle_name_th", "last_name_th", "first_name_en", "middle_name_en", "last_name_en"},
+	"family": {"last_name_th", "last_name_en"},
+	"given":  {"first_name_th", "middle_name_th", "first_name_en", "middle_name_en"},
+}
+
+// identifierColumns are the columns of the v2-0203 identifier types
+var identifierColumns = map[string]string{
+	fhir.IdentifierHN:       "patient_hn",
+	fhir.IdentifierNational: "national_id",
+	fhir.IdentifierThai:     "national_id",
+	fhir.IdentifierPassport: "passport_id",
+}
+
+// match adds the criterion as one condition, its values ORed. The values were
+// checked by ParseSearch.
+func match(query *bun.SelectQuery, criterion fhirapidto.Criterion) {
+	query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+		for _, value := range criterion.Values {
+			matchValue(q, criterion.Name, criterion.Modifier, value)
+		}
+		return q
+	})
+}
+
+func matchValue(q *bun.SelectQuery, name, modifier, value string) {
+	switch name {
+	case "_id":
+		// an id that is not a uuid cannot match, and would fail the uuid cast
+		if _, err := uuid.Parse(value); err != nil {
+			q.WhereOr("FALSE")
+			return
+		}
+		q.WhereOr("id = ?", value)
+	case "identifier":
+		if modifier == "of-type" {
+			code, identifier, _ := fhirapidto.OfType(value)
+			column := identifierColumns[code]
+			if column == "patient_hn" {
+				q.WhereOr("patient_hn = ?", identifier)
+				return
+			}
+			// the identifiers are encrypted, they match on their blind index
+			q.WhereOr("? = ?", bun.Ident(column+"_bidx"), model.PatientBlindIndex(column, identifier))
+			return
+		}
+		_, identifier, _ := fhir.Token(value)
+		q.WhereOr("patient_hn = ? OR national_id_bidx = ? OR passport_id_bidx = ?", identifier,
+			model.PatientBlindIndex("national_id", identifier), model.PatientBlindIndex("passport_id", identifier))
+	case "name", "family", "given":
+		// string parameters match the start of any part, case-insensitively
+		for _, column := range nameColumns[name] {
+			switch modifier {
+			case "exact":
+				q.WhereOr("? = ?", bun.Ident(column), value)
+			case "contains":
+				q.WhereOr("lower(?) LIKE ?", bun.Ident(column), "%"+likeEscaper.Replace(strings.ToLower(value))+"%")
+			default:
+				q.WhereOr("lower(?) LIKE ?", bun.Ident(column), likeEscaper.Replace(strings.ToLower(value))+"%")
+			}
+		}
+	case "birthdate":
+		date, _ := fhir.ParseDate(value)
+		switch date.Prefix {
+		case fhir.PrefixNe:
+			q.WhereOr("date_of_birth < ? OR date_of_birth >= ?", date.Start, date.End)
+		case fhir.PrefixLt:
+			q.WhereOr("date_of_birth < ?", date.Start)
+		case fhir.PrefixLe:
+			q.WhereOr("date_of_birth < ?", date.End)
+		case fhir.PrefixGt:
+			q.WhereOr("date_of_birth >= ?", date.End)
+		case fhir.PrefixGe:
+			q.WhereOr("date_of_birth >= ?", date.Start)
+		default:
+			q.WhereOr("date_of_birth >= ? AND date_of_birth < ?", date.Start, date.End)
+		}
+	case "gender":
+		gender := fhir.Gender(value)
+		// patients without a gender are unknown too
+		if gender == string(enum.GENDER_UNKNOWN) {
+			q.WhereOr("gender = ? OR gender IS NULL", gender)
+			return
+		}
+		q.WhereOr("gender = ?", gender)
+	case "phone":
+		q.WhereOr("phone_number_bidx = ?", model.PatientBlindIndex("phone_number", value))
+	case "email":
+		q.WhereOr("email_bidx = ?", model.PatientBlindIndex("email", value))
+	}
+}
@@ -0,0 +1,238 @@
+package hospital
+
+import (
+	"app/app/enum"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	hospitaldto "app/app/modules/hospital/dto"
+	"app/app/util/his"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// HospitalMockService for testing
+type HospitalMockService struct {
+	mock.Mock
+}
+
+func (m *HospitalMockService) List(ctx context.Context, req *hospitaldto.ListHospitalRequest) ([]*hospitaldto.HospitalResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*hospitaldto.HospitalResponse), args.Error(1)
+}
+
+func (m *HospitalMockService) Get(ctx context.Context, code string) (*hospitaldto.HospitalResponse, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*hospitaldto.HospitalResponse), args.Error(1)
+}
+
+func (m *HospitalMockService) Create(ctx context.Context, req *hospitaldto.CreateHospitalRequest) (*hospitaldto.HospitalResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*hospitaldto.HospitalResponse), args.Error(1)
+}
+
+func (m *HospitalMockService) Update(ctx context.Context, code string, req *hospitaldto.UpdateHospitalRequest) (*hospitaldto.HospitalResponse, error) {
+	args := m.Called(ctx, code, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*hospitaldto.HospitalResponse), args.Error(1)
+}
+
+// Helper functions
+// 🎯 Hospital Controller Tests - Success & Fail Only
+func TestHospitalController_Create(t *testing.T) {
+	t.Run("Success - Create Hospital", func(t *testing.T) {
+		// Setup
+		mockService := new(HospitalMockService)
+		createReq := &hospitaldto.CreateHospitalRequest{
+			Code:   "hospital-b",
+			NameTH: "โรงพยาบาล บี",
+			NameEN: "Hospital B",
+			API: &his.AdapterConfig{
+				BaseURL: "https://hospital-b.api.co.th",
+			},
+		}
+		mockService.On("Create", mock.Anything, createReq).Return(&hospitaldto.HospitalResponse{Code: "hospital-b"}, nil)
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := testhelper.NewContext("POST", "/hospital/create", createReq)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		t.Log("✅ PASS: Create hospital returned status 200")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Hospital Already Exists", func(t *testing.T) {
+		// Setup
+		testhelper.UseJSONNaming(t)
+		mockService := new(HospitalMockService)
+		createReq := &hospitaldto.CreateHospitalRequest{
+			Code:   "hospital-a",
+			NameTH: "โรงพยาบาล เอ",
+			NameEN: "Hospital A",
+		}
+		mockService.On("Create", mock.Anything, createReq).Return(nil, errors.New(message.HospitalAlreadyExists))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := testhelper.NewContext("POST", "/hospital/create", createReq)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		t.Log("❌ PASS: Duplicate hospital returned status 400")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Invalid Status", func(t *testing.T) {
+		// Setup
+		testhelper.UseJSONNaming(t)
+		mockService := new(HospitalMockService)
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := testhelper.NewContext("POST", "/hospital/create", &hospitaldto.CreateHospitalRequest{
+			Code:   "hospital-b",
+			NameTH: "โรงพยาบาล บี",
+			NameEN: "Hospital B",
+			Status: "closed",
+		})
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		t.Log("❌ PASS: Unknown status returned status 400")
+		mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Fail - Invalid Request Body", func(t *testing.T) {
+		// Setup
+		mockService := new(HospitalMockService)
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := testhelper.NewContext("POST", "/hospital/create", map[string]string{})
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		t.Log("❌ PASS: Invalid hospital request returned status 400")
+	})
+}
+
+func TestHospitalController_GetUpdate(t *testing.T) {
+	t.Run("Success - Get Hospital", func(t *testing.T) {
+		// Setup
+		mockService := new(HospitalMockService)
+		mockService.On("Get", mock.Anything, "hospital-a").Return(&hospitaldto.HospitalResponse{Code: "hospital-a"}, nil)
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := testhelper.NewContext("GET", "/hospital/hospital-a", nil)
+		c.Params = gin.Params{{Key: "code", Value: "hospital-a"}}
+		controller.Get(c)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		t.Log("✅ PASS: Get hospital returned status 200")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Hospital Not Found", func(t *testing.T) {
+		// Setup
+		testhelper.UseJSONNaming(t)
+		mockService := new(HospitalMockService)
+		mockService.On("Get", mock.Anything, "hospital-z").Return(nil, errors.New(message.HospitalNotFound))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := testhelper.NewContext("GET", "/hospital/hospital-z", nil)
+		c.Params = gin.Params{{Key: "code", Value: "hospital-z"}}
+		controller.Get(c)
+
+		// Assert
+		assert.Equal(t, 404, w.Code)
+		t.Log("❌ PASS: Unknown hospital returned status 404")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Success - Deactivate Hospital", func(t *testing.T) {
+		// Setup
+		mockService := new(HospitalMockService)
+		updateReq := &hospitaldto.UpdateHospitalRequest{
+			NameTH: "โรงพยาบาล เอ",
+			NameEN: "Hospital A",
+			Status: enum.STATUS_INACTIVE,
+		}
+		mockService.On("Update", mock.Anything, "hospital-a", updateReq).
+			Return(&hospitaldto.HospitalResponse{Code: "hospital-a", Status: enum.STATUS_INACTIVE}, nil)
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := testhelper.NewContext("PUT", "/hospital/hospital-a", updateReq)
+		c.Params = gin.Params{{Key: "code", Value: "hospital-a"}}
+		controller.Update(c)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		t.Log("✅ PASS: Deactivate hospital returned status 200")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Invalid HIS Settings", func(t *testing.T) {
+		// Setup
+		testhelper.UseJSONNaming(t)
+		mockService := new(HospitalMockService)
+		updateReq := &hospitaldto.UpdateHospitalRequest{
+			NameTH: "โรงพยาบาล เอ",
+			NameEN: "Hospital A",
+			Status: enum.STATUS_ACTIVE,
+			API:    &his.AdapterConfig{BaseURL: "not a url"},
+		}
+		mockService.On("Update", mock.Anything, "hospital-a", updateReq).Return(nil, errors.New(message.InvalidHospitalAPI))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := testhelper.NewContext("PUT", "/hospital/hospital-a", updateReq)
+		c.Params = gin.Params{{Key: "code", Value: "hospital-a"}}
+		controller.Update(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		t.Log("❌ PASS: Invalid HIS settings returned status 400")
+		mockService.AssertExpectations(t)
+	})
+}
+
+// 📊 Test Summary
+func TestHospitalController_Summary(t *testing.T) {
+	t.Log("🧪 Hospital Controller Test Summary")
+	t.Log("===================================")
+	t.Log("✅ Create / Get / Update Hospital - Success Cases")
+	t.Log("❌ Create / Get / Update Hospital - Fail Cases")
+	t.Log("🎯 Focus: Success/Fail scenarios only")
+	t.Log("📁 File: controller_test.go")
+}
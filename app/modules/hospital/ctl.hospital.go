@@ -0,0 +1,114 @@
+package hospital
+
+import (
+	"app/app/message"
+	hospitaldto "app/app/modules/hospital/dto"
+	"app/app/response"
+	"app/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller struct {
+	Service ServiceInterface
+}
+
+func NewController(svc ServiceInterface) *Controller {
+	return &Controller{
+		Service: svc,
+	}
+}
+
+func (c *Controller) List(ctx *gin.Context) {
+	req := new(hospitaldto.ListHospitalRequest)
+	if err := ctx.BindQuery(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if !hospitaldto.ValidStatus(req.Status) {
+		response.BadRequest(ctx, message.InvalidHospitalStatus, nil)
+		return
+	}
+	data, err := c.Service.List(ctx, req)
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	response.Success(ctx, data)
+}
+
+func (c *Controller) Get(ctx *gin.Context) {
+	code := new(hospitaldto.HospitalCodeRequest)
+	if err := ctx.BindUri(code); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	data, err := c.Service.Get(ctx, code.Code)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, data)
+}
+
+func (c *Controller) Create(ctx *gin.Context) {
+	req := new(hospitaldto.CreateHospitalRequest)
+	if err := ctx.Bind(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if !hospitaldto.ValidStatus(req.Status) {
+		response.BadRequest(ctx, message.InvalidHospitalStatus, nil)
+		return
+	}
+	data, err := c.Service.Create(ctx, req)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, data)
+}
+
+func (c *Controller) Update(ctx *gin.Context) {
+	code := new(hospitaldto.HospitalCodeRequest)
+	if err := ctx.BindUri(code); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := new(hospitaldto.UpdateHospitalRequest)
+	if err := ctx.Bind(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if !hospitaldto.ValidStatus(req.Status) {
+		response.BadRequest(ctx, message.InvalidHospitalStatus, nil)
+		return
+	}
+	data, err := c.Service.Update(ctx, code.Code, req)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, data)
+}
+
+// respondError maps the message of a service error onto its status code
+func respondError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case message.HospitalNotFound:
+		response.NotFound(ctx, err.Error(), nil)
+	case message.HospitalAlreadyExists, message.InvalidHospitalAPI:
+		response.BadRequest(ctx, err.Error(), nil)
+	default:
+		response.InternalError(ctx, err.Error(), nil)
+	}
+}
@@ -0,0 +1,52 @@
+package hospitaldto
+
+import (
+	"app/app/enum"
+	"app/app/util/his"
+)
+
+type HospitalCodeRequest struct {
+	Code string `uri:"code" binding:"required"`
+}
+
+type CreateHospitalRequest struct {
+	Code   string      `json:"code" binding:"required"`
+	NameTH string      `json:"name_th" binding:"required"`
+	NameEN string      `json:"name_en" binding:"required"`
+	Status enum.Status `json:"status"`
+	// API is the upstream HIS integration, its hospital is always the code of the hospital
+	API *his.AdapterConfig `json:"api"`
+}
+
+type UpdateHospitalRequest struct {
+	NameTH string             `json:"name_th" binding:"required"`
+	NameEN string             `json:"name_en" binding:"required"`
+	Status enum.Status        `json:"status" binding:"required"`
+	API    *his.AdapterConfig `json:"api"`
+}
+
+type ListHospitalRequest struct {
+	Status enum.Status `form:"status"`
+}
+
+type HospitalResponse struct {
+	Code      string               `json:"code"`
+	NameTH    string               `json:"name_th"`
+	NameEN    string               `json:"name_en"`
+	Status    enum.Status          `json:"status"`
+	API       *HospitalAPIResponse `json:"api"`
+	CreatedAt int64                `json:"created_at"`
+	UpdatedAt int64                `json:"updated_at"`
+}
+
+// HospitalAPIResponse is the upstream integration without its credentials
+type HospitalAPIResponse struct {
+	BaseURL    string `json:"base_url"`
+	SearchPath string `json:"search_path"`
+	AuthType   string `json:"auth_type"`
+}
+
+// ValidStatus reports whether status is empty or a known hospital status
+func ValidStatus(status enum.Status) bool {
+	return status == "" || status == enum.STATUS_ACTIVE || status == enum.STATUS_INACTIVE
+}
@@ -0,0 +1,53 @@
+package hospital
+
+import (
+	"app/app/helper/testhelper"
+	hospitaldto "app/app/modules/hospital/dto"
+	"app/app/util/his"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_APIEncryption(t *testing.T) {
+	ctx := context.Background()
+	db := testhelper.DB(t)
+	keyring := testhelper.UseKeyring(t)
+	registry := his.NewRegistry()
+	svc := NewService(db, registry)
+
+	code := "test-" + uuid.NewString()[:8]
+	_, err := svc.Create(ctx, &hospitaldto.CreateHospitalRequest{
+		Code:   code,
+		NameTH: code,
+		NameEN: code,
+		API: &his.AdapterConfig{
+			BaseURL: "https://" + code + ".api.co.th",
+			Auth:    his.AuthConfig{Type: "bearer", Token: "his-secret-token"},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("Success - Stored encrypted", func(t *testing.T) {
+		var stored string
+		require.NoError(t, db.NewRaw("SELECT api FROM hospitals WHERE code = ?", code).Scan(ctx, &stored))
+		assert.Contains(t, stored, keyring.Prefix())
+		assert.NotContains(t, stored, "his-secret-token")
+	})
+
+	t.Run("Success - Plaintext integration re-encrypted and loaded", func(t *testing.T) {
+		_, err := db.NewRaw(`UPDATE hospitals SET api = '{"base_url":"https://legacy.api.co.th"}' WHERE code = ?`, code).Exec(ctx)
+		require.NoError(t, err)
+
+		rewritten, err := svc.Reencrypt(ctx)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, rewritten, 1)
+
+		require.NoError(t, svc.LoadAdapters(ctx))
+		_, err = registry.Get(code)
+		assert.NoError(t, err)
+	})
+}
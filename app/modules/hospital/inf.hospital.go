@@ -0,0 +1,16 @@
+package hospital
+
+import (
+	hospitaldto "app/app/modules/hospital/dto"
+	"context"
+)
+
+// ServiceInterface defines the interface for hospital service operations
+type ServiceInterface interface {
+	List(ctx context.Context, req *hospitaldto.ListHospitalRequest) ([]*hospitaldto.HospitalResponse, error)
+	Get(ctx context.Context, code string) (*hospitaldto.HospitalResponse, error)
+	Create(ctx context.Context, req *hospitaldto.CreateHospitalRequest) (*hospitaldto.HospitalResponse, error)
+	Update(ctx context.Context, code string, req *hospitaldto.UpdateHospitalRequest) (*hospitaldto.HospitalResponse, error)
+}
+
+var _ ServiceInterface = (*Service)(nil)
@@ -0,0 +1,20 @@
+package hospital
+
+import (
+	"app/app/util/his"
+
+	"github.com/uptrace/bun"
+)
+
+type Module struct {
+	Ctl *Controller
+	Svc *Service
+}
+
+func NewModule(db *bun.DB, registry *his.Registry) *Module {
+	svc := NewService(db, registry)
+	return &Module{
+		Ctl: NewController(svc),
+		Svc: svc,
+	}
+}
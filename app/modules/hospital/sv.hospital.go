@@ -0,0 +1,255 @@
+package hospital
+
+import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/model"
+	hospitaldto "app/app/modules/hospital/dto"
+	"app/app/util/fieldcrypt"
+	"app/app/util/his"
+	"app/internal/logger"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/uptrace/bun"
+)
+
+type Service struct {
+	db  *bun.DB
+	his *his.Registry
+}
+
+func NewService(db *bun.DB, registry *his.Registry) *Service {
+	return &Service{
+		db:  db,
+		his: registry,
+	}
+}
+
+func (s *Service) List(ctx context.Context, req *hospitaldto.ListHospitalRequest) ([]*hospitaldto.HospitalResponse, error) {
+	hospitals := []*model.Hospital{}
+	query := s.db.NewSelect().
+		Model(&hospitals).
+		Order("code ASC")
+	if req.Status != "" {
+		query.Where("status = ?", req.Status)
+	}
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+
+	resp := make([]*hospitaldto.HospitalResponse, 0, len(hospitals))
+	for _, hospital := range hospitals {
+		resp = append(resp, toHospitalResponse(hospital))
+	}
+	return resp, nil
+}
+
+func (s *Service) Get(ctx context.Context, code string) (*hospitaldto.HospitalResponse, error) {
+	hospital, err := s.get(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return toHospitalResponse(hospital), nil
+}
+
+func (s *Service) Create(ctx context.Context, req *hospitaldto.CreateHospitalRequest) (*hospitaldto.HospitalResponse, error) {
+	exists, err := s.db.NewSelect().
+		Model((*model.Hospital)(nil)).
+		Where("code = ?", req.Code).
+		Exists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New(message.HospitalAlreadyExists)
+	}
+
+	status := req.Status
+	if status == "" {
+		status = enum.STATUS_ACTIVE
+	}
+	hospital := &model.Hospital{
+		Code:   req.Code,
+		NameTH: req.NameTH,
+		NameEN: req.NameEN,
+		Status: status,
+	}
+	adapter, err := setAPI(hospital, req.API)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.NewInsert().
+		Model(hospital).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.register(hospital.Code, adapter)
+	return toHospitalResponse(hospital), nil
+}
+
+// Update replaces the hospital settings, an empty api removes the integration
+func (s *Service) Update(ctx context.Context, code string, req *hospitaldto.UpdateHospitalRequest) (*hospitaldto.HospitalResponse, error) {
+	hospital, err := s.get(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	hospital.NameTH = req.NameTH
+	hospital.NameEN = req.NameEN
+	hospital.Status = req.Status
+	adapter, err := setAPI(hospital, req.API)
+	if err != nil {
+		return nil, err
+	}
+	hospital.SetUpdateNow()
+	_, err = s.db.NewUpdate().
+		Model(hospital).
+		Column("name_th", "name_en", "status", "api", "updated_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.register(hospital.Code, adapter)
+	return toHospitalResponse(hospital), nil
+}
+
+// LoadAdapters registers the integration of every active hospital, overriding HIS_ADAPTERS
+func (s *Service) LoadAdapters(ctx context.Context) error {
+	hospitals := []*model.Hospital{}
+	err := s.db.NewSelect().
+		Model(&hospitals).
+		Where("status = ?", enum.STATUS_ACTIVE).
+		Where("api IS NOT NULL").
+		Scan(ctx)
+	if err != nil {
+		return err
+	}
+	for _, hospital := range hospitals {
+		conf := new(his.AdapterConfig)
+		if err := json.Unmarshal([]byte(hospital.API), conf); err != nil {
+			logger.Errf("hospital %s: invalid api: %s", hospital.Code, err)
+			continue
+		}
+		conf.Hospital = hospital.Code
+		adapter, err := his.NewAdapter(*conf)
+		if err != nil {
+			logger.Errf("hospital %s: %s", hospital.Code, err)
+			continue
+		}
+		s.his.Register(adapter)
+	}
+	return nil
+}
+
+// Reencrypt rewrites the integration of every hospital with the active key,
+// integrations saved before they were encrypted included
+func (s *Service) Reencrypt(ctx context.Context) (int, error) {
+	keyring := fieldcrypt.Current()
+	if keyring == nil {
+		return 0, fieldcrypt.ErrNoKeyring
+	}
+	hospitals := []*model.Hospital{}
+	err := s.db.NewSelect().
+		Model(&hospitals).
+		Where("api IS NOT NULL").
+		Where("api NOT LIKE ?", keyring.Prefix()+"%").
+		Scan(ctx)
+	if err != nil {
+		return 0, err
+	}
+	err = s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, hospital := range hospitals {
+			// decrypted on scan, encrypted with the active key on update
+			_, err := tx.NewUpdate().
+				Model(hospital).
+				Column("api").
+				WherePK().
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(hospitals), nil
+}
+
+func (s *Service) get(ctx context.Context, code string) (*model.Hospital, error) {
+	hospital := new(model.Hospital)
+	err := s.db.NewSelect().
+		Model(hospital).
+		Where("code = ?", code).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.HospitalNotFound)
+		}
+		return nil, err
+	}
+	return hospital, nil
+}
+
+// register keeps the shared HIS registry in line with the hospital,
+// an inactive or not integrated hospital has no adapter
+func (s *Service) register(code string, adapter *his.Adapter) {
+	if s.his == nil {
+		return
+	}
+	if adapter == nil {
+		s.his.Remove(code)
+		return
+	}
+	s.his.Register(adapter)
+}
+
+// setAPI validates the integration and stores it on the hospital. The adapter is
+// only returned for an active hospital.
+func setAPI(hospital *model.Hospital, conf *his.AdapterConfig) (*his.Adapter, error) {
+	if conf == nil {
+		hospital.API = ""
+		return nil, nil
+	}
+	conf.Hospital = hospital.Code
+	adapter, err := his.NewAdapter(*conf)
+	if err != nil {
+		logger.Err(err)
+		return nil, errors.New(message.InvalidHospitalAPI)
+	}
+	raw, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	hospital.API = fieldcrypt.EncryptedString(raw)
+	if hospital.Status != enum.STATUS_ACTIVE {
+		return nil, nil
+	}
+	return adapter, nil
+}
+
+func toHospitalResponse(hospital *model.Hospital) *hospitaldto.HospitalResponse {
+	resp := &hospitaldto.HospitalResponse{
+		Code:      hospital.Code,
+		NameTH:    hospital.NameTH,
+		NameEN:    hospital.NameEN,
+		Status:    hospital.Status,
+		CreatedAt: hospital.CreatedAt,
+		UpdatedAt: hospital.UpdatedAt,
+	}
+	conf := new(his.AdapterConfig)
+	if len(hospital.API) > 0 && json.Unmarshal([]byte(hospital.API), conf) == nil {
+		resp.API = &hospitaldto.HospitalAPIResponse{
+			BaseURL:    conf.BaseURL,
+			SearchPath: conf.SearchPath,
+			AuthType:   conf.Auth.Type,
+		}
+	}
+	return resp
+}
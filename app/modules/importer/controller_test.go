@@ -0,0 +1,241 @@
+package importer
+
+import (
+	"app/app/helper"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	importerdto "app/app/modules/importer/dto"
+	"app/app/util/jwt"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ImporterMockService for testing
+type ImporterMockService struct {
+	mock.Mock
+}
+
+func (m *ImporterMockService) Start(ctx context.Context, hospital, createdBy string, req *importerdto.CreateImportRequest, fileName string, data []byte) (*model.ImportJob, error) {
+	args := m.Called(ctx, hospital, createdBy, req, fileName, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ImportJob), args.Error(1)
+}
+
+func (m *ImporterMockService) Get(ctx context.Context, id string, hospital string) (*model.ImportJob, error) {
+	args := m.Called(ctx, id, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ImportJob), args.Error(1)
+}
+
+func (m *ImporterMockService) Errors(ctx context.Context, id string, hospital string, req *importerdto.ListImportErrorRequest) ([]*model.ImportJobError, int, error) {
+	args := m.Called(ctx, id, hospital, req)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*model.ImportJobError), args.Int(1), args.Error(2)
+}
+
+var claims = &jwt.Claims{
+	Data: jwt.ClaimData{
+		ID:       "staff-1",
+		Username: "registrar",
+		Hospital: "hospital-a",
+	},
+}
+
+const jobID = "7b0c6f1e-7c4e-4d7b-9a53-0f6f3f0f6a11"
+
+// Helper functions
+func createUploadContext(fields map[string]string, fileName string, file []byte) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	if file != nil {
+		part, _ := writer.CreateFormFile("file", fileName)
+		part.Write(file)
+	}
+	writer.Close()
+
+	c.Request = httptest.NewRequest("POST", "/patient/import", body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	helper.SetUserInClaims(c, claims)
+	return c, w
+}
+
+func createJobContext(url string, id string) (*gin.Context, *httptest.ResponseRecorder) {
+	c, w := testhelper.NewContextWithClaims("GET", url, nil, claims)
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	return c, w
+}
+
+// 🎯 Importer Controller Tests - Success & Fail Only
+func TestImporterController_Create(t *testing.T) {
+	file := []byte("national_id,first_name_th,last_name_th,date_of_birth,gender\n1103702071811,สมชาย,ใจดี,1990-01-01,1\n")
+
+	t.Run("Success - Start Import", func(t *testing.T) {
+		// Setup
+		mockService := new(ImporterMockService)
+		req := &importerdto.CreateImportRequest{Format: "csv"}
+		mockService.On("Start", mock.Anything, "hospital-a", "staff-1", req, "patients.csv", file).
+			Return(&model.ImportJob{ID: jobID, Status: "pending", Total: 1}, nil)
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createUploadContext(map[string]string{"format": "csv"}, "patients.csv", file)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		t.Log("✅ PASS: Import started with status 200")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Missing File", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		// Setup
+		mockService := new(ImporterMockService)
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createUploadContext(map[string]string{"format": "csv"}, "", nil)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), message.ImportFileRequired)
+		t.Log("❌ PASS: Missing file returned status 400")
+		mockService.AssertNotCalled(t, "Start")
+	})
+
+	t.Run("Fail - Unknown Format", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		// Setup
+		mockService := new(ImporterMockService)
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createUploadContext(map[string]string{"format": "xml"}, "patients.xml", file)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		t.Log("❌ PASS: Unknown format returned status 400")
+		mockService.AssertNotCalled(t, "Start")
+	})
+
+	t.Run("Fail - Unreadable File", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		// Setup
+		mockService := new(ImporterMockService)
+		req := &importerdto.CreateImportRequest{Format: "fhir"}
+		mockService.On("Start", mock.Anything, "hospital-a", "staff-1", req, "bundle.json", file).
+			Return(nil, fmt.Errorf("%w: not json", ErrInvalidImportFile))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createUploadContext(map[string]string{"format": "fhir"}, "bundle.json", file)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), "not json")
+		t.Log("❌ PASS: Unreadable file returned status 400 with the detail")
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestImporterController_Get(t *testing.T) {
+	t.Run("Success - Get Job", func(t *testing.T) {
+		// Setup
+		mockService := new(ImporterMockService)
+		mockService.On("Get", mock.Anything, jobID, "hospital-a").Return(&model.ImportJob{ID: jobID, Status: "running"}, nil)
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createJobContext("/patient/import/"+jobID, jobID)
+		controller.Get(c)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		t.Log("✅ PASS: Get import job returned status 200")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Job Of Another Hospital", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		// Setup
+		mockService := new(ImporterMockService)
+		mockService.On("Get", mock.Anything, jobID, "hospital-a").Return(nil, errors.New(message.ImportJobNotFound))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createJobContext("/patient/import/"+jobID, jobID)
+		controller.Get(c)
+
+		// Assert
+		assert.Equal(t, 404, w.Code)
+		t.Log("❌ PASS: Unknown import job returned status 404")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Invalid ID", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		// Setup
+		mockService := new(ImporterMockService)
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createJobContext("/patient/import/abc", "abc")
+		controller.Get(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		t.Log("❌ PASS: Invalid job ID returned status 400")
+		mockService.AssertNotCalled(t, "Get")
+	})
+}
+
+func TestImporterController_Errors(t *testing.T) {
+	t.Run("Success - Error Report", func(t *testing.T) {
+		// Setup
+		mockService := new(ImporterMockService)
+		req := &importerdto.ListImportErrorRequest{Page: 2, Size: 50}
+		failures := []*model.ImportJobError{{Row: 7, Identifier: "1103702071811", Message: message.InvalidDateOfBirth}}
+		mockService.On("Errors", mock.Anything, jobID, "hospital-a", req).Return(failures, 51, nil)
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createJobContext("/patient/import/"+jobID+"/errors?page=2&size=50", jobID)
+		controller.Errors(c)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		t.Log("✅ PASS: Error report returned status 200")
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"app/app/helper"
+	"app/app/message"
+	importerdto "app/app/modules/importer/dto"
+	"app/app/response"
+	"app/internal/logger"
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFileSize bounds an uploaded import file
+const maxFileSize = 64 << 20
+
+type Controller struct {
+	Service ServiceInterface
+}
+
+func NewController(svc ServiceInterface) *Controller {
+	return &Controller{
+		Service: svc,
+	}
+}
+
+func (c *Controller) Create(ctx *gin.Context) {
+	req := new(importerdto.CreateImportRequest)
+	if err := ctx.ShouldBind(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		response.BadRequest(ctx, message.ImportFileRequired, nil)
+		return
+	}
+	if file.Size > maxFileSize {
+		response.BadRequest(ctx, message.ImportFileTooLarge, nil)
+		return
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+
+	job, err := c.Service.Start(ctx, user.Data.Hospital, user.Data.ID, req, file.Filename, data)
+	if err != nil {
+		logger.Err(err)
+		if errors.Is(err, ErrInvalidImportFile) {
+			// the detail tells which line or column is wrong
+			response.BadRequest(ctx, message.InvalidImportFile, err.Error())
+			return
+		}
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	response.Success(ctx, job)
+}
+
+func (c *Controller) Get(ctx *gin.Context) {
+	id := new(importerdto.ImportJobRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	job, err := c.Service.Get(ctx, id.ID, user.Data.Hospital)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, job)
+}
+
+func (c *Controller) Errors(ctx *gin.Context) {
+	id := new(importerdto.ImportJobRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := importerdto.ListImportErrorRequest{
+		Page: 1,
+		Size: 100,
+	}
+	if err := ctx.BindQuery(&req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	req.Size = response.PageSize(req.Size)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, total, err := c.Service.Errors(ctx, id.ID, user.Data.Hospital, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	response.SuccessWithPaginate(ctx, data, req.Page, req.Size, total)
+}
+
+func respondError(ctx *gin.Context, err error) {
+	logger.Err(err)
+	switch err.Error() {
+	case message.ImportJobNotFound:
+		response.NotFound(ctx, err.Error(), nil)
+	default:
+		response.InternalError(ctx, err.Error(), nil)
+	}
+}
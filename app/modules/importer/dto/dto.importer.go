@@ -0,0 +1,24 @@
+package importerdto
+
+const (
+	FormatCSV  = "csv"
+	FormatFHIR = "fhir"
+)
+
+// CreateImportRequest is the multipart form of an upload, the file itself is the "file" part
+type CreateImportRequest struct {
+	Format string `form:"format" binding:"required,oneof=csv fhir"`
+	// Mapping is a JSON object from patient field to CSV header, e.g.
+	// {"national_id": "CID", "first_name_th": "ชื่อ"}. Unmapped fields are read
+	// from the column named like the field.
+	Mapping string `form:"mapping"`
+}
+
+type ImportJobRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type ListImportErrorRequest struct {
+	Page int `form:"page"`
+	Size int `form:"size"`
+}
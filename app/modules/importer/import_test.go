@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"app/app/message"
+	patientdto "app/app/modules/patient/dto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSV(t *testing.T) {
+	t.Run("Mapped and default columns", func(t *testing.T) {
+		file := "\xef\xbb\xbfCID,ชื่อ,นามสกุล,date_of_birth,Gender,note\n" +
+			"1103702071811, สมชาย ,ใจดี,1990-01-01,male,vip\n" +
+			"\"3100503375851\",\"สมหญิง\",\"ใจดี\",1985-05-05,2\n"
+		rows, err := Parse("csv", `{"national_id": "CID", "first_name_th": "ชื่อ", "last_name_th": "นามสกุล"}`, []byte(file))
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+
+		assert.Equal(t, 2, rows[0].Line)
+		assert.Equal(t, "1103702071811", rows[0].Request.NationalID)
+		assert.Equal(t, "สมชาย", rows[0].Request.FirstNameTH)
+		assert.Equal(t, "1", rows[0].Request.Gender)
+		assert.NoError(t, validateRow(rows[0].Request))
+		assert.Equal(t, 3, rows[1].Line)
+		assert.Equal(t, "2", rows[1].Request.Gender)
+	})
+
+	t.Run("Mapped column missing", func(t *testing.T) {
+		_, err := Parse("csv", `{"national_id": "CID"}`, []byte("national_id\n1103702071811\n"))
+		assert.ErrorIs(t, err, ErrInvalidImportFile)
+	})
+
+	t.Run("Unknown field in mapping", func(t *testing.T) {
+		_, err := Parse("csv", `{"blood_type": "blood"}`, []byte("blood\nA\n"))
+		assert.ErrorIs(t, err, ErrInvalidImportFile)
+	})
+
+	t.Run("No patient column", func(t *testing.T) {
+		_, err := Parse("csv", "", []byte("a,b\n1,2\n"))
+		assert.ErrorIs(t, err, ErrInvalidImportFile)
+	})
+}
+
+func TestParseFHIR(t *testing.T) {
+	rows, err := Parse("fhir", "", []byte(`{"resourceType": "Bundle", "entry": [
+		{"resource": {"resourceType": "Patient", "gender": "female", "birthDate": "1985-05-05",
+			"name": [{"family": "Jaidee", "given": ["Somying"]}],
+			"identifier": [{"type": {"coding": [{"code": "PPN"}]}, "value": "AA1234567"}]}}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "AA1234567", rows[0].Request.PassportID)
+	assert.NoError(t, validateRow(rows[0].Request))
+}
+
+func TestValidateRow(t *testing.T) {
+	req := &patientdto.CreatePatientRequest{
+		FirstNameEN: "Somchai",
+		LastNameEN:  "Jaidee",
+		DateOfBirth: "1990-01-01",
+		NationalID:  "1103702071811",
+		Gender:      "1",
+		Email:       "not-an-email",
+	}
+	assert.EqualError(t, validateRow(req), message.InvalidEmail)
+
+	req.Email = ""
+	req.NationalID = "1103702071812"
+	assert.EqualError(t, validateRow(req), message.InvalidNationalID)
+}
+
+func TestDuplicate(t *testing.T) {
+	seen := map[string]int{}
+	first := Row{Line: 2, Request: &patientdto.CreatePatientRequest{NationalID: "1103702071811", PassportID: "AA1234567"}}
+	second := Row{Line: 9, Request: &patientdto.CreatePatientRequest{PassportID: "AA1234567"}}
+	other := Row{Line: 10, Request: &patientdto.CreatePatientRequest{NationalID: "3100503375851"}}
+
+	assert.NoError(t, duplicate(seen, first))
+	assert.EqualError(t, duplicate(seen, second), message.ImportDuplicateRow+": row 2")
+	assert.NoError(t, duplicate(seen, other))
+}
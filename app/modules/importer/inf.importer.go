@@ -0,0 +1,15 @@
+package importer
+
+import (
+	"app/app/model"
+	importerdto "app/app/modules/importer/dto"
+	"context"
+)
+
+type ServiceInterface interface {
+	Start(ctx context.Context, hospital, createdBy string, req *importerdto.CreateImportRequest, fileName string, data []byte) (*model.ImportJob, error)
+	Get(ctx context.Context, id string, hospital string) (*model.ImportJob, error)
+	Errors(ctx context.Context, id string, hospital string, req *importerdto.ListImportErrorRequest) ([]*model.ImportJobError, int, error)
+}
+
+var _ ServiceInterface = (*Service)(nil)
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"app/app/enum"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	importerdto "app/app/modules/importer/dto"
+	patientdto "app/app/modules/patient/dto"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+// failingPatients creates every patient but fails the transaction of any call
+// holding the national ID in broken, an existing one is updated
+type failingPatients struct {
+	broken   string
+	existing string
+}
+
+func (f *failingPatients) Import(ctx context.Context, tx bun.IDB, hospital string, reqs []*patientdto.CreatePatientRequest) ([]patientdto.ImportResult, error) {
+	results := make([]patientdto.ImportResult, len(reqs))
+	for i, req := range reqs {
+		if req.NationalID == f.broken {
+			return nil, errors.New("deadlock detected")
+		}
+		results[i].Created = req.NationalID != f.existing
+	}
+	return results, nil
+}
+
+func importRow(line int, nationalID string) Row {
+	return Row{Line: line, Request: &patientdto.CreatePatientRequest{
+		FirstNameEN: "Somchai",
+		LastNameEN:  "Jaidee",
+		DateOfBirth: "1990-01-01",
+		NationalID:  nationalID,
+		Gender:      "1",
+	}}
+}
+
+func jobErrors(t *testing.T, db bun.IDB, job *model.ImportJob) map[int]string {
+	t.Helper()
+	var errs []*model.ImportJobError
+	err := db.NewSelect().Model(&errs).Where("job_id = ?", job.ID).Scan(context.Background())
+	require.NoError(t, err)
+	messages := map[int]string{}
+	for _, e := range errs {
+		messages[e.Row] = e.Message
+	}
+	return messages
+}
+
+func TestService_Run(t *testing.T) {
+	ctx := context.Background()
+	db := testhelper.DB(t)
+	hospital := testhelper.Hospital(t, db)
+	patients := &failingPatients{broken: "3100600123450", existing: "1103702071811"}
+	svc := NewService(db, patients)
+
+	invalid := importRow(4, "1103702071812")
+	email := importRow(5, "5101499000243")
+	email.Request.Email = "not-an-email"
+	rows := []Row{
+		importRow(2, "1103702071811"),
+		importRow(3, "3100600123450"),
+		invalid,
+		email,
+		importRow(6, "1103702071811"),
+		importRow(7, "1234567890121"),
+	}
+	job := &model.ImportJob{Hospital: hospital, Format: importerdto.FormatCSV, Total: len(rows)}
+	_, err := db.NewInsert().Model(job).Returning("*").Exec(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Run(ctx, job, rows))
+
+	saved, err := svc.Get(ctx, job.ID, hospital)
+	require.NoError(t, err)
+	assert.Equal(t, enum.JOB_COMPLETED, saved.Status)
+	assert.Equal(t, 6, saved.Processed)
+	assert.Equal(t, 1, saved.Inserted)
+	assert.Equal(t, 1, saved.Updated)
+	assert.Equal(t, 4, saved.Failed)
+	assert.Equal(t, map[int]string{
+		3: message.ImportRowFailed,
+		4: message.InvalidNationalID,
+		5: message.InvalidEmail,
+		6: message.ImportDuplicateRow + ": row 2",
+	}, jobErrors(t, db, job))
+}
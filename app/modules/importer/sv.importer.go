@@ -0,0 +1,514 @@
+package importer
+
+import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/model"
+	importerdto "app/app/modules/importer/dto"
+	patientdto "app/app/modules/patient/dto"
+	"app/app/util/fhir"
+	"app/app/util/fieldcrypt"
+	"app/internal/logger"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/uptrace/bun"
+)
+
+const (
+	// batchSize rows are saved per transaction
+	batchSize = 500
+	// staleAfter without a heartbeat a running import is reported failed, a
+	// batch takes seconds so only a stopped process gets there
+	staleAfter = 15 * time.Minute
+)
+
+var ErrInvalidImportFile = errors.New(message.InvalidImportFile)
+
+// PatientImporter saves a batch of patients, patient.Service implements it
+type PatientImporter interface {
+	Import(ctx context.Context, tx bun.IDB, hospital string, reqs []*patientdto.CreatePatientRequest) ([]patientdto.ImportResult, error)
+}
+
+// Row is one patient of an import file, Line is the CSV line or the FHIR entry
+type Row struct {
+	Line    int
+	Request *patientdto.CreatePatientRequest
+	Err     error
+}
+
+type Service struct {
+	db       *bun.DB
+	patients PatientImporter
+}
+
+func NewService(db *bun.DB, patients PatientImporter) *Service {
+	return &Service{
+		db:       db,
+		patients: patients,
+	}
+}
+
+// Start parses the file, records the job and runs it in the background. Files
+// that cannot be read at all fail here with ErrInvalidImportFile.
+func (s *Service) Start(ctx context.Context, hospital, createdBy string, req *importerdto.CreateImportRequest, fileName string, data []byte) (*model.ImportJob, error) {
+	job, rows, err := s.Create(ctx, hospital, createdBy, req, fileName, data)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := s.Run(context.Background(), job, rows); err != nil {
+			logger.Errf("import %s failed: %s", job.ID, err)
+		}
+	}()
+	return job, nil
+}
+
+// Create parses the file and records a pending job for it
+func (s *Service) Create(ctx context.Context, hospital, createdBy string, req *importerdto.CreateImportRequest, fileName string, data []byte) (*model.ImportJob, []Row, error) {
+	rows, err := Parse(req.Format, req.Mapping, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	job := &model.ImportJob{
+		Hospital:  hospital,
+		Format:    req.Format,
+		FileName:  fileName,
+		Status:    enum.JOB_PENDING,
+		Total:     len(rows),
+		CreatedBy: createdBy,
+	}
+	_, err = s.db.NewInsert().
+		Model(job).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, rows, nil
+}
+
+// Run validates the rows and saves them in batches, each batch in its own
+// transaction. Rows that cannot be saved are written to the error report and
+// the counters of the job are updated after every batch.
+func (s *Service) Run(ctx context.Context, job *model.ImportJob, rows []Row) error {
+	job.Status = enum.JOB_RUNNING
+	job.StartedAt = time.Now().Unix()
+	if err := s.saveJob(ctx, s.db, job); err != nil {
+		return s.fail(job, err)
+	}
+
+	// first line of every national ID and passport ID, a second row with the
+	// same identifier would overwrite the first
+	seen := map[string]int{}
+	batch := make([]Row, 0, batchSize)
+	failures := []*model.ImportJobError{}
+	handled := 0
+	for _, row := range rows {
+		handled++
+		if row.Err == nil {
+			row.Err = validateRow(row.Request)
+		}
+		if row.Err == nil {
+			row.Err = duplicate(seen, row)
+		}
+		if row.Err != nil {
+			failures = append(failures, rowError(job, row, row.Err))
+			continue
+		}
+		batch = append(batch, row)
+		if len(batch) < batchSize {
+			continue
+		}
+		if err := s.flush(ctx, job, batch, failures, handled); err != nil {
+			return s.fail(job, err)
+		}
+		batch, failures, handled = batch[:0], failures[:0], 0
+	}
+	if err := s.flush(ctx, job, batch, failures, handled); err != nil {
+		return s.fail(job, err)
+	}
+
+	job.Status = enum.JOB_COMPLETED
+	job.FinishedAt = time.Now().Unix()
+	if err := s.saveJob(ctx, s.db, job); err != nil {
+		return s.fail(job, err)
+	}
+	return nil
+}
+
+// flush saves the batch, reports the failures and moves the counters on by the
+// handled rows. A batch whose transaction fails is saved again a row at a time,
+// so only the rows that fail on their own are reported.
+func (s *Service) flush(ctx context.Context, job *model.ImportJob, batch []Row, failures []*model.ImportJobError, handled int) error {
+	inserted, updated := 0, 0
+	if len(batch) > 0 {
+		saved, err := s.save(ctx, job, batch)
+		if err != nil {
+			logger.Errf("import %s: batch failed, saving its rows one by one: %s", job.ID, err)
+			for _, row := range batch {
+				result, err := s.save(ctx, job, []Row{row})
+				if err != nil {
+					logger.Errf("import %s: row %d failed: %s", job.ID, row.Line, err)
+					failures = append(failures, rowError(job, row, errors.New(message.ImportRowFailed)))
+					continue
+				}
+				saved = append(saved, result...)
+			}
+		}
+		for _, result := range saved {
+			switch {
+			case result.err != nil:
+				failures = append(failures, result.err)
+			case result.created:
+				inserted++
+			default:
+				updated++
+			}
+		}
+	}
+
+	job.Processed += handled
+	job.Inserted += inserted
+	job.Updated += updated
+	job.Failed += len(failures)
+	return s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if len(failures) > 0 {
+			if _, err := tx.NewInsert().Model(&failures).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return s.saveJob(ctx, tx, job)
+	})
+}
+
+// outcome is what became of a row handed to the PatientImporter
+type outcome struct {
+	created bool
+	err     *model.ImportJobError
+}
+
+// save imports the rows in one transaction
+func (s *Service) save(ctx context.Context, job *model.ImportJob, rows []Row) ([]outcome, error) {
+	reqs := make([]*patientdto.CreatePatientRequest, len(rows))
+	for i, row := range rows {
+		reqs[i] = row.Request
+	}
+	resp := make([]outcome, 0, len(rows))
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		results, err := s.patients.Import(ctx, tx, job.Hospital, reqs)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			if result.Err != nil {
+				resp = append(resp, outcome{err: rowError(job, rows[i], result.Err)})
+				continue
+			}
+			resp = append(resp, outcome{created: result.Created})
+		}
+		return nil
+	})
+	if err != nil {
+		return resp[:0], err
+	}
+	return resp, nil
+}
+
+func (s *Service) saveJob(ctx context.Context, db bun.IDB, job *model.ImportJob) error {
+	job.SetUpdateNow()
+	_, err := db.NewUpdate().
+		Model(job).
+		Column("status", "processed", "inserted", "updated", "failed", "error", "started_at", "finished_at", "updated_at").
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+// fail marks the job failed, with a context of its own as ctx may be the reason
+func (s *Service) fail(job *model.ImportJob, cause error) error {
+	job.Status = enum.JOB_FAILED
+	job.Error = cause.Error()
+	job.FinishedAt = time.Now().Unix()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.saveJob(ctx, s.db, job); err != nil {
+		logger.Errf("import %s: %s", job.ID, err)
+	}
+	return cause
+}
+
+func (s *Service) Get(ctx context.Context, id string, hospital string) (*model.ImportJob, error) {
+	job := new(model.ImportJob)
+	err := s.db.NewSelect().
+		Model(job).
+		Where("id = ?", id).
+		Where("hospital = ?", hospital).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.ImportJobNotFound)
+		}
+		return nil, err
+	}
+	// the process running the import stopped without finishing it
+	unfinished := job.Status == enum.JOB_PENDING || job.Status == enum.JOB_RUNNING
+	if unfinished && time.Since(time.Unix(job.UpdatedAt, 0)) > staleAfter {
+		job.Status = enum.JOB_FAILED
+		job.Error = message.ImportInterrupted
+		job.FinishedAt = time.Now().Unix()
+		if err := s.saveJob(ctx, s.db, job); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// Errors returns a page of the error report of the job, ordered by row
+func (s *Service) Errors(ctx context.Context, id string, hospital string, req *importerdto.ListImportErrorRequest) ([]*model.ImportJobError, int, error) {
+	if _, err := s.Get(ctx, id, hospital); err != nil {
+		return nil, 0, err
+	}
+	resp := []*model.ImportJobError{}
+	total, err := s.db.NewSelect().
+		Model(&resp).
+		Where("job_id = ?", id).
+		Order("row ASC", "id ASC").
+		Offset((req.Page - 1) * req.Size).
+		Limit(req.Size).
+		ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp, total, nil
+}
+
+// reencryptBatchSize is how many error rows ReencryptErrors rewrites per query
+const reencryptBatchSize = 1000
+
+// ReencryptErrors rewrites the identifiers of the error reports that hold
+// plaintext or values under a key other than the active one, like
+// patient.Service.Reencrypt does for patients
+func (s *Service) ReencryptErrors(ctx context.Context) (int, error) {
+	keyring := fieldcrypt.Current()
+	if keyring == nil {
+		return 0, fieldcrypt.ErrNoKeyring
+	}
+	total, after := 0, int64(0)
+	for {
+		batch := []*model.ImportJobError{}
+		err := s.db.NewSelect().
+			Model(&batch).
+			Where("id > ?", after).
+			Where("identifier NOT LIKE ?", keyring.Prefix()+"%").
+			Order("id ASC").
+			Limit(reencryptBatchSize).
+			Scan(ctx)
+		if err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+		_, err = s.db.NewUpdate().
+			With("_data", s.db.NewValues(&batch)).
+			Model((*model.ImportJobError)(nil)).
+			TableExpr("_data").
+			Set("identifier = _data.identifier").
+			Where("?TableAlias.id = _data.id").
+			Exec(ctx)
+		if err != nil {
+			return total, err
+		}
+		total += len(batch)
+		after = batch[len(batch)-1].ID
+	}
+}
+
+func rowError(job *model.ImportJob, row Row, err error) *model.ImportJobError {
+	identifier := ""
+	if row.Request != nil {
+		identifier = row.Request.NationalID
+		if identifier == "" {
+			identifier = row.Request.PassportID
+		}
+	}
+	return &model.ImportJobError{
+		JobID:      job.ID,
+		Row:        row.Line,
+		Identifier: fieldcrypt.EncryptedString(identifier),
+		Message:    err.Error(),
+	}
+}
+
+// tagMessages are the error messages of the binding tags of a create request,
+// by field and tag
+var tagMessages = map[string]string{
+	"DateOfBirth.required": message.InvalidDateOfBirth,
+	"Email.email":          message.InvalidEmail,
+	"Gender.required":      message.InvalidGender,
+}
+
+// validateRow applies the checks of a create request, binding tags included
+func validateRow(req *patientdto.CreatePatientRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	err := binding.Validator.ValidateStruct(req)
+	if err == nil {
+		return nil
+	}
+	var fields validator.ValidationErrors
+	if errors.As(err, &fields) && len(fields) > 0 {
+		if msg, ok := tagMessages[fields[0].StructField()+"."+fields[0].Tag()]; ok {
+			return errors.New(msg)
+		}
+	}
+	return errors.New(message.InvalidRequest)
+}
+
+func duplicate(seen map[string]int, row Row) error {
+	keys := []string{}
+	if row.Request.NationalID != "" {
+		keys = append(keys, "national_id:"+row.Request.NationalID)
+	}
+	if row.Request.PassportID != "" {
+		keys = append(keys, "passport_id:"+row.Request.PassportID)
+	}
+	for _, key := range keys {
+		if line, ok := seen[key]; ok {
+			return fmt.Errorf("%s: row %d", message.ImportDuplicateRow, line)
+		}
+	}
+	for _, key := range keys {
+		seen[key] = row.Line
+	}
+	return nil
+}
+
+// Parse reads the rows of an import file. Rows that cannot be turned into a
+// request carry their error, a file that cannot be read fails as a whole.
+func Parse(format, mapping string, data []byte) ([]Row, error) {
+	switch format {
+	case importerdto.FormatCSV:
+		columns := map[string]string{}
+		if strings.TrimSpace(mapping) != "" {
+			if err := json.Unmarshal([]byte(mapping), &columns); err != nil {
+				return nil, fmt.Errorf("%w: mapping: %s", ErrInvalidImportFile, err)
+			}
+		}
+		return parseCSV(data, columns)
+	case importerdto.FormatFHIR:
+		return parseFHIR(data)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidImportFile, format)
+	}
+}
+
+// csvFields are the fields a CSV row fills, named like the create request json
+func csvFields(req *patientdto.CreatePatientRequest) map[string]*string {
+	return map[string]*string{
+		"first_name_th":  &req.FirstNameTH,
+		"middle_name_th": &req.MiddleNameTH,
+		"last_name_th":   &req.LastNameTH,
+		"first_name_en":  &req.FirstNameEN,
+		"middle_name_en": &req.MiddleNameEN,
+		"last_name_en":   &req.LastNameEN,
+		"date_of_birth":  &req.DateOfBirth,
+		"national_id":    &req.NationalID,
+		"passport_id":    &req.PassportID,
+		"phone_number":   &req.PhoneNumber,
+		"email":          &req.Email,
+		"gender":         &req.Gender,
+	}
+}
+
+// parseCSV reads a CSV with a header line. mapping renames the header of a
+// field, mapped headers have to be present while unmapped fields are optional.
+func parseCSV(data []byte, mapping map[string]string) ([]Row, error) {
+	// spreadsheet exports often start with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %s", ErrInvalidImportFile, err)
+	}
+	positions := map[string]int{}
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	fields := csvFields(new(patientdto.CreatePatientRequest))
+	columns := map[string]int{}
+	for field, name := range mapping {
+		if _, ok := fields[field]; !ok {
+			return nil, fmt.Errorf("%w: mapping: unknown field %q", ErrInvalidImportFile, field)
+		}
+		position, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("%w: mapping: no column %q for %s", ErrInvalidImportFile, name, field)
+		}
+		columns[field] = position
+	}
+	for field := range fields {
+		if _, ok := columns[field]; ok {
+			continue
+		}
+		if _, mapped := mapping[field]; mapped {
+			continue
+		}
+		if position, ok := positions[field]; ok {
+			columns[field] = position
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: no patient column in the header", ErrInvalidImportFile)
+	}
+
+	rows := []Row{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		req := new(patientdto.CreatePatientRequest)
+		values := csvFields(req)
+		for field, position := range columns {
+			if position < len(record) {
+				*values[field] = strings.TrimSpace(record[position])
+			}
+		}
+		req.Gender = fhir.Gender(req.Gender)
+		rows = append(rows, Row{Line: line, Request: req})
+	}
+	return rows, nil
+}
+
+func parseFHIR(data []byte) ([]Row, error) {
+	patients, err := fhir.ParsePatients(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err)
+	}
+	rows := make([]Row, len(patients))
+	for i, patient := range patients {
+		rows[i] = Row{Line: i + 1, Request: fhir.ToPatientRequest(patient)}
+	}
+	return rows, nil
+}
@@ -0,0 +1,190 @@
+package merge
+
+import (
+	"app/app/helper"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	mergedto "app/app/modules/merge/dto"
+	"app/app/util/jwt"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MergeMockService for testing
+type MergeMockService struct {
+	mock.Mock
+}
+
+func (m *MergeMockService) Duplicates(ctx context.Context, req *mergedto.ListDuplicateRequest, hospital string) ([]mergedto.Candidate, int, error) {
+	args := m.Called(ctx, req, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]mergedto.Candidate), args.Int(1), args.Error(2)
+}
+
+func (m *MergeMockService) Patients(ctx context.Context, ids []string, hospital string) (map[string]*model.Patient, error) {
+	args := m.Called(ctx, ids, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]*model.Patient), args.Error(1)
+}
+
+func (m *MergeMockService) Merge(ctx context.Context, survivorID string, req *mergedto.MergePatientRequest, hospital, staffID string) (*model.Patient, error) {
+	args := m.Called(ctx, survivorID, req, hospital, staffID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Patient), args.Error(1)
+}
+
+func (m *MergeMockService) History(ctx context.Context, id string, hospital string) ([]*model.PatientMerge, error) {
+	args := m.Called(ctx, id, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.PatientMerge), args.Error(1)
+}
+
+const (
+	survivorID  = "0b9e8f3a-4c2d-4f51-9a47-6f1d2c3b4a59"
+	duplicateID = "65e08e33-9f57-45fe-b725-82242e3581ad"
+)
+
+var claims = &jwt.Claims{Data: jwt.ClaimData{
+	ID:          "staff-1",
+	Hospital:    "hospital-a",
+	Role:        "registrar",
+	Permissions: []string{"patient:read", "patient:merge"},
+}}
+
+func TestMergeController_Duplicates(t *testing.T) {
+	patients := map[string]*model.Patient{
+		survivorID:  {ID: survivorID, FirstNameTH: "สมชาย", NationalID: "1103702071811", DateOfBirth: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+		duplicateID: {ID: duplicateID, FirstNameTH: "สมชัย", PassportID: "AA1234567", DateOfBirth: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+	}
+
+	t.Run("Success - Pairs with both patients, masked", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MergeMockService)
+		mockService.On("Duplicates", mock.Anything, &mergedto.ListDuplicateRequest{Page: 1, Size: 20, MinScore: DefaultMinScore}, "hospital-a").
+			Return([]mergedto.Candidate{{PatientID: survivorID, DuplicateID: duplicateID, Score: 0.7, Reasons: []string{"name", "date_of_birth"}}}, 1, nil)
+		mockService.On("Patients", mock.Anything, []string{survivorID, duplicateID}, "hospital-a").Return(patients, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/duplicates", nil, claims)
+		controller.Duplicates(c)
+
+		assert.Equal(t, 200, w.Code)
+		body := w.Body.String()
+		assert.Contains(t, body, `"score":0.7`)
+		assert.Contains(t, body, "สมชัย")
+		assert.NotContains(t, body, "1103702071811")
+		assert.Contains(t, body, "*********1811")
+		assert.Equal(t, []string{survivorID, duplicateID}, helper.GetAuditPatients(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Score out of range", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MergeMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/duplicates?min_score=2", nil, claims)
+		controller.Duplicates(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "Duplicates", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestMergeController_Merge(t *testing.T) {
+	req := &mergedto.MergePatientRequest{DuplicateID: duplicateID, Reason: "Registered twice at the front desk"}
+
+	t.Run("Success - Merge into the patient of the uri", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MergeMockService)
+		mockService.On("Merge", mock.Anything, survivorID, req, "hospital-a", "staff-1").Return(&model.Patient{ID: survivorID}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+survivorID+"/merge", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: survivorID}}
+		controller.Merge(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, []string{survivorID, duplicateID}, helper.GetAuditPatients(c))
+		assert.Equal(t, req.Reason, helper.GetAuditReason(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Identities conflict", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MergeMockService)
+		mockService.On("Merge", mock.Anything, survivorID, req, "hospital-a", "staff-1").Return(nil, errors.New(message.PatientMergeConflict))
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+survivorID+"/merge", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: survivorID}}
+		controller.Merge(c)
+
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), message.PatientMergeConflict)
+	})
+
+	t.Run("Fail - Patient not found", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MergeMockService)
+		mockService.On("Merge", mock.Anything, survivorID, req, "hospital-a", "staff-1").Return(nil, errors.New(message.PatientNotFound))
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+survivorID+"/merge", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: survivorID}}
+		controller.Merge(c)
+
+		assert.Equal(t, 404, w.Code)
+	})
+
+	t.Run("Fail - Duplicate id missing", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MergeMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+survivorID+"/merge", map[string]string{"reason": "twice"}, claims)
+		c.Params = gin.Params{{Key: "id", Value: survivorID}}
+		controller.Merge(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "Merge", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestMergeController_History(t *testing.T) {
+	t.Run("Success - Merges of the patient", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MergeMockService)
+		mockService.On("History", mock.Anything, survivorID, "hospital-a").Return([]*model.PatientMerge{{
+			SurvivorID: survivorID,
+			MergedID:   duplicateID,
+			Fields:     []string{"passport_id"},
+			Snapshot:   `{"national_id":"1103702071811"}`,
+		}}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/"+survivorID+"/merges", nil, claims)
+		c.Params = gin.Params{{Key: "id", Value: survivorID}}
+		controller.History(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), duplicateID)
+		assert.NotContains(t, w.Body.String(), "1103702071811", "the snapshot is not returned")
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,148 @@
+package merge
+
+import (
+	"app/app/helper"
+	"app/app/message"
+	mergedto "app/app/modules/merge/dto"
+	"app/app/modules/patient"
+	"app/app/response"
+	"app/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller struct {
+	Service ServiceInterface
+}
+
+func NewController(svc ServiceInterface) *Controller {
+	return &Controller{
+		Service: svc,
+	}
+}
+
+// Duplicates lists the suspected duplicate pairs of the caller's hospital with
+// both patients, shaped by the masking policy
+func (c *Controller) Duplicates(ctx *gin.Context) {
+	req := mergedto.ListDuplicateRequest{
+		Page:     1,
+		Size:     20,
+		MinScore: DefaultMinScore,
+	}
+	if err := ctx.BindQuery(&req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	req.Size = response.PageSize(req.Size)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	candidates, total, err := c.Service.Duplicates(ctx, &req, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	ids := make([]string, 0, len(candidates)*2)
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.PatientID, candidate.DuplicateID)
+	}
+	patients, err := c.Service.Patients(ctx, ids, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+
+	data := make([]mergedto.DuplicateResponse, 0, len(candidates))
+	for _, candidate := range candidates {
+		a, b := patients[candidate.PatientID], patients[candidate.DuplicateID]
+		// merged or deleted since the pairs were read
+		if a == nil || b == nil {
+			continue
+		}
+		helper.AuditPatients(ctx, a.ID, b.ID)
+		data = append(data, mergedto.DuplicateResponse{
+			Patient:   patient.ToPatientDetail(a).Shape(viewer),
+			Duplicate: patient.ToPatientDetail(b).Shape(viewer),
+			Score:     candidate.Score,
+			Reasons:   candidate.Reasons,
+		})
+	}
+	response.SuccessWithPaginate(ctx, data, req.Page, req.Size, total)
+}
+
+// Merge merges the duplicate of the body into the patient of the uri
+func (c *Controller) Merge(ctx *gin.Context) {
+	id := new(mergedto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := new(mergedto.MergePatientRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID, req.DuplicateID)
+	if req.Reason != "" {
+		helper.AuditReason(ctx, req.Reason)
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Merge(ctx, id.ID, req, user.Data.Hospital, user.Data.ID)
+	if err != nil {
+		logger.Err(err)
+		switch err.Error() {
+		case message.PatientNotFound:
+			response.NotFound(ctx, err.Error(), nil)
+		case message.PatientMergeSelf, message.PatientMergeConflict:
+			response.BadRequest(ctx, err.Error(), nil)
+		default:
+			response.InternalError(ctx, err.Error(), nil)
+		}
+		return
+	}
+	response.Success(ctx, patient.ToPatientDetail(data).Shape(viewer))
+}
+
+// History lists the merges the patient of the uri took part in
+func (c *Controller) History(ctx *gin.Context) {
+	id := new(mergedto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, err := c.Service.History(ctx, id.ID, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	response.Success(ctx, data)
+}
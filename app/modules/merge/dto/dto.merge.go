@@ -0,0 +1,50 @@
+package mergedto
+
+import (
+	patientdto "app/app/modules/patient/dto"
+)
+
+// ListDuplicateRequest pages the suspected duplicates of the caller's hospital,
+// pairs scoring below MinScore are left out
+type ListDuplicateRequest struct {
+	Page     int     `form:"page"`
+	Size     int     `form:"size"`
+	MinScore float64 `form:"min_score" binding:"omitempty,gte=0,lte=1"`
+}
+
+type PatientIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// MergePatientRequest merges the duplicate into the patient of the uri, which survives
+type MergePatientRequest struct {
+	DuplicateID string `json:"duplicate_id" binding:"required,uuid"`
+	Reason      string `json:"reason" binding:"max=500"`
+}
+
+// The reasons two patients are suspected to be the same person
+const (
+	MatchName        = "name"
+	MatchDateOfBirth = "date_of_birth"
+	MatchPhoneNumber = "phone_number"
+	MatchEmail       = "email"
+	// MatchIdentifiers is one patient holding the national ID and the other the
+	// passport, so neither identifier tells them apart
+	MatchIdentifiers = "identifiers"
+)
+
+// Candidate is a pair of patients that may be the same person, Score from 0 to 1
+type Candidate struct {
+	PatientID   string
+	DuplicateID string
+	Score       float64
+	Reasons     []string
+}
+
+// DuplicateResponse is a Candidate with both patients, shaped for the caller
+type DuplicateResponse struct {
+	Patient   patientdto.PatientDetail `json:"patient"`
+	Duplicate patientdto.PatientDetail `json:"duplicate"`
+	Score     float64                  `json:"score"`
+	Reasons   []string                 `json:"reasons"`
+}
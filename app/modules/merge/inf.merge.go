@@ -0,0 +1,16 @@
+package merge
+
+import (
+	"app/app/model"
+	mergedto "app/app/modules/merge/dto"
+	"context"
+)
+
+type ServiceInterface interface {
+	Duplicates(ctx context.Context, req *mergedto.ListDuplicateRequest, hospital string) ([]mergedto.Candidate, int, error)
+	Patients(ctx context.Context, ids []string, hospital string) (map[string]*model.Patient, error)
+	Merge(ctx context.Context, survivorID string, req *mergedto.MergePatientRequest, hospital, staffID string) (*model.Patient, error)
+	History(ctx context.Context, id string, hospital string) ([]*model.PatientMerge, error)
+}
+
+var _ ServiceInterface = (*Service)(nil)
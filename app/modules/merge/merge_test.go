@@ -0,0 +1,78 @@
+package merge
+
+import (
+	"app/app/model"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestCandidates(t *testing.T) {
+	s := NewService(bun.NewDB(&sql.DB{}, pgdialect.New()))
+	query := s.candidates("hospital-a", 0.6).String()
+
+	for _, column := range []string{"date_of_birth", "phone_number_bidx", "email_bidx"} {
+		assert.Contains(t, query, `b.hospital = a.hospital AND b."`+column+`" = a."`+column+`" AND b.id > a.id AND b.deleted_at IS NULL`)
+	}
+	assert.Contains(t, query, "(a.hospital = 'hospital-a') AND (a.deleted_at IS NULL)")
+	assert.Contains(t, query, ") UNION (")
+	assert.NotContains(t, query, " OR a.phone_number_bidx = b.phone_number_bidx", "no OR join, it compares every pair")
+	assert.Contains(t, query, "similarity("+fullName("a", "th")+", "+fullName("b", "th")+")")
+	assert.Contains(t, query, "similarity("+fullName("a", "en")+", "+fullName("b", "en")+")")
+	assert.Contains(t, query, "WHERE (score >= 0.6)")
+	assert.NotContains(t, query, "a.national_id_bidx = b.passport_id_bidx", "blind indexes are keyed per column")
+}
+
+func TestToCandidate(t *testing.T) {
+	candidate := toCandidate(candidateRow{
+		PatientID:        "a",
+		DuplicateID:      "b",
+		NameSimilarity:   0.8,
+		SameDateOfBirth:  true,
+		SplitIdentifiers: true,
+		Score:            0.75,
+	})
+	assert.Equal(t, []string{"name", "date_of_birth", "identifiers"}, candidate.Reasons)
+	assert.Equal(t, 0.75, candidate.Score)
+
+	assert.Empty(t, toCandidate(candidateRow{NameSimilarity: 0.3, SamePhoneNumber: false}).Reasons)
+}
+
+func TestConflicts(t *testing.T) {
+	a := &model.Patient{NationalIDIndex: "n1"}
+	assert.False(t, conflicts(a, &model.Patient{PassportIDIndex: "p1"}), "national ID on one, passport on the other")
+	assert.False(t, conflicts(a, &model.Patient{NationalIDIndex: "n1", PassportIDIndex: "p1"}))
+	assert.True(t, conflicts(a, &model.Patient{NationalIDIndex: "n2"}))
+	assert.True(t, conflicts(&model.Patient{PassportIDIndex: "p1"}, &model.Patient{PassportIDIndex: "p2"}))
+}
+
+func TestTakeOver(t *testing.T) {
+	survivor := &model.Patient{
+		FirstNameTH: "สมชาย",
+		LastNameTH:  "ใจดี",
+		NationalID:  "1103702071811",
+		Gender:      "0",
+	}
+	duplicate := &model.Patient{
+		FirstNameTH: "สมชัย",
+		LastNameTH:  "ใจดี",
+		FirstNameEN: "Somchai",
+		LastNameEN:  "Jaidee",
+		DateOfBirth: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC),
+		PassportID:  "AA1234567",
+		PhoneNumber: "0812345678",
+		Gender:      "1",
+	}
+
+	fields := takeOver(survivor, duplicate)
+	assert.Equal(t, []string{"first_name_en", "last_name_en", "date_of_birth", "passport_id", "phone_number", "gender"}, fields)
+	assert.Equal(t, "สมชาย", survivor.FirstNameTH, "the survivor keeps what it has")
+	assert.EqualValues(t, "1103702071811", survivor.NationalID)
+	assert.EqualValues(t, "AA1234567", survivor.PassportID)
+	assert.Equal(t, "1", survivor.Gender)
+	assert.Empty(t, survivor.Email)
+}
@@ -0,0 +1,21 @@
+package merge
+
+import (
+	"app/app/modules/patient"
+
+	"github.com/uptrace/bun"
+)
+
+type Module struct {
+	Ctl *Controller
+	Svc *Service
+}
+
+func NewModule(db *bun.DB, links patient.Linker) *Module {
+	svc := NewService(db)
+	svc.Links = links
+	return &Module{
+		Ctl: NewController(svc),
+		Svc: svc,
+	}
+}
@@ -0,0 +1,152 @@
+package merge
+
+import (
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	mergedto "app/app/modules/merge/dto"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func insertPatient(t *testing.T, db bun.IDB, patient *model.Patient) *model.Patient {
+	t.Helper()
+	_, err := db.NewInsert().Model(patient).Returning("*").Exec(context.Background())
+	require.NoError(t, err)
+	return patient
+}
+
+// twins are two records of the same person, one registered by national ID and
+// the other by passport
+func twins(t *testing.T, db bun.IDB, hospital string) (*model.Patient, *model.Patient) {
+	t.Helper()
+	born := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	first := insertPatient(t, db, &model.Patient{
+		FirstNameTH: "สมชาย",
+		LastNameTH:  "ใจดี",
+		DateOfBirth: born,
+		PatientHN:   "HN000001",
+		NationalID:  "1103702071811",
+		PhoneNumber: "0812345678",
+		Gender:      "1",
+		Hospital:    hospital,
+	})
+	second := insertPatient(t, db, &model.Patient{
+		FirstNameTH: "สมชาย",
+		LastNameTH:  "ใจดี",
+		FirstNameEN: "Somchai",
+		LastNameEN:  "Jaidee",
+		DateOfBirth: born,
+		PatientHN:   "HN000002",
+		PassportID:  "AA1234567",
+		Email:       "somchai@example.com",
+		Gender:      "1",
+		Hospital:    hospital,
+	})
+	return first, second
+}
+
+func TestService_Duplicates(t *testing.T) {
+	ctx := context.Background()
+	db := testhelper.DB(t)
+	hospital := testhelper.Hospital(t, db)
+	first, second := twins(t, db, hospital)
+	// born the same day but nobody's twin, and a deleted patient
+	insertPatient(t, db, &model.Patient{
+		FirstNameEN: "Malee",
+		LastNameEN:  "Srisuk",
+		DateOfBirth: first.DateOfBirth,
+		PatientHN:   "HN000003",
+		PassportID:  "BB7654321",
+		Gender:      "2",
+		Hospital:    hospital,
+	})
+	deleted := insertPatient(t, db, &model.Patient{
+		FirstNameTH: "สมชาย",
+		LastNameTH:  "ใจดี",
+		DateOfBirth: first.DateOfBirth,
+		PatientHN:   "HN000004",
+		PhoneNumber: "0812345678",
+		Hospital:    hospital,
+	})
+	_, err := db.NewDelete().Model(deleted).WherePK().Exec(ctx)
+	require.NoError(t, err)
+
+	svc := NewService(db)
+	candidates, total, err := svc.Duplicates(ctx, &mergedto.ListDuplicateRequest{Page: 1, Size: 10, MinScore: DefaultMinScore}, hospital)
+
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Len(t, candidates, 1)
+	assert.ElementsMatch(t, []string{first.ID, second.ID}, []string{candidates[0].PatientID, candidates[0].DuplicateID})
+	assert.Equal(t, []string{mergedto.MatchName, mergedto.MatchDateOfBirth, mergedto.MatchIdentifiers}, candidates[0].Reasons)
+}
+
+func TestService_Merge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - The survivor takes over the duplicate", func(t *testing.T) {
+		db := testhelper.DB(t)
+		hospital := testhelper.Hospital(t, db)
+		survivor, duplicate := twins(t, db, hospital)
+		svc := NewService(db)
+
+		merged, err := svc.Merge(ctx, survivor.ID, &mergedto.MergePatientRequest{DuplicateID: duplicate.ID, Reason: "Registered twice"}, hospital, "")
+
+		require.NoError(t, err)
+		assert.EqualValues(t, "AA1234567", merged.PassportID)
+		assert.EqualValues(t, "somchai@example.com", merged.Email)
+
+		stored := new(model.Patient)
+		require.NoError(t, db.NewSelect().Model(stored).Where("id = ?", survivor.ID).Scan(ctx))
+		assert.EqualValues(t, "1103702071811", stored.NationalID)
+		assert.EqualValues(t, "AA1234567", stored.PassportID)
+		assert.Equal(t, model.PatientBlindIndex("passport_id", "AA1234567"), stored.PassportIDIndex)
+		assert.Equal(t, "Somchai", stored.FirstNameEN)
+
+		gone := new(model.Patient)
+		require.NoError(t, db.NewSelect().Model(gone).WhereAllWithDeleted().Where("id = ?", duplicate.ID).Scan(ctx))
+		assert.NotNil(t, gone.DeletedAt)
+		assert.Equal(t, survivor.ID, gone.MergedInto)
+		assert.Empty(t, gone.PassportID, "the duplicate gives up its identifiers")
+		assert.Empty(t, gone.PassportIDIndex)
+
+		history, err := svc.History(ctx, duplicate.ID, hospital)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, survivor.ID, history[0].SurvivorID)
+		assert.Contains(t, history[0].Fields, "passport_id")
+	})
+
+	t.Run("Fail - Different national IDs", func(t *testing.T) {
+		db := testhelper.DB(t)
+		hospital := testhelper.Hospital(t, db)
+		survivor, duplicate := twins(t, db, hospital)
+		duplicate.NationalID = "1234567890121"
+		_, err := db.NewUpdate().Model(duplicate).Column("national_id", "national_id_bidx").WherePK().Exec(ctx)
+		require.NoError(t, err)
+
+		_, err = NewService(db).Merge(ctx, survivor.ID, &mergedto.MergePatientRequest{DuplicateID: duplicate.ID}, hospital, "")
+
+		require.Error(t, err)
+		assert.Equal(t, message.PatientMergeConflict, err.Error())
+	})
+
+	t.Run("Fail - Patient of another hospital", func(t *testing.T) {
+		db := testhelper.DB(t)
+		hospital := testhelper.Hospital(t, db)
+		survivor, _ := twins(t, db, hospital)
+		other := testhelper.Hospital(t, db)
+		_, duplicate := twins(t, db, other)
+
+		_, err := NewService(db).Merge(ctx, survivor.ID, &mergedto.MergePatientRequest{DuplicateID: duplicate.ID}, hospital, "")
+
+		require.Error(t, err)
+		assert.Equal(t, message.PatientNotFound, err.Error())
+	})
+}
@@ -0,0 +1,389 @@
+package merge
+
+import (
+	"app/app/message"
+	"app/app/model"
+	mergedto "app/app/modules/merge/dto"
+	"app/app/modules/patient"
+	"app/app/util/fieldcrypt"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+type Service struct {
+	db *bun.DB
+	// Links relinks written patients, they are not linked when it is nil
+	Links patient.Linker
+}
+
+func NewService(db *bun.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// DefaultMinScore is the score a pair needs to be listed when the request sets none
+const DefaultMinScore = 0.6
+
+// The weights of the reasons in the score of a pair. Two patients born the
+// same day with near identical names score 0.7, the same phone number instead
+// of the birth date 0.65. A national ID or passport that differs between the
+// two takes 0.5 off, they are more likely relatives sharing a phone.
+const (
+	nameWeight        = 0.5
+	dateOfBirthWeight = 0.25
+	phoneNumberWeight = 0.2
+	emailWeight       = 0.2
+	identifierWeight  = 0.1
+	conflictPenalty   = 0.5
+)
+
+// fullName is the lower case name of the patient alias in TH or EN, for
+// trigram similarity
+func fullName(alias, lang string) string {
+	return "lower(concat_ws(' ', " + alias + ".first_name_" + lang + ", nullif(" + alias + ".middle_name_" + lang + ", ''), " + alias + ".last_name_" + lang + "))"
+}
+
+// onlyNationalID is a patient identified by national ID alone, the other side
+// of onlyPassportID
+func onlyNationalID(alias string) string {
+	return "(" + alias + ".national_id_bidx IS NOT NULL AND " + alias + ".passport_id_bidx IS NULL)"
+}
+
+func onlyPassportID(alias string) string {
+	return "(" + alias + ".passport_id_bidx IS NOT NULL AND " + alias + ".national_id_bidx IS NULL)"
+}
+
+type candidateRow struct {
+	PatientID          string  `bun:"patient_id"`
+	DuplicateID        string  `bun:"duplicate_id"`
+	NameSimilarity     float64 `bun:"name_similarity"`
+	SameDateOfBirth    bool    `bun:"same_date_of_birth"`
+	SamePhoneNumber    bool    `bun:"same_phone_number"`
+	SameEmail          bool    `bun:"same_email"`
+	SplitIdentifiers   bool    `bun:"split_identifiers"`
+	IdentifierConflict bool    `bun:"identifier_conflict"`
+	Score              float64 `bun:"score"`
+}
+
+// sharing pairs the live patients of the hospital with the same value in
+// column. It is an equi-join the (hospital, column) indexes serve, an OR of the
+// columns in one join would compare every pair of patients of the hospital.
+func (s *Service) sharing(hospital, column string) *bun.SelectQuery {
+	return s.db.NewSelect().
+		TableExpr("patients AS a").
+		Join("JOIN patients AS b ON b.hospital = a.hospital AND b.? = a.? AND b.id > a.id AND b.deleted_at IS NULL", bun.Ident(column), bun.Ident(column)).
+		ColumnExpr("a.id AS patient_id, b.id AS duplicate_id").
+		Where("a.hospital = ?", hospital).
+		Where("a.deleted_at IS NULL")
+}
+
+// candidates pairs the live patients of the hospital sharing a birth date,
+// phone number or email, the only pairs that can reach a score worth listing,
+// and scores them. The name is compared per script, a TH only patient is not
+// compared with an EN only one. Identifiers cannot be compared across columns,
+// they are encrypted and their blind indexes are keyed per column, so a
+// national ID on one patient and a passport on the other only count as not
+// telling the two apart.
+func (s *Service) candidates(hospital string, minScore float64) *bun.SelectQuery {
+	// UNION drops the pairs sharing more than one of the columns twice
+	matches := s.sharing(hospital, "date_of_birth").
+		Union(s.sharing(hospital, "phone_number_bidx")).
+		Union(s.sharing(hospital, "email_bidx"))
+
+	pairs := s.db.NewSelect().
+		TableExpr("matches").
+		Join("JOIN patients AS a ON a.id = matches.patient_id").
+		Join("JOIN patients AS b ON b.id = matches.duplicate_id").
+		ColumnExpr("matches.patient_id, matches.duplicate_id").
+		ColumnExpr("greatest(similarity(" + fullName("a", "th") + ", " + fullName("b", "th") + "), similarity(" + fullName("a", "en") + ", " + fullName("b", "en") + ")) AS name_similarity").
+		ColumnExpr("coalesce(a.date_of_birth = b.date_of_birth, false) AS same_date_of_birth").
+		ColumnExpr("coalesce(a.phone_number_bidx = b.phone_number_bidx, false) AS same_phone_number").
+		ColumnExpr("coalesce(a.email_bidx = b.email_bidx, false) AS same_email").
+		ColumnExpr("(" + onlyNationalID("a") + " AND " + onlyPassportID("b") + " OR " + onlyPassportID("a") + " AND " + onlyNationalID("b") + ") AS split_identifiers").
+		ColumnExpr("coalesce(a.national_id_bidx <> b.national_id_bidx, false) OR coalesce(a.passport_id_bidx <> b.passport_id_bidx, false) AS identifier_conflict")
+
+	scored := s.db.NewSelect().
+		TableExpr("pairs").
+		ColumnExpr("pairs.*").
+		ColumnExpr("greatest(0, least(1, ? * name_similarity + ? * same_date_of_birth::int + ? * same_phone_number::int + ? * same_email::int + ? * split_identifiers::int - ? * identifier_conflict::int)) AS score",
+			nameWeight, dateOfBirthWeight, phoneNumberWeight, emailWeight, identifierWeight, conflictPenalty)
+
+	return s.db.NewSelect().
+		With("matches", matches).
+		With("pairs", pairs).
+		With("scored", scored).
+		TableExpr("scored").
+		ColumnExpr("scored.*").
+		Where("score >= ?", minScore)
+}
+
+// Duplicates lists the suspected duplicate pairs of the hospital, best first
+func (s *Service) Duplicates(ctx context.Context, req *mergedto.ListDuplicateRequest, hospital string) ([]mergedto.Candidate, int, error) {
+	resp := []mergedto.Candidate{}
+	query := s.candidates(hospital, req.MinScore)
+	total, err := query.Count(ctx)
+	if err != nil {
+		return resp, 0, err
+	}
+	if total == 0 {
+		return resp, 0, nil
+	}
+
+	rows := []candidateRow{}
+	err = query.
+		Order("score DESC", "patient_id ASC", "duplicate_id ASC").
+		Offset((req.Page-1)*req.Size).
+		Limit(req.Size).
+		Scan(ctx, &rows)
+	if err != nil {
+		return resp, 0, err
+	}
+	for _, row := range rows {
+		resp = append(resp, toCandidate(row))
+	}
+	return resp, total, nil
+}
+
+// nameThreshold is the name similarity that counts as a matching name
+const nameThreshold = 0.5
+
+func toCandidate(row candidateRow) mergedto.Candidate {
+	candidate := mergedto.Candidate{
+		PatientID:   row.PatientID,
+		DuplicateID: row.DuplicateID,
+		Score:       row.Score,
+		Reasons:     []string{},
+	}
+	if row.NameSimilarity >= nameThreshold {
+		candidate.Reasons = append(candidate.Reasons, mergedto.MatchName)
+	}
+	if row.SameDateOfBirth {
+		candidate.Reasons = append(candidate.Reasons, mergedto.MatchDateOfBirth)
+	}
+	if row.SamePhoneNumber {
+		candidate.Reasons = append(candidate.Reasons, mergedto.MatchPhoneNumber)
+	}
+	if row.SameEmail {
+		candidate.Reasons = append(candidate.Reasons, mergedto.MatchEmail)
+	}
+	if row.SplitIdentifiers {
+		candidate.Reasons = append(candidate.Reasons, mergedto.MatchIdentifiers)
+	}
+	return candidate
+}
+
+// Patients returns the patients of the hospital with the ids, by id
+func (s *Service) Patients(ctx context.Context, ids []string, hospital string) (map[string]*model.Patient, error) {
+	patients := []*model.Patient{}
+	if len(ids) > 0 {
+		err := s.db.NewSelect().
+			Model(&patients).
+			Where("id IN (?)", bun.In(ids)).
+			Where("hospital = ?", hospital).
+			Scan(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[string]*model.Patient, len(patients))
+	for _, p := range patients {
+		byID[p.ID] = p
+	}
+	return byID, nil
+}
+
+// reference is a column holding patient ids
+type reference struct {
+	Table  string
+	Column string
+}
+
+// references are the columns a merge points from the merged patient to the
+// survivor. The audit log keeps the ids it was written with, it is append-only.
+var references = []reference{
+	// patients merged into the merged patient before
+	{Table: "patients", Column: "merged_into"},
+	{Table: "record_requests", Column: "patient_id"},
+	{Table: "record_requests", Column: "source_patient_id"},
+	{Table: "patient_consents", Column: "patient_id"},
+	{Table: "pdpa_requests", Column: "patient_id"},
+}
+
+// Merge merges the duplicate into the survivor in one transaction: the
+// survivor takes over the fields it lacks, the references to the duplicate
+// move to the survivor, and the duplicate is soft-deleted pointing at the
+// survivor. Two patients with different national IDs or passports are not
+// merged. The duplicate gives up its identifiers, which are unique per
+// hospital deleted patients included, so the survivor can hold them and an
+// import or HIS sync of them finds the survivor.
+func (s *Service) Merge(ctx context.Context, survivorID string, req *mergedto.MergePatientRequest, hospital, staffID string) (*model.Patient, error) {
+	if survivorID == req.DuplicateID {
+		return nil, errors.New(message.PatientMergeSelf)
+	}
+	var survivor *model.Patient
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		patients := []*model.Patient{}
+		err := tx.NewSelect().
+			Model(&patients).
+			Where("id IN (?)", bun.In([]string{survivorID, req.DuplicateID})).
+			Where("hospital = ?", hospital).
+			For("UPDATE").
+			Scan(ctx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if len(patients) != 2 {
+			return errors.New(message.PatientNotFound)
+		}
+		var duplicate *model.Patient
+		for _, p := range patients {
+			if p.ID == survivorID {
+				survivor = p
+			} else {
+				duplicate = p
+			}
+		}
+		if conflicts(survivor, duplicate) {
+			return errors.New(message.PatientMergeConflict)
+		}
+
+		snapshot, err := json.Marshal(patient.ToPatientDetail(duplicate))
+		if err != nil {
+			return err
+		}
+		fields := takeOver(survivor, duplicate)
+
+		now := time.Now()
+		duplicate.NationalID, duplicate.PassportID = "", ""
+		duplicate.MergedInto = survivor.ID
+		duplicate.DeletedAt = &now
+		duplicate.SetUpdateNow()
+		_, err = tx.NewUpdate().
+			Model(duplicate).
+			Column("national_id", "passport_id", "national_id_bidx", "passport_id_bidx", "merged_into", "deleted_at", "updated_at").
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		survivor.SetUpdateNow()
+		columns := append([]string{"updated_at"}, fields...)
+		for _, field := range fields {
+			if _, ok := encryptedFields[field]; ok {
+				columns = append(columns, field+"_bidx")
+			}
+		}
+		_, err = tx.NewUpdate().
+			Model(survivor).
+			Column(columns...).
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, ref := range references {
+			_, err := tx.NewUpdate().
+				Table(ref.Table).
+				Set("? = ?", bun.Ident(ref.Column), survivor.ID).
+				Where("? = ?", bun.Ident(ref.Column), duplicate.ID).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.NewInsert().
+			Model(&model.PatientMerge{
+				Hospital:   hospital,
+				SurvivorID: survivor.ID,
+				MergedID:   duplicate.ID,
+				Fields:     fields,
+				Reason:     req.Reason,
+				MergedBy:   staffID,
+				Snapshot:   fieldcrypt.EncryptedString(snapshot),
+			}).
+			Exec(ctx)
+		if err != nil || s.Links == nil {
+			return err
+		}
+		return s.Links.Link(ctx, tx, survivor.ID, duplicate.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return survivor, nil
+}
+
+// conflicts reports whether the patients hold different national IDs or passports
+func conflicts(a, b *model.Patient) bool {
+	differ := func(x, y string) bool { return x != "" && y != "" && x != y }
+	return differ(a.NationalIDIndex, b.NationalIDIndex) || differ(a.PassportIDIndex, b.PassportIDIndex)
+}
+
+// encryptedFields are the fields takeOver can move that have a blind index
+var encryptedFields = map[string]struct{}{
+	"national_id": {}, "passport_id": {}, "phone_number": {}, "email": {},
+}
+
+// takeOver fills the empty fields of the survivor from the duplicate and
+// returns the columns it filled
+func takeOver(survivor, duplicate *model.Patient) []string {
+	fields := []string{}
+	text := func(column string, to *string, from string) {
+		if *to == "" && from != "" {
+			*to = from
+			fields = append(fields, column)
+		}
+	}
+	encrypted := func(column string, to *fieldcrypt.EncryptedString, from fieldcrypt.EncryptedString) {
+		if *to == "" && from != "" {
+			*to = from
+			fields = append(fields, column)
+		}
+	}
+	text("first_name_th", &survivor.FirstNameTH, duplicate.FirstNameTH)
+	text("middle_name_th", &survivor.MiddleNameTH, duplicate.MiddleNameTH)
+	text("last_name_th", &survivor.LastNameTH, duplicate.LastNameTH)
+	text("first_name_en", &survivor.FirstNameEN, duplicate.FirstNameEN)
+	text("middle_name_en", &survivor.MiddleNameEN, duplicate.MiddleNameEN)
+	text("last_name_en", &survivor.LastNameEN, duplicate.LastNameEN)
+	if survivor.DateOfBirth.IsZero() && !duplicate.DateOfBirth.IsZero() {
+		survivor.DateOfBirth = duplicate.DateOfBirth
+		fields = append(fields, "date_of_birth")
+	}
+	encrypted("national_id", &survivor.NationalID, duplicate.NationalID)
+	encrypted("passport_id", &survivor.PassportID, duplicate.PassportID)
+	encrypted("phone_number", &survivor.PhoneNumber, duplicate.PhoneNumber)
+	encrypted("email", &survivor.Email, duplicate.Email)
+	if (survivor.Gender == "" || survivor.Gender == "0") && duplicate.Gender != "" && duplicate.Gender != "0" {
+		survivor.Gender = duplicate.Gender
+		fields = append(fields, "gender")
+	}
+	return fields
+}
+
+// History lists the merges the patient of the hospital took part in, as
+// survivor or as the merged patient, newest first
+func (s *Service) History(ctx context.Context, id string, hospital string) ([]*model.PatientMerge, error) {
+	resp := []*model.PatientMerge{}
+	err := s.db.NewSelect().
+		Model(&resp).
+		Where("hospital = ?", hospital).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("survivor_id = ?", id).WhereOr("merged_id = ?", id)
+		}).
+		Order("created_at DESC", "id DESC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return resp, err
+	}
+	return resp, nil
+}
@@ -1,24 +1,112 @@
 package modules
 
 import (
+	"app/app/modules/audit"
+	"app/app/modules/consent"
+	"app/app/modules/fhirapi"
+	"app/app/modules/hospital"
+	"app/app/modules/importer"
+	"app/app/modules/merge"
+	"app/app/modules/mpi"
 	"app/app/modules/patient"
+	"app/app/modules/pdpa"
 	"app/app/modules/staff"
+	"app/app/util/fieldcrypt"
+	"app/app/util/his"
+	"app/app/util/hn"
+	"app/app/util/pii"
 	"app/config"
+	"app/database/rbac"
+	"app/internal/logger"
+	"context"
+	"log"
+	"sync"
 )
 
 type Module struct {
-	Patient *patient.Module
-	Staff   *staff.Module
+	Audit    *audit.Module
+	Consent  *consent.Module
+	FHIR     *fhirapi.Module
+	Hospital *hospital.Module
+	Importer *importer.Module
+	Merge    *merge.Module
+	MPI      *mpi.Module
+	Patient  *patient.Module
+	PDPA     *pdpa.Module
+	Staff    *staff.Module
 }
 
+var (
+	once   sync.Once
+	module *Module
+)
+
+// New builds the modules once, every route group shares them so the
+// HIS registry edited through the hospital module is the one patients use
 func New() *Module {
+	once.Do(func() {
+		module = newModule()
+	})
+	return module
+}
+
+func newModule() *Module {
 
 	db := config.GetDB()
-	patient := patient.NewModule(db)
+	// permissions added to the matrix since the last start are granted before any request
+	if err := rbac.Sync(context.Background(), db); err != nil {
+		log.Fatalf("Failed to sync role permissions: %v", err)
+	}
+	registry, err := his.Load()
+	if err != nil {
+		log.Fatalf("Failed to load HIS adapters: %v", err)
+	}
+	hnFormat, err := hn.Load()
+	if err != nil {
+		log.Fatalf("Failed to load HN format: %v", err)
+	}
+	keyring, err := fieldcrypt.Load()
+	if err != nil {
+		log.Fatalf("Failed to load field encryption keys: %v", err)
+	}
+	fieldcrypt.Use(keyring)
+	policy, err := pii.Load()
+	if err != nil {
+		log.Fatalf("Failed to load PII masking policy: %v", err)
+	}
+	pii.Use(policy)
+	hospital := hospital.NewModule(db, registry)
+	if err := hospital.Svc.LoadAdapters(context.Background()); err != nil {
+		logger.Errf("Failed to load hospital HIS adapters: %s", err)
+	}
+	consent := consent.NewModule(db)
+	mpi := mpi.NewModule(db, consent.Svc)
+	patient := patient.NewModule(db, registry, hnFormat, mpi.Svc)
+	// patients without blind indexes would be missed by lookups and duplicated by syncs
+	unindexed, err := patient.Svc.Unindexed(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to check patient blind indexes: %v", err)
+	}
+	if unindexed > 0 {
+		log.Fatalf("%d patients have no blind indexes yet, run `go run . cmd reencrypt-patients` first", unindexed)
+	}
+	importer := importer.NewModule(db, patient.Svc)
+	fhir := fhirapi.NewModule(db, patient.Svc)
+	merge := merge.NewModule(db, mpi.Svc)
+	pdpa := pdpa.NewModule(db, mpi.Svc)
 	staff := staff.NewModule(db)
+	audit := audit.NewModule(db)
 
 	return &Module{
-		Patient: patient,
-		Staff:   staff,
+		Audit:    audit,
+		Consent:  consent,
+		FHIR:     fhir,
+		Hospital: hospital,
+		Importer: importer,
+		Merge:    merge,
+		MPI:      mpi,
+		Patient:  patient,
+		PDPA:     pdpa,
+		Staff:    staff,
 	}
 }
@@ -0,0 +1,285 @@
+package mpi
+
+import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	mpidto "app/app/modules/mpi/dto"
+	"app/app/util/jwt"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MPIMockService for testing
+type MPIMockService struct {
+	mock.Mock
+}
+
+func (m *MPIMockService) Links(ctx context.Context, id string, hospital string, purpose enum.Purpose) ([]*model.PatientLink, error) {
+	args := m.Called(ctx, id, hospital, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.PatientLink), args.Error(1)
+}
+
+func (m *MPIMockService) Request(ctx context.Context, id string, req *mpidto.CreateRecordRequest, hospital, staffID string, purpose enum.Purpose) (*model.RecordRequest, error) {
+	args := m.Called(ctx, id, req, hospital, staffID, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RecordRequest), args.Error(1)
+}
+
+func (m *MPIMockService) Requests(ctx context.Context, req *mpidto.ListRecordRequest, hospital string) ([]*model.RecordRequest, int, error) {
+	args := m.Called(ctx, req, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*model.RecordRequest), args.Int(1), args.Error(2)
+}
+
+func (m *MPIMockService) Decide(ctx context.Context, id string, req *mpidto.DecideRecordRequest, hospital, staffID string) (*model.RecordRequest, error) {
+	args := m.Called(ctx, id, req, hospital, staffID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RecordRequest), args.Error(1)
+}
+
+func (m *MPIMockService) Record(ctx context.Context, id string, hospital string) (*model.Patient, error) {
+	args := m.Called(ctx, id, hospital)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Patient), args.Error(1)
+}
+
+const (
+	patientID       = "0b9e8f3a-4c2d-4f51-9a47-6f1d2c3b4a59"
+	sourcePatientID = "65e08e33-9f57-45fe-b725-82242e3581ad"
+	requestID       = "3f1c2b7e-8a94-4d0e-b6c5-1e2f3a4b5c6d"
+)
+
+var claims = &jwt.Claims{Data: jwt.ClaimData{
+	ID:          "staff-1",
+	Hospital:    "hospital-a",
+	Role:        "doctor",
+	Permissions: []string{"patient:read", "mpi:request"},
+}}
+
+func TestDenyAll(t *testing.T) {
+	allowed, err := DenyAll{}.Allowed(context.Background(), &model.Patient{ID: sourcePatientID, Hospital: "hospital-b"}, "hospital-a", enum.PURPOSE_TREATMENT)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestMPIController_Links(t *testing.T) {
+	t.Run("Success - Links disclosed to the hospital", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+		mockService.On("Links", mock.Anything, patientID, "hospital-a", enum.PURPOSE_TREATMENT).
+			Return([]*model.PatientLink{{PatientID: sourcePatientID, PersonID: "person-1", Hospital: "hospital-b", MatchedOn: MatchNationalID}}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/"+patientID+"/links", nil, claims)
+		c.Request.Header.Set("X-Purpose-Of-Use", "treatment")
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Links(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), "hospital-b")
+		assert.NotContains(t, w.Body.String(), "person-1", "the person id is not returned")
+		assert.Equal(t, []string{patientID, sourcePatientID}, helper.GetAuditPatients(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Unknown purpose of use", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/"+patientID+"/links", nil, claims)
+		c.Request.Header.Set("X-Purpose-Of-Use", "marketing")
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Links(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "Links", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestMPIController_Request(t *testing.T) {
+	req := &mpidto.CreateRecordRequest{SourcePatientID: sourcePatientID, Reason: "Referred for follow-up care"}
+
+	t.Run("Success - Request created", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+		mockService.On("Request", mock.Anything, patientID, req, "hospital-a", "staff-1", enum.Purpose("")).
+			Return(&model.RecordRequest{ID: requestID, PatientID: patientID, SourcePatientID: sourcePatientID, Status: enum.RECORD_REQUEST_PENDING}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/record-requests", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Request(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"pending"`)
+		assert.Equal(t, []string{patientID, sourcePatientID}, helper.GetAuditPatients(c))
+		assert.Equal(t, req.Reason, helper.GetAuditReason(c))
+		mockService.AssertExpectations(t)
+	})
+
+	cases := map[string]struct {
+		err  string
+		code int
+	}{
+		"Fail - Consent required":  {message.PatientConsentRequired, 403},
+		"Fail - Not linked":        {message.PatientNotLinked, 400},
+		"Fail - Patient not found": {message.PatientNotFound, 404},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			testhelper.UseJSONNaming(t)
+			mockService := new(MPIMockService)
+			mockService.On("Request", mock.Anything, patientID, req, "hospital-a", "staff-1", enum.Purpose("")).Return(nil, errors.New(tc.err))
+
+			controller := NewController(mockService)
+			c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/record-requests", req, claims)
+			c.Params = gin.Params{{Key: "id", Value: patientID}}
+			controller.Request(c)
+
+			assert.Equal(t, tc.code, w.Code)
+			assert.Contains(t, w.Body.String(), tc.err)
+		})
+	}
+
+	t.Run("Fail - Reason missing", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/record-requests", map[string]string{"source_patient_id": sourcePatientID}, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Request(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "Request", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestMPIController_Requests(t *testing.T) {
+	t.Run("Success - Incoming requests audit the own patients", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+		mockService.On("Requests", mock.Anything, &mpidto.ListRecordRequest{Page: 1, Size: 20, Status: enum.RECORD_REQUEST_PENDING, Direction: mpidto.DirectionIncoming}, "hospital-a").
+			Return([]*model.RecordRequest{{ID: requestID, PatientID: patientID, SourcePatientID: sourcePatientID}}, 1, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/record-requests/incoming?status=pending", nil, claims)
+		controller.Incoming(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, []string{sourcePatientID}, helper.GetAuditPatients(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Unknown status", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/record-requests/outgoing?status=lost", nil, claims)
+		controller.Outgoing(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "Requests", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestMPIController_Decide(t *testing.T) {
+	req := &mpidto.DecideRecordRequest{Status: enum.RECORD_REQUEST_APPROVED}
+
+	t.Run("Success - Request approved", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+		mockService.On("Decide", mock.Anything, requestID, req, "hospital-a", "staff-1").
+			Return(&model.RecordRequest{ID: requestID, SourcePatientID: sourcePatientID, Status: enum.RECORD_REQUEST_APPROVED}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("PUT", "/patient/record-requests/"+requestID, req, claims)
+		c.Params = gin.Params{{Key: "id", Value: requestID}}
+		controller.Decide(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, []string{sourcePatientID}, helper.GetAuditPatients(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Already decided", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+		mockService.On("Decide", mock.Anything, requestID, req, "hospital-a", "staff-1").Return(nil, errors.New(message.RecordRequestDecided))
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("PUT", "/patient/record-requests/"+requestID, req, claims)
+		c.Params = gin.Params{{Key: "id", Value: requestID}}
+		controller.Decide(c)
+
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), message.RecordRequestDecided)
+	})
+
+	t.Run("Fail - Pending is not a decision", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("PUT", "/patient/record-requests/"+requestID, map[string]string{"status": "pending"}, claims)
+		c.Params = gin.Params{{Key: "id", Value: requestID}}
+		controller.Decide(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "Decide", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestMPIController_Record(t *testing.T) {
+	t.Run("Success - Record shaped for the caller", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+		mockService.On("Record", mock.Anything, requestID, "hospital-a").
+			Return(&model.Patient{ID: sourcePatientID, Hospital: "hospital-b", NationalID: "1103702071811"}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/record-requests/"+requestID+"/record", nil, claims)
+		c.Params = gin.Params{{Key: "id", Value: requestID}}
+		controller.Record(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.NotContains(t, w.Body.String(), "1103702071811")
+		assert.Contains(t, w.Body.String(), "*********1811")
+		assert.Equal(t, []string{sourcePatientID}, helper.GetAuditPatients(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Not approved", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(MPIMockService)
+		mockService.On("Record", mock.Anything, requestID, "hospital-a").Return(nil, errors.New(message.RecordRequestNotApproved))
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/record-requests/"+requestID+"/record", nil, claims)
+		c.Params = gin.Params{{Key: "id", Value: requestID}}
+		controller.Record(c)
+
+		assert.Equal(t, 400, w.Code)
+	})
+}
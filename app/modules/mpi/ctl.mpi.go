@@ -0,0 +1,202 @@
+package mpi
+
+import (
+	"app/app/helper"
+	"app/app/message"
+	mpidto "app/app/modules/mpi/dto"
+	"app/app/modules/patient"
+	"app/app/response"
+	"app/app/util/pii"
+	"app/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller struct {
+	Service ServiceInterface
+}
+
+func NewController(svc ServiceInterface) *Controller {
+	return &Controller{
+		Service: svc,
+	}
+}
+
+// Links lists the records of the patient at other hospitals the person
+// agreed to disclose to the caller's hospital
+func (c *Controller) Links(ctx *gin.Context) {
+	id := new(mpidto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	purpose, err := helper.GetPurpose(ctx)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Links(ctx, id.ID, user.Data.Hospital, purpose)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	for _, link := range data {
+		helper.AuditPatients(ctx, link.PatientID)
+	}
+	response.Success(ctx, data)
+}
+
+// Request asks the hospital of a linked record for it
+func (c *Controller) Request(ctx *gin.Context) {
+	id := new(mpidto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := new(mpidto.CreateRecordRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID, req.SourcePatientID)
+	helper.AuditReason(ctx, req.Reason)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	purpose, err := helper.GetPurpose(ctx)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Request(ctx, id.ID, req, user.Data.Hospital, user.Data.ID, purpose)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, data)
+}
+
+// Incoming lists the record requests other hospitals made to the caller's hospital
+func (c *Controller) Incoming(ctx *gin.Context) {
+	c.requests(ctx, mpidto.DirectionIncoming)
+}
+
+// Outgoing lists the record requests the caller's hospital made
+func (c *Controller) Outgoing(ctx *gin.Context) {
+	c.requests(ctx, mpidto.DirectionOutgoing)
+}
+
+func (c *Controller) requests(ctx *gin.Context, direction string) {
+	req := mpidto.ListRecordRequest{
+		Page: 1,
+		Size: 20,
+	}
+	if err := ctx.BindQuery(&req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	req.Size = response.PageSize(req.Size)
+	req.Direction = direction
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, total, err := c.Service.Requests(ctx, &req, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	for _, request := range data {
+		if req.Direction == mpidto.DirectionOutgoing {
+			helper.AuditPatients(ctx, request.PatientID)
+		} else {
+			helper.AuditPatients(ctx, request.SourcePatientID)
+		}
+	}
+	response.SuccessWithPaginate(ctx, data, req.Page, req.Size, total)
+}
+
+// Decide approves or denies a request made to the caller's hospital
+func (c *Controller) Decide(ctx *gin.Context) {
+	id := new(mpidto.RecordRequestIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := new(mpidto.DecideRecordRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, err := c.Service.Decide(ctx, id.ID, req, user.Data.Hospital, user.Data.ID)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	helper.AuditPatients(ctx, data.SourcePatientID)
+	response.Success(ctx, data)
+}
+
+// Record returns the record of an approved request, shaped for the caller
+func (c *Controller) Record(ctx *gin.Context) {
+	id := new(mpidto.RecordRequestIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	purpose, err := helper.GetPurpose(ctx)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Record(ctx, id.ID, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	helper.AuditPatients(ctx, data.ID)
+	response.Success(ctx, patient.ToPatientDetail(data).Shape(pii.ViewerOf(user.Data, purpose)))
+}
+
+// respondError maps the message of a service error onto its status code
+func respondError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case message.PatientNotFound, message.RecordRequestNotFound:
+		response.NotFound(ctx, err.Error(), nil)
+	case message.PatientConsentRequired:
+		response.Forbidden(ctx, err.Error(), nil)
+	case message.PatientNotLinked, message.RecordRequestDecided, message.RecordRequestNotApproved:
+		response.BadRequest(ctx, err.Error(), nil)
+	default:
+		response.InternalError(ctx, err.Error(), nil)
+	}
+}
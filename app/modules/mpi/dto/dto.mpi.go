@@ -0,0 +1,38 @@
+package mpidto
+
+import "app/app/enum"
+
+type PatientIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type RecordRequestIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// CreateRecordRequest asks the hospital of a linked record for it
+type CreateRecordRequest struct {
+	SourcePatientID string `json:"source_patient_id" binding:"required,uuid"`
+	Reason          string `json:"reason" binding:"required,min=10,max=500"`
+}
+
+// The directions of the record requests of a hospital
+const (
+	DirectionIncoming = "incoming"
+	DirectionOutgoing = "outgoing"
+)
+
+// ListRecordRequest pages the requests other hospitals made to the caller's
+// hospital (incoming) or the ones it made (outgoing), the direction is set by
+// the route
+type ListRecordRequest struct {
+	Page      int                      `form:"page"`
+	Size      int                      `form:"size"`
+	Status    enum.RecordRequestStatus `form:"status" binding:"omitempty,oneof=pending approved denied"`
+	Direction string                   `form:"-"`
+}
+
+// DecideRecordRequest approves or denies a pending request
+type DecideRecordRequest struct {
+	Status enum.RecordRequestStatus `json:"status" binding:"required,oneof=approved denied"`
+}
@@ -0,0 +1,33 @@
+package mpi
+
+import (
+	"app/app/enum"
+	"app/app/model"
+	mpidto "app/app/modules/mpi/dto"
+	"context"
+)
+
+type ServiceInterface interface {
+	Links(ctx context.Context, id string, hospital string, purpose enum.Purpose) ([]*model.PatientLink, error)
+	Request(ctx context.Context, id string, req *mpidto.CreateRecordRequest, hospital, staffID string, purpose enum.Purpose) (*model.RecordRequest, error)
+	Requests(ctx context.Context, req *mpidto.ListRecordRequest, hospital string) ([]*model.RecordRequest, int, error)
+	Decide(ctx context.Context, id string, req *mpidto.DecideRecordRequest, hospital, staffID string) (*model.RecordRequest, error)
+	Record(ctx context.Context, id string, hospital string) (*model.Patient, error)
+}
+
+var _ ServiceInterface = (*Service)(nil)
+
+// ConsentChecker tells whether the patient agreed to their record being
+// disclosed to another hospital for the purpose. Only the ID and Hospital of
+// the patient are set for certain.
+type ConsentChecker interface {
+	Allowed(ctx context.Context, patient *model.Patient, hospital string, purpose enum.Purpose) (bool, error)
+}
+
+// DenyAll is a ConsentChecker that discloses no record to another hospital,
+// see consent.Service for the one backed by recorded consents
+type DenyAll struct{}
+
+func (DenyAll) Allowed(ctx context.Context, patient *model.Patient, hospital string, purpose enum.Purpose) (bool, error) {
+	return false, nil
+}
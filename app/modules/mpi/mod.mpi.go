@@ -0,0 +1,18 @@
+package mpi
+
+import (
+	"github.com/uptrace/bun"
+)
+
+type Module struct {
+	Ctl *Controller
+	Svc *Service
+}
+
+func NewModule(db *bun.DB, consent ConsentChecker) *Module {
+	svc := NewService(db, consent)
+	return &Module{
+		Ctl: NewController(svc),
+		Svc: svc,
+	}
+}
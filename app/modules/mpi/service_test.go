@@ -0,0 +1,215 @@
+package mpi
+
+import (
+	"app/app/enum"
+	"app/app/helper/testhelper"
+	"app/app/model"
+	"app/app/util/fieldcrypt"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+// allowAll discloses every record, so Links lists the whole group
+type allowAll struct{}
+
+func (allowAll) Allowed(ctx context.Context, patient *model.Patient, hospital string, purpose enum.Purpose) (bool, error) {
+	return true, nil
+}
+
+// identifier is a passport or national ID no other test holds, the test
+// database is shared and linking reads every hospital
+func identifier() string {
+	return strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:12])
+}
+
+var born = time.Date(1985, 3, 9, 0, 0, 0, 0, time.UTC)
+
+// record inserts a patient born on born at a hospital of its own
+func record(t *testing.T, db bun.IDB, nationalID, passportID string) *model.Patient {
+	t.Helper()
+	patient := &model.Patient{
+		FirstNameEN: "Somchai",
+		DateOfBirth: born,
+		PatientHN:   "HN000001",
+		NationalID:  fieldcrypt.EncryptedString(nationalID),
+		PassportID:  fieldcrypt.EncryptedString(passportID),
+		Hospital:    testhelper.Hospital(t, db),
+	}
+	_, err := db.NewInsert().Model(patient).Returning("*").Exec(context.Background())
+	require.NoError(t, err)
+	return patient
+}
+
+// personOf returns the person the patient is linked to, empty when it is in no group
+func personOf(t *testing.T, db bun.IDB, id string) string {
+	t.Helper()
+	link := new(model.PatientLink)
+	err := db.NewSelect().Model(link).Where("patient_id = ?", id).Scan(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ""
+	}
+	require.NoError(t, err)
+	return link.PersonID
+}
+
+func TestService_Link(t *testing.T) {
+	ctx := context.Background()
+	db := testhelper.DB(t)
+	svc := NewService(db, allowAll{})
+
+	t.Run("Success - Links The Records Of A Person Across Hospitals", func(t *testing.T) {
+		passport := identifier()
+		a := record(t, db, "", passport)
+		b := record(t, db, "", passport)
+		other := record(t, db, "", identifier())
+
+		require.NoError(t, svc.Link(ctx, db, a.ID, other.ID))
+
+		person := personOf(t, db, a.ID)
+		assert.NotEmpty(t, person)
+		assert.Equal(t, person, personOf(t, db, b.ID))
+		assert.Empty(t, personOf(t, db, other.ID))
+	})
+
+	t.Run("Success - Joins Two Groups Through A Record Of Both", func(t *testing.T) {
+		nationalID, passport := identifier(), identifier()
+		a := record(t, db, nationalID, "")
+		b := record(t, db, nationalID, "")
+		c := record(t, db, "", passport)
+		d := record(t, db, "", passport)
+		require.NoError(t, svc.Link(ctx, db, a.ID, c.ID))
+		assert.NotEqual(t, personOf(t, db, a.ID), personOf(t, db, c.ID))
+
+		bridge := record(t, db, nationalID, passport)
+		require.NoError(t, svc.Link(ctx, db, bridge.ID))
+
+		person := personOf(t, db, bridge.ID)
+		for _, p := range []*model.Patient{a, b, c, d} {
+			assert.Equal(t, person, personOf(t, db, p.ID))
+		}
+		link := new(model.PatientLink)
+		require.NoError(t, db.NewSelect().Model(link).Where("patient_id = ?", c.ID).Scan(ctx))
+		assert.Equal(t, MatchPassportID, link.MatchedOn)
+	})
+
+	t.Run("Success - Relinks A Record Whose Identifier Changed", func(t *testing.T) {
+		passport := identifier()
+		a := record(t, db, "", passport)
+		b := record(t, db, "", passport)
+		require.NoError(t, svc.Link(ctx, db, a.ID))
+		require.NotEmpty(t, personOf(t, db, b.ID))
+
+		b.PassportID = fieldcrypt.EncryptedString(identifier())
+		_, err := db.NewUpdate().Model(b).Column("passport_id", "passport_id_bidx").WherePK().Exec(ctx)
+		require.NoError(t, err)
+		require.NoError(t, svc.Link(ctx, db, b.ID))
+
+		assert.Empty(t, personOf(t, db, a.ID))
+		assert.Empty(t, personOf(t, db, b.ID))
+	})
+
+	t.Run("Success - Splits The Group Of A Record That Held It Together", func(t *testing.T) {
+		nationalID, passport := identifier(), identifier()
+		bridge := record(t, db, nationalID, passport)
+		a := record(t, db, nationalID, "")
+		b := record(t, db, "", passport)
+		c := record(t, db, "", passport)
+		require.NoError(t, svc.Link(ctx, db, bridge.ID))
+		require.Equal(t, personOf(t, db, a.ID), personOf(t, db, b.ID))
+
+		_, err := db.NewDelete().Model(bridge).WherePK().Exec(ctx)
+		require.NoError(t, err)
+		require.NoError(t, svc.Link(ctx, db, bridge.ID))
+
+		assert.Empty(t, personOf(t, db, bridge.ID))
+		assert.Empty(t, personOf(t, db, a.ID))
+		assert.NotEmpty(t, personOf(t, db, b.ID))
+		assert.Equal(t, personOf(t, db, b.ID), personOf(t, db, c.ID))
+	})
+
+	t.Run("Success - Links Records Of A Person Written At Once", func(t *testing.T) {
+		passport := identifier()
+		first, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer first.Rollback()
+		second, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer second.Rollback()
+
+		a := record(t, first, "", passport)
+		require.NoError(t, svc.Link(ctx, first, a.ID))
+		b := record(t, second, "", passport)
+		done := make(chan error, 1)
+		go func() {
+			// waits for the first writer, then sees its record
+			if err := svc.Link(ctx, second, b.ID); err != nil {
+				done <- err
+				return
+			}
+			done <- second.Commit()
+		}()
+		require.NoError(t, first.Commit())
+		require.NoError(t, <-done)
+
+		assert.NotEmpty(t, personOf(t, db, a.ID))
+		assert.Equal(t, personOf(t, db, a.ID), personOf(t, db, b.ID))
+	})
+
+	t.Run("Success - Unlinks A Patient That No Longer Exists", func(t *testing.T) {
+		passport := identifier()
+		a := record(t, db, "", passport)
+		b := record(t, db, "", passport)
+		require.NoError(t, svc.Link(ctx, db, a.ID))
+
+		_, err := db.NewDelete().Model(b).WherePK().ForceDelete().Exec(ctx)
+		require.NoError(t, err)
+		require.NoError(t, svc.Link(ctx, db, b.ID))
+
+		assert.Empty(t, personOf(t, db, a.ID))
+	})
+}
+
+func TestService_Links(t *testing.T) {
+	ctx := context.Background()
+	db := testhelper.DB(t)
+	svc := NewService(db, allowAll{})
+
+	t.Run("Success - Lists The Linked Records Without Linking", func(t *testing.T) {
+		passport := identifier()
+		a := record(t, db, "", passport)
+		b := record(t, db, "", passport)
+
+		links, err := svc.Links(ctx, a.ID, a.Hospital, enum.PURPOSE_TREATMENT)
+		assert.NoError(t, err)
+		assert.Empty(t, links)
+		assert.Empty(t, personOf(t, db, a.ID))
+
+		require.NoError(t, svc.Link(ctx, db, a.ID))
+		links, err = svc.Links(ctx, a.ID, a.Hospital, enum.PURPOSE_TREATMENT)
+		assert.NoError(t, err)
+		if assert.Len(t, links, 1) {
+			assert.Equal(t, b.ID, links[0].PatientID)
+			assert.Equal(t, MatchPassportID, links[0].MatchedOn)
+		}
+	})
+
+	t.Run("Success - Leaves Out Records The Person Did Not Disclose", func(t *testing.T) {
+		passport := identifier()
+		a := record(t, db, "", passport)
+		record(t, db, "", passport)
+		require.NoError(t, svc.Link(ctx, db, a.ID))
+
+		links, err := NewService(db, DenyAll{}).Links(ctx, a.ID, a.Hospital, enum.PURPOSE_TREATMENT)
+		assert.NoError(t, err)
+		assert.Empty(t, links)
+	})
+}
@@ -0,0 +1,524 @@
+package mpi
+
+import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/model"
+	mpidto "app/app/modules/mpi/dto"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type Service struct {
+	db      *bun.DB
+	consent ConsentChecker
+}
+
+func NewService(db *bun.DB, consent ConsentChecker) *Service {
+	return &Service{
+		db:      db,
+		consent: consent,
+	}
+}
+
+// The identifiers records are linked on
+const (
+	MatchNationalID = "national_id"
+	MatchPassportID = "passport_id"
+)
+
+// linkColumns are the patient columns linking reads, the blind indexes rather
+// than the encrypted identifiers, so nothing is decrypted
+var linkColumns = []string{"id", "hospital", "date_of_birth", "national_id_bidx", "passport_id_bidx", "deleted_at"}
+
+// Link relinks the patients after they were written in db, the transaction of
+// the write, see link. A patient that no longer exists is only taken out of its
+// group.
+func (s *Service) Link(ctx context.Context, db bun.IDB, ids ...string) error {
+	patients := make([]*model.Patient, 0, len(ids))
+	for _, id := range ids {
+		p := new(model.Patient)
+		err := db.NewSelect().
+			Model(p).
+			Column(linkColumns...).
+			WhereAllWithDeleted().
+			Where("id = ?", id).
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			// matches nobody
+			p = &model.Patient{ID: id}
+		} else if err != nil {
+			return err
+		}
+		patients = append(patients, p)
+	}
+	if err := s.lock(ctx, db, patients...); err != nil {
+		return err
+	}
+	for _, p := range patients {
+		if err := s.link(ctx, db, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// link takes the patient out of its group and puts it in one with the live
+// patients of the other hospitals holding the same national ID or passport and
+// born the same day, the identifiers alone are not trusted to be typed right.
+// Links are followed from one record to the next, so groups the patient joins
+// together become one. The others of its old group are regrouped the same way,
+// they may have been linked through the patient alone. A deleted patient or a
+// patient that matches nobody is in no group.
+func (s *Service) link(ctx context.Context, db bun.IDB, p *model.Patient) error {
+	members := []string{}
+	err := db.NewSelect().
+		Model((*model.PatientLink)(nil)).
+		Column("patient_id").
+		Where("person_id = (?)", db.NewSelect().
+			Model((*model.PatientLink)(nil)).
+			Column("person_id").
+			Where("patient_id = ?", p.ID)).
+		Where("patient_id <> ?", p.ID).
+		Order("patient_id ASC").
+		Scan(ctx, &members)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	_, err = db.NewDelete().
+		Model((*model.PatientLink)(nil)).
+		Where("patient_id IN (?)", bun.In(append([]string{p.ID}, members...))).
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	grouped := map[string]bool{}
+	if err := s.regroup(ctx, db, p, grouped); err != nil {
+		return err
+	}
+	for _, id := range members {
+		if grouped[id] {
+			continue
+		}
+		member := new(model.Patient)
+		err := db.NewSelect().
+			Model(member).
+			Column(linkColumns...).
+			Where("id = ?", id).
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			// deleted since, its link is gone already
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.regroup(ctx, db, member, grouped); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lock takes the transaction-level advisory locks of the identifiers of the
+// patients and of their groups, so writers linking a person run one after the
+// other and each sees the records the one before committed. Two records of a
+// person written at once at two hospitals would otherwise both stay alone. The
+// locks are taken in order so writers cannot deadlock on them, and last until
+// the transaction of db ends.
+func (s *Service) lock(ctx context.Context, db bun.IDB, patients ...*model.Patient) error {
+	ids := make([]string, 0, len(patients))
+	for _, p := range patients {
+		ids = append(ids, p.ID)
+	}
+	group := []*model.Patient{}
+	err := db.NewSelect().
+		Model(&group).
+		Column("national_id_bidx", "passport_id_bidx").
+		WhereAllWithDeleted().
+		Where("id IN (?)", db.NewSelect().
+			Model((*model.PatientLink)(nil)).
+			Column("patient_id").
+			Where("person_id IN (?)", db.NewSelect().
+				Model((*model.PatientLink)(nil)).
+				Column("person_id").
+				Where("patient_id IN (?)", bun.In(ids)))).
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	keys := []string{}
+	for _, patient := range append(group, patients...) {
+		if patient.NationalIDIndex != "" {
+			keys = append(keys, MatchNationalID+":"+patient.NationalIDIndex)
+		}
+		if patient.PassportIDIndex != "" {
+			keys = append(keys, MatchPassportID+":"+patient.PassportIDIndex)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range slices.Compact(keys) {
+		_, err := db.NewRaw("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", key).Exec(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// regroup links the patient and the records reachable from it by matches as
+// one person, marking them in grouped
+func (s *Service) regroup(ctx context.Context, db bun.IDB, p *model.Patient, grouped map[string]bool) error {
+	person := uuid.NewString()
+	grouped[p.ID] = true
+	links := []*model.PatientLink{}
+	own := ""
+	queue := []*model.Patient{p}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		matches, err := s.matches(ctx, db, current)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			on := MatchPassportID
+			if current.NationalIDIndex != "" && match.NationalIDIndex == current.NationalIDIndex {
+				on = MatchNationalID
+			}
+			if current == p && own != MatchNationalID {
+				own = on
+			}
+			if grouped[match.ID] {
+				continue
+			}
+			grouped[match.ID] = true
+			links = append(links, &model.PatientLink{PatientID: match.ID, PersonID: person, Hospital: match.Hospital, MatchedOn: on})
+			queue = append(queue, match)
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	links = append(links, &model.PatientLink{PatientID: p.ID, PersonID: person, Hospital: p.Hospital, MatchedOn: own})
+	// the records joined from other groups move over with their links
+	_, err := db.NewInsert().
+		Model(&links).
+		On("CONFLICT (patient_id) DO UPDATE").
+		Set("person_id = EXCLUDED.person_id").
+		Set("matched_on = EXCLUDED.matched_on").
+		Set("updated_at = EXTRACT(EPOCH FROM NOW())").
+		Exec(ctx)
+	return err
+}
+
+// matches returns the live patients of the other hospitals with the national
+// ID or passport of the patient and born the same day
+func (s *Service) matches(ctx context.Context, db bun.IDB, p *model.Patient) ([]*model.Patient, error) {
+	matches := []*model.Patient{}
+	if p.DeletedAt != nil || (p.NationalIDIndex == "" && p.PassportIDIndex == "") || p.DateOfBirth.IsZero() {
+		return matches, nil
+	}
+	err := db.NewSelect().
+		Model(&matches).
+		Column(linkColumns...).
+		Where("hospital <> ?", p.Hospital).
+		Where("date_of_birth = ?", p.DateOfBirth).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			if p.NationalIDIndex != "" {
+				q.WhereOr("national_id_bidx = ?", p.NationalIDIndex)
+			}
+			if p.PassportIDIndex != "" {
+				q.WhereOr("passport_id_bidx = ?", p.PassportIDIndex)
+			}
+			return q
+		}).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// linkBatchSize is how many patients LinkAll links per transaction
+const linkBatchSize = 500
+
+// LinkAll links every live patient and drops the links of deleted ones,
+// returning how many patients it linked
+func (s *Service) LinkAll(ctx context.Context) (int, error) {
+	_, err := s.db.NewDelete().
+		Model((*model.PatientLink)(nil)).
+		Where("patient_id IN (?)", s.db.NewSelect().
+			Model((*model.Patient)(nil)).
+			Column("id").
+			WhereDeleted()).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total, after := 0, ""
+	for {
+		batch := []*model.Patient{}
+		query := s.db.NewSelect().
+			Model(&batch).
+			Column(linkColumns...).
+			Order("id ASC").
+			Limit(linkBatchSize)
+		if after != "" {
+			query.Where("id > ?", after)
+		}
+		if err := query.Scan(ctx); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+		err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			if err := s.lock(ctx, tx, batch...); err != nil {
+				return err
+			}
+			for _, p := range batch {
+				if err := s.link(ctx, tx, p); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += len(batch)
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// patient returns the live patient of the hospital with only linkColumns read
+func (s *Service) patient(ctx context.Context, id string, hospital string) (*model.Patient, error) {
+	p := new(model.Patient)
+	err := s.db.NewSelect().
+		Model(p).
+		Column(linkColumns...).
+		Where("id = ?", id).
+		Where("hospital = ?", hospital).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.PatientNotFound)
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// Links returns the records of the same person at other hospitals the person
+// agreed to disclose to the hospital of the patient. The others are left out,
+// their existence is not disclosed either. Patients are linked when they are
+// written, see Link, reading the links changes nothing.
+func (s *Service) Links(ctx context.Context, id string, hospital string, purpose enum.Purpose) ([]*model.PatientLink, error) {
+	if _, err := s.patient(ctx, id, hospital); err != nil {
+		return nil, err
+	}
+
+	links := []*model.PatientLink{}
+	err := s.db.NewSelect().
+		Model(&links).
+		Join("JOIN patients AS p ON p.id = patient_link.patient_id AND p.deleted_at IS NULL").
+		Where("patient_link.person_id = (?)", s.db.NewSelect().
+			Model((*model.PatientLink)(nil)).
+			Column("person_id").
+			Where("patient_id = ?", id)).
+		Where("patient_link.patient_id <> ?", id).
+		Order("patient_link.hospital ASC", "patient_link.patient_id ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	allowed := make([]*model.PatientLink, 0, len(links))
+	for _, link := range links {
+		ok, err := s.consent.Allowed(ctx, &model.Patient{ID: link.PatientID, Hospital: link.Hospital}, hospital, purpose)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			allowed = append(allowed, link)
+		}
+	}
+	return allowed, nil
+}
+
+// linked reports whether the two patients are records of the same person
+func (s *Service) linked(ctx context.Context, a, b string) (bool, error) {
+	return s.db.NewSelect().
+		TableExpr("patient_links AS a").
+		Join("JOIN patient_links AS b ON b.person_id = a.person_id").
+		Where("a.patient_id = ?", a).
+		Where("b.patient_id = ?", b).
+		Exists(ctx)
+}
+
+// Request asks the hospital of a record linked to the patient of the
+// hospital for it, which needs the consent of the person
+func (s *Service) Request(ctx context.Context, id string, req *mpidto.CreateRecordRequest, hospital, staffID string, purpose enum.Purpose) (*model.RecordRequest, error) {
+	if _, err := s.patient(ctx, id, hospital); err != nil {
+		return nil, err
+	}
+	source := new(model.Patient)
+	err := s.db.NewSelect().
+		Model(source).
+		Column(linkColumns...).
+		Where("id = ?", req.SourcePatientID).
+		Where("hospital <> ?", hospital).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.PatientNotLinked)
+		}
+		return nil, err
+	}
+	ok, err := s.linked(ctx, id, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New(message.PatientNotLinked)
+	}
+	ok, err = s.consent.Allowed(ctx, source, hospital, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New(message.PatientConsentRequired)
+	}
+
+	request := &model.RecordRequest{
+		Hospital:        hospital,
+		PatientID:       id,
+		SourceHospital:  source.Hospital,
+		SourcePatientID: source.ID,
+		Purpose:         purpose,
+		Reason:          req.Reason,
+		Status:          enum.RECORD_REQUEST_PENDING,
+		RequestedBy:     staffID,
+	}
+	_, err = s.db.NewInsert().
+		Model(request).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Requests lists the record requests of the hospital, newest first
+func (s *Service) Requests(ctx context.Context, req *mpidto.ListRecordRequest, hospital string) ([]*model.RecordRequest, int, error) {
+	resp := []*model.RecordRequest{}
+	query := s.db.NewSelect().Model(&resp)
+	if req.Direction == mpidto.DirectionOutgoing {
+		query.Where("hospital = ?", hospital)
+	} else {
+		query.Where("source_hospital = ?", hospital)
+	}
+	if req.Status != "" {
+		query.Where("status = ?", req.Status)
+	}
+	total, err := query.
+		Order("created_at DESC", "id DESC").
+		Offset((req.Page - 1) * req.Size).
+		Limit(req.Size).
+		ScanAndCount(ctx)
+	if err != nil {
+		return resp, 0, err
+	}
+	return resp, total, nil
+}
+
+// Decide approves or denies a pending request made to the hospital
+func (s *Service) Decide(ctx context.Context, id string, req *mpidto.DecideRecordRequest, hospital, staffID string) (*model.RecordRequest, error) {
+	request := new(model.RecordRequest)
+	err := s.db.NewSelect().
+		Model(request).
+		Where("id = ?", id).
+		Where("source_hospital = ?", hospital).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.RecordRequestNotFound)
+		}
+		return nil, err
+	}
+	if request.Status != enum.RECORD_REQUEST_PENDING {
+		return nil, errors.New(message.RecordRequestDecided)
+	}
+
+	request.Status = req.Status
+	request.DecidedBy = staffID
+	request.DecidedAt = time.Now().Unix()
+	request.SetUpdateNow()
+	res, err := s.db.NewUpdate().
+		Model(request).
+		Column("status", "decided_by", "decided_at", "updated_at").
+		WherePK().
+		Where("status = ?", enum.RECORD_REQUEST_PENDING).
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// decided by someone else in the meantime
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return nil, errors.New(message.RecordRequestDecided)
+	}
+	return request, nil
+}
+
+// Record returns the source patient of an approved request the hospital
+// made, as long as the person's consent still holds
+func (s *Service) Record(ctx context.Context, id string, hospital string) (*model.Patient, error) {
+	request := new(model.RecordRequest)
+	err := s.db.NewSelect().
+		Model(request).
+		Where("id = ?", id).
+		Where("hospital = ?", hospital).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.RecordRequestNotFound)
+		}
+		return nil, err
+	}
+	if request.Status != enum.RECORD_REQUEST_APPROVED {
+		return nil, errors.New(message.RecordRequestNotApproved)
+	}
+
+	source := new(model.Patient)
+	err = s.db.NewSelect().
+		Model(source).
+		Where("id = ?", request.SourcePatientID).
+		Where("hospital = ?", request.SourceHospital).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.PatientNotFound)
+		}
+		return nil, err
+	}
+	ok, err := s.consent.Allowed(ctx, source, hospital, request.Purpose)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New(message.PatientConsentRequired)
+	}
+	return source, nil
+}
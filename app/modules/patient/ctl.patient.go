@@ -3,11 +3,20 @@ package patient
 import (
 	"app/app/helper"
 	"app/app/message"
+	"app/app/model"
 	patientdto "app/app/modules/patient/dto"
 	"app/app/response"
+	"app/app/util/export"
+	"app/app/util/fhir"
+	"app/app/util/his"
+	"app/app/util/pii"
 	"app/internal/logger"
-	"encoding/json"
-	"net/http"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,25 +38,42 @@ func (c *Controller) GetPatient(ctx *gin.Context) {
 		response.BadRequest(ctx, message.InvalidRequest, nil)
 		return
 	}
-	resp, err := c.Service.GetPatient(ctx, id.ID)
-	if err != nil {
-		logger.Err(err)
-		response.InternalError(ctx, err.Error(), nil)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
 		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		ctx.DataFromReader(resp.StatusCode, resp.ContentLength, resp.Header.Get("Content-Type"), resp.Body, nil)
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
 		return
 	}
-	//decoding the response body
-	var patientData patientdto.PatientResponse
-	if err := json.NewDecoder(resp.Body).Decode(&patientData); err != nil {
+	patientData, err := c.Service.GetPatient(ctx, id.ID, user.Data.Hospital, viewer.Purpose)
+	if err != nil {
 		logger.Err(err)
-		response.InternalError(ctx, message.InternalServerError, nil)
+		var upstream *his.UpstreamError
+		switch {
+		case err.Error() == message.PatientHospitalMismatch, err.Error() == message.PatientConsentRequired:
+			response.Forbidden(ctx, err.Error(), nil)
+		case errors.Is(err, his.ErrAdapterNotFound):
+			response.BadRequest(ctx, message.HospitalNotIntegrated, nil)
+		case errors.Is(err, his.ErrPatientNotFound):
+			response.NotFound(ctx, message.PatientNotFound, nil)
+		case errors.Is(err, his.ErrCircuitOpen), errors.Is(err, his.ErrUpstreamUnavailable):
+			response.ServiceUnavailable(ctx, message.HospitalAPIUnavailable, nil)
+		case errors.Is(err, his.ErrUpstreamTimeout):
+			response.GatewayTimeout(ctx, message.HospitalAPITimeout, nil)
+		case errors.As(err, &upstream):
+			response.BadGateway(ctx, message.HospitalAPIError, nil)
+		default:
+			response.InternalError(ctx, err.Error(), nil)
+		}
 		return
 	}
-	response.Success(ctx, patientData)
+	// a record that could not be stored has no local patient to name
+	if patientData.ID != "" {
+		helper.AuditPatients(ctx, patientData.ID)
+	}
+	response.Success(ctx, patientData.Shape(viewer))
 }
 
 func (c *Controller) List(ctx *gin.Context) {
@@ -62,12 +88,446 @@ func (c *Controller) List(ctx *gin.Context) {
 		response.BadRequest(ctx, message.InvalidRequest, nil)
 		return
 	}
-	user, _ := helper.GetUserByToken(ctx)
+	if _, err := req.SortFields(); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidSortField, patientdto.InvalidSortResponse{
+			ValidFields: patientdto.PatientSort.Names(),
+		})
+		return
+	}
+	req.Size = response.PageSize(req.Size)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	req.Purpose = viewer.Purpose
+	if req.UsesCursor() {
+		c.listCursor(ctx, &req, user.Data.Hospital, viewer)
+		return
+	}
 	data, total, err := c.Service.List(ctx, &req, user.Data.Hospital)
 	if err != nil {
 		logger.Err(err)
 		response.InternalError(ctx, err.Error(), nil)
 		return
 	}
-	response.SuccessWithPaginate(ctx, data, req.Page, req.Size, total)
+	helper.AuditPatients(ctx, patientIDs(data)...)
+	response.SuccessWithPaginate(ctx, shape(data, viewer), req.Page, req.Size, total)
+}
+
+func (c *Controller) listCursor(ctx *gin.Context, req *patientdto.ListPatientRequest, hospital string, viewer pii.Viewer) {
+	data, page, err := c.Service.ListCursor(ctx, req, hospital)
+	if err != nil {
+		logger.Err(err)
+		switch err.Error() {
+		case message.InvalidCursor, message.CursorWithSearch:
+			response.BadRequest(ctx, err.Error(), nil)
+		default:
+			response.InternalError(ctx, err.Error(), nil)
+		}
+		return
+	}
+	helper.AuditPatients(ctx, patientIDs(data)...)
+	response.SuccessWithCursor(ctx, shape(data, viewer), response.CursorPagination{
+		Size:  req.Size,
+		Next:  page.Next,
+		Prev:  page.Prev,
+		Total: page.Total,
+	})
+}
+
+// exportTypes are the content types of the export formats
+var exportTypes = map[string]string{
+	patientdto.ExportCSV:  "text/csv; charset=utf-8",
+	patientdto.ExportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	patientdto.ExportFHIR: "application/fhir+json",
+}
+
+// Export streams the patients matching the list filters as CSV, XLSX or a FHIR
+// searchset Bundle. The body is held in memory until the first batch is
+// written, so an error before that still gets a JSON error response.
+func (c *Controller) Export(ctx *gin.Context) {
+	req := patientdto.ExportPatientRequest{
+		ListPatientRequest: patientdto.ListPatientRequest{
+			OrderBy: "asc",
+			SortBy:  "created_at",
+		},
+	}
+	if err := ctx.BindQuery(&req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if _, err := req.SortFields(); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidSortField, patientdto.InvalidSortResponse{
+			ValidFields: patientdto.PatientSort.Names(),
+		})
+		return
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	req.Purpose = viewer.Purpose
+
+	body := bufio.NewWriter(ctx.Writer)
+	held := &heldWriter{w: body}
+	writer, err := newExporter(req.Format, held)
+	if err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	ctx.Header("Content-Type", exportTypes[req.Format])
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="patients-%s.%s"`, time.Now().Format("20060102-150405"), exportExtension(req.Format)))
+
+	err = c.Service.Export(ctx, &req.ListPatientRequest, user.Data.Hospital, func(records []patientdto.PatientRecord) error {
+		for _, record := range records {
+			helper.AuditPatients(ctx, record.ID)
+			if err := writer.Write(record.Shape(viewer)); err != nil {
+				return err
+			}
+		}
+		if err := held.Release(); err != nil {
+			return err
+		}
+		if err := body.Flush(); err != nil {
+			return err
+		}
+		ctx.Writer.Flush()
+		helper.FlushAuditPatients(ctx)
+		return nil
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = held.Release()
+	}
+	if err == nil {
+		err = body.Flush()
+	}
+	if err != nil {
+		logger.Err(err)
+		// once the body started the status is sent, the client sees a truncated file
+		if ctx.Writer.Written() {
+			ctx.Abort()
+			return
+		}
+		ctx.Writer.Header().Del("Content-Type")
+		ctx.Writer.Header().Del("Content-Disposition")
+		response.InternalError(ctx, err.Error(), nil)
+	}
+}
+
+// heldWriter keeps what is written in memory until Release, then passes it on
+type heldWriter struct {
+	w        io.Writer
+	buf      bytes.Buffer
+	released bool
+}
+
+func (h *heldWriter) Write(p []byte) (int, error) {
+	if h.released {
+		return h.w.Write(p)
+	}
+	return h.buf.Write(p)
+}
+
+// Release writes what was held and lets the writes after it through
+func (h *heldWriter) Release() error {
+	if h.released {
+		return nil
+	}
+	h.released = true
+	_, err := h.buf.WriteTo(h.w)
+	return err
+}
+
+func exportExtension(format string) string {
+	if format == patientdto.ExportFHIR {
+		return "json"
+	}
+	return format
+}
+
+// exporter writes patient records in one of the export formats
+type exporter interface {
+	Write(record patientdto.PatientRecord) error
+	Close() error
+}
+
+func newExporter(format string, w io.Writer) (exporter, error) {
+	switch format {
+	case patientdto.ExportFHIR:
+		bundle, err := fhir.NewBundleWriter(w, "searchset")
+		if err != nil {
+			return nil, err
+		}
+		return bundleExporter{bundle}, nil
+	case patientdto.ExportXLSX:
+		table, err := export.NewXLSX(w, "Patients")
+		if err != nil {
+			return nil, err
+		}
+		return newTableExporter(table)
+	default:
+		return newTableExporter(export.NewCSV(w))
+	}
+}
+
+type tableExporter struct {
+	export.Table
+}
+
+func newTableExporter(table export.Table) (exporter, error) {
+	if err := table.Row(patientdto.PatientRecordHeader); err != nil {
+		return nil, err
+	}
+	return tableExporter{table}, nil
+}
+
+func (e tableExporter) Write(record patientdto.PatientRecord) error {
+	return e.Row(record.Cells())
+}
+
+type bundleExporter struct {
+	*fhir.BundleWriter
+}
+
+func (e bundleExporter) Write(record patientdto.PatientRecord) error {
+	return e.Add("urn:uuid:"+record.ID, fhir.FromRecord(record))
+}
+
+func (c *Controller) Create(ctx *gin.Context) {
+	req := new(patientdto.CreatePatientRequest)
+	if err := ctx.Bind(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Create(ctx, req, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	helper.AuditPatients(ctx, data.ID)
+	response.Success(ctx, ToPatientDetail(data).Shape(viewer))
+}
+
+func (c *Controller) Get(ctx *gin.Context) {
+	id := new(patientdto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Get(ctx, id.ID, user.Data.Hospital, viewer.Purpose)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, ToPatientDetail(data).Shape(viewer))
+}
+
+// Unmask is break-glass access: it returns the patient with every field shown,
+// whatever the masking policy says, and records the reason in the audit log
+func (c *Controller) Unmask(ctx *gin.Context) {
+	id := new(patientdto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	req := new(patientdto.UnmaskPatientRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.UnmaskReasonRequired, nil)
+		return
+	}
+	helper.AuditReason(ctx, req.Reason)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	purpose, err := helper.GetPurpose(ctx)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Get(ctx, id.ID, user.Data.Hospital, purpose)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, ToPatientDetail(data))
+}
+
+func (c *Controller) Update(ctx *gin.Context) {
+	id := new(patientdto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	req := new(patientdto.UpdatePatientRequest)
+	if err := ctx.Bind(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Update(ctx, id.ID, req, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, ToPatientDetail(data).Shape(viewer))
+}
+
+func (c *Controller) Patch(ctx *gin.Context) {
+	id := new(patientdto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	req := new(patientdto.PatchPatientRequest)
+	if err := ctx.Bind(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	viewer, err := helper.GetViewer(ctx, user)
+	if err != nil {
+		response.BadRequest(ctx, err.Error(), nil)
+		return
+	}
+	data, err := c.Service.Patch(ctx, id.ID, req, user.Data.Hospital)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, ToPatientDetail(data).Shape(viewer))
+}
+
+func (c *Controller) Delete(ctx *gin.Context) {
+	id := new(patientdto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	if err := c.Service.Delete(ctx, id.ID, user.Data.Hospital); err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// patientIDs are the ids of the patients, for the audit log
+func patientIDs(patients []*model.Patient) []string {
+	ids := make([]string, 0, len(patients))
+	for _, patient := range patients {
+		ids = append(ids, patient.ID)
+	}
+	return ids
+}
+
+// shape turns the patients into what the viewer may see of them
+func shape(patients []*model.Patient, viewer pii.Viewer) []patientdto.PatientDetail {
+	details := make([]patientdto.PatientDetail, 0, len(patients))
+	for _, patient := range patients {
+		details = append(details, ToPatientDetail(patient).Shape(viewer))
+	}
+	return details
+}
+
+// respondError maps the message of a service error onto its status code
+func respondError(ctx *gin.Context, err error) {
+	// a concurrent write took the identifier or HN after the service checked it
+	if isUniqueViolation(err) {
+		err = errors.New(message.PatientAlreadyExists)
+	}
+	switch err.Error() {
+	case message.PatientNotFound:
+		response.NotFound(ctx, err.Error(), nil)
+	case message.PatientHospitalMismatch, message.PatientConsentRequired:
+		response.Forbidden(ctx, err.Error(), nil)
+	case message.PatientAlreadyExists,
+		message.PatientNameRequired,
+		message.PatientIdentityRequired,
+		message.InvalidNationalID,
+		message.InvalidPassportID,
+		message.InvalidGender,
+		message.InvalidDateOfBirth,
+		message.MaskedValue:
+		response.BadRequest(ctx, err.Error(), nil)
+	default:
+		response.InternalError(ctx, err.Error(), nil)
+	}
 }
@@ -1,12 +1,133 @@
 package patientdto
 
+import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/util/mask"
+	"app/app/util/pii"
+	"app/app/util/sorting"
+	"app/app/util/validate"
+	"errors"
+	"strings"
+	"time"
+)
+
 type GetPatientByIdRequest struct {
 	ID string `uri:"id" binding:"required"`
 }
 
+type PatientIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type CreatePatientRequest struct {
+	FirstNameTH  string `json:"first_name_th"`
+	MiddleNameTH string `json:"middle_name_th"`
+	LastNameTH   string `json:"last_name_th"`
+	FirstNameEN  string `json:"first_name_en"`
+	MiddleNameEN string `json:"middle_name_en"`
+	LastNameEN   string `json:"last_name_en"`
+	DateOfBirth  string `json:"date_of_birth" binding:"required"`
+	NationalID   string `json:"national_id"`
+	PassportID   string `json:"passport_id"`
+	PhoneNumber  string `json:"phone_number"`
+	Email        string `json:"email" binding:"omitempty,email"`
+	Gender       string `json:"gender" binding:"required"`
+}
+
+// UpdatePatientRequest replaces every field of the patient
+type UpdatePatientRequest struct {
+	CreatePatientRequest
+}
+
+// PatchPatientRequest only changes the fields that are present
+type PatchPatientRequest struct {
+	FirstNameTH  *string `json:"first_name_th"`
+	MiddleNameTH *string `json:"middle_name_th"`
+	LastNameTH   *string `json:"last_name_th"`
+	FirstNameEN  *string `json:"first_name_en"`
+	MiddleNameEN *string `json:"middle_name_en"`
+	LastNameEN   *string `json:"last_name_en"`
+	DateOfBirth  *string `json:"date_of_birth"`
+	NationalID   *string `json:"national_id"`
+	PassportID   *string `json:"passport_id"`
+	PhoneNumber  *string `json:"phone_number"`
+	Email        *string `json:"email" binding:"omitempty,email"`
+	Gender       *string `json:"gender"`
+}
+
+// Validate normalizes the identifiers and checks what binding tags cannot express
+func (r *CreatePatientRequest) Validate() error {
+	r.NationalID = strings.TrimSpace(r.NationalID)
+	r.PassportID = strings.ToUpper(strings.TrimSpace(r.PassportID))
+
+	// a masked value read from the API and sent back would replace the real one
+	for _, value := range []string{r.NationalID, r.PassportID, r.PhoneNumber, r.Email} {
+		if mask.Masked(value) {
+			return errors.New(message.MaskedValue)
+		}
+	}
+	if (r.FirstNameTH == "" || r.LastNameTH == "") && (r.FirstNameEN == "" || r.LastNameEN == "") {
+		return errors.New(message.PatientNameRequired)
+	}
+	if r.NationalID == "" && r.PassportID == "" {
+		return errors.New(message.PatientIdentityRequired)
+	}
+	if r.NationalID != "" && !validate.NationalID(r.NationalID) {
+		return errors.New(message.InvalidNationalID)
+	}
+	if r.PassportID != "" && !validate.Passport(r.PassportID) {
+		return errors.New(message.InvalidPassportID)
+	}
+	if !validate.Gender(r.Gender) {
+		return errors.New(message.InvalidGender)
+	}
+	if _, ok := validate.DateOfBirth(r.DateOfBirth); !ok {
+		return errors.New(message.InvalidDateOfBirth)
+	}
+	return nil
+}
+
+// Apply copies the present fields onto a full request
+func (r *PatchPatientRequest) Apply(req *CreatePatientRequest) {
+	fields := []struct {
+		src *string
+		dst *string
+	}{
+		{r.FirstNameTH, &req.FirstNameTH},
+		{r.MiddleNameTH, &req.MiddleNameTH},
+		{r.LastNameTH, &req.LastNameTH},
+		{r.FirstNameEN, &req.FirstNameEN},
+		{r.MiddleNameEN, &req.MiddleNameEN},
+		{r.LastNameEN, &req.LastNameEN},
+		{r.DateOfBirth, &req.DateOfBirth},
+		{r.NationalID, &req.NationalID},
+		{r.PassportID, &req.PassportID},
+		{r.PhoneNumber, &req.PhoneNumber},
+		{r.Email, &req.Email},
+		{r.Gender, &req.Gender},
+	}
+	for _, f := range fields {
+		if f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+}
+
+const PaginateCursor = "cursor"
+
 type ListPatientRequest struct {
-	Page        int    `form:"page"`
-	Size        int    `form:"size"`
+	Page int `form:"page"`
+	Size int `form:"size"`
+	// Paginate is "offset" (page and size, the default) or "cursor"
+	Paginate string `form:"paginate" binding:"omitempty,oneof=offset cursor"`
+	// Cursor is the next or prev cursor of the previous page, it implies cursor pagination
+	Cursor string `form:"cursor"`
+	// WithTotal counts the matching patients in cursor pagination, offset pagination always does
+	WithTotal bool `form:"with_total"`
+	// Sort is a comma separated list of PatientSort fields, "-" in front sorts
+	// descending, e.g. "last_name_en,-date_of_birth". It replaces sort_by and order_by.
+	Sort        string `form:"sort"`
 	SortBy      string `form:"sort_by"`
 	OrderBy     string `form:"order_by"`
 	NationalID  string `form:"national_id"`
@@ -17,9 +138,122 @@ type ListPatientRequest struct {
 	DateOfBirth string `form:"date_of_birth"`
 	Email       string `form:"email"`
 	PhoneNumber string `form:"phone_number"`
+	HN          string `form:"hn"`
+	// Q searches names, HN, national ID, passport, phone and email at once, best matches first
+	Q string `form:"q" binding:"max=100"`
+	// Purpose is the caller's purpose of use, research only lists the patients
+	// who consented to it
+	Purpose enum.Purpose `form:"-"`
+}
+
+// PatientSort is what the patient list can be sorted by, keyed by the json field
+// names. The encrypted identifiers and contact details cannot be sorted on.
+var PatientSort = sorting.Spec{
+	"first_name_th": "first_name_th",
+	"last_name_th":  "last_name_th",
+	"first_name_en": "first_name_en",
+	"last_name_en":  "last_name_en",
+	"date_of_birth": "date_of_birth",
+	"patient_hn":    "patient_hn",
+	"hn":            "patient_hn",
+	"gender":        "gender",
+	"created_at":    "created_at",
+	"updated_at":    "updated_at",
+}
+
+// SortFields parses Sort, or sort_by and order_by when Sort is empty
+func (r *ListPatientRequest) SortFields() ([]sorting.Field, error) {
+	if r.Sort != "" {
+		return PatientSort.Parse(r.Sort)
+	}
+	sort, err := sorting.Legacy(r.SortBy, r.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	return PatientSort.Parse(sort)
+}
+
+// UsesCursor tells whether the list is cursor paginated
+func (r *ListPatientRequest) UsesCursor() bool {
+	return r.Paginate == PaginateCursor || r.Cursor != ""
+}
+
+// The formats patients are exported in
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+	ExportFHIR = "fhir"
+)
+
+// ExportPatientRequest exports every patient matching the list filters, Page,
+// Size and the pagination fields are ignored
+type ExportPatientRequest struct {
+	ListPatientRequest
+	Format string `form:"format" binding:"required,oneof=csv xlsx fhir"`
+}
+
+// PageCursors are where the pages around a cursor paginated page start, Total is
+// only counted when WithTotal is set
+type PageCursors struct {
+	Next  string
+	Prev  string
+	Total *int
+}
+
+// ImportResult is what an import did with one request, Err is set when the
+// request was not saved
+type ImportResult struct {
+	Created bool
+	Err     error
+}
+
+// InvalidSortResponse tells the client which fields it can sort by
+type InvalidSortResponse struct {
+	ValidFields []string `json:"valid_fields"`
+}
+
+// UnmaskPatientRequest is break-glass access to a patient, the reason goes into the audit log
+type UnmaskPatientRequest struct {
+	Reason string `json:"reason" binding:"required,min=10,max=500"`
+}
+
+// PatientDetail is a patient as the API returns it, its sensitive fields are
+// shaped for the caller by Shape. Omitted fields are left out.
+type PatientDetail struct {
+	ID           string     `json:"id"`
+	FirstNameTH  string     `json:"first_name_th"`
+	MiddleNameTH string     `json:"middle_name_th"`
+	LastNameTH   string     `json:"last_name_th"`
+	FirstNameEN  string     `json:"first_name_en"`
+	MiddleNameEN string     `json:"middle_name_en"`
+	LastNameEN   string     `json:"last_name_en"`
+	DateOfBirth  string     `json:"date_of_birth,omitempty"`
+	PatientHN    string     `json:"patient_hn"`
+	NationalID   string     `json:"national_id,omitempty"`
+	PassportID   string     `json:"passport_id,omitempty"`
+	PhoneNumber  string     `json:"phone_number,omitempty"`
+	Email        string     `json:"email,omitempty"`
+	Gender       string     `json:"gender"`
+	Hospital     string     `json:"hospital"`
+	SyncedAt     int64      `json:"synced_at"`
+	CreatedAt    int64      `json:"created_at"`
+	UpdatedAt    int64      `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at"`
+}
+
+// Shape masks or omits the sensitive fields the viewer may not see
+func (d PatientDetail) Shape(v pii.Viewer) PatientDetail {
+	d.NationalID = v.Apply("national_id", d.NationalID)
+	d.PassportID = v.Apply("passport_id", d.PassportID)
+	d.PhoneNumber = v.Apply("phone_number", d.PhoneNumber)
+	d.Email = v.Apply("email", d.Email)
+	d.DateOfBirth = v.Apply("date_of_birth", d.DateOfBirth)
+	return d
 }
 
 type PatientResponse struct {
+	// ID is the local patient of the record, empty while it is not stored
+	ID           string `json:"-"`
 	FirstNameTH  string `json:"first_name_th"`
 	MiddleNameTH string `json:"middle_name_th"`
 	LastNameTH   string `json:"last_name_th"`
@@ -33,4 +267,65 @@ type PatientResponse struct {
 	PhoneNumber  string `json:"phone_number"`
 	Email        string `json:"email"`
 	Gender       string `json:"gender"`
+	Hospital     string `json:"hospital"`
+}
+
+// Shape masks or empties the sensitive fields the viewer may not see
+func (r PatientResponse) Shape(v pii.Viewer) PatientResponse {
+	r.NationalID = v.Apply("national_id", r.NationalID)
+	r.PassportID = v.Apply("passport_id", r.PassportID)
+	r.PhoneNumber = v.Apply("phone_number", r.PhoneNumber)
+	r.Email = v.Apply("email", r.Email)
+	r.DateOfBirth = v.Apply("date_of_birth", r.DateOfBirth)
+	return r
+}
+
+// PatientRecord is an exported patient, every value is text the way it is written out
+type PatientRecord struct {
+	ID           string
+	PatientHN    string
+	NationalID   string
+	PassportID   string
+	FirstNameTH  string
+	MiddleNameTH string
+	LastNameTH   string
+	FirstNameEN  string
+	MiddleNameEN string
+	LastNameEN   string
+	DateOfBirth  string
+	Gender       string
+	PhoneNumber  string
+	Email        string
+	CreatedAt    string
+	UpdatedAt    string
+}
+
+// PatientRecordHeader names the columns of PatientRecord.Cells
+var PatientRecordHeader = []string{
+	"id", "patient_hn", "national_id", "passport_id",
+	"first_name_th", "middle_name_th", "last_name_th",
+	"first_name_en", "middle_name_en", "last_name_en",
+	"date_of_birth", "gender", "phone_number", "email",
+	"created_at", "updated_at",
+}
+
+// Cells returns the values in the order of PatientRecordHeader
+func (r PatientRecord) Cells() []string {
+	return []string{
+		r.ID, r.PatientHN, r.NationalID, r.PassportID,
+		r.FirstNameTH, r.MiddleNameTH, r.LastNameTH,
+		r.FirstNameEN, r.MiddleNameEN, r.LastNameEN,
+		r.DateOfBirth, r.Gender, r.PhoneNumber, r.Email,
+		r.CreatedAt, r.UpdatedAt,
+	}
+}
+
+// Shape masks or empties the sensitive fields the viewer may not see
+func (r PatientRecord) Shape(v pii.Viewer) PatientRecord {
+	r.NationalID = v.Apply("national_id", r.NationalID)
+	r.PassportID = v.Apply("passport_id", r.PassportID)
+	r.PhoneNumber = v.Apply("phone_number", r.PhoneNumber)
+	r.Email = v.Apply("email", r.Email)
+	r.DateOfBirth = v.Apply("date_of_birth", r.DateOfBirth)
+	return r
 }
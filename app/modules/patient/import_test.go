@@ -0,0 +1,97 @@
+package patient
+
+import (
+	"app/app/message"
+	patientdto "app/app/modules/patient/dto"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+// recordingLinker records the patients it is asked to link
+type recordingLinker struct {
+	ids []string
+}
+
+func (l *recordingLinker) Link(ctx context.Context, db bun.IDB, ids ...string) error {
+	l.ids = append(l.ids, ids...)
+	return nil
+}
+
+func importRequest(nationalID, passportID string) *patientdto.CreatePatientRequest {
+	return &patientdto.CreatePatientRequest{
+		FirstNameEN: "Somchai",
+		LastNameEN:  "Jaidee",
+		DateOfBirth: "1990-01-01",
+		NationalID:  nationalID,
+		PassportID:  passportID,
+		Gender:      "1",
+	}
+}
+
+func TestService_Import(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Creates new patients and updates existing ones", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		require.NoError(t, svc.Sync(ctx, upstreamPatient(upstream.Hospital), upstream.Hospital))
+
+		links := &recordingLinker{}
+		svc.Links = links
+
+		existing := importRequest("1103702071811", "")
+		existing.PhoneNumber = "0899999999"
+		results, err := svc.Import(ctx, svc.db, upstream.Hospital, []*patientdto.CreatePatientRequest{
+			existing,
+			importRequest("", "AA1234567"),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []patientdto.ImportResult{{}, {Created: true}}, results)
+		patients := syncedPatients(t, svc.db, upstream.Hospital)
+		require.Len(t, patients, 2)
+		assert.Equal(t, "HIS-0001", patients[0].PatientHN, "the HN is kept")
+		assert.Equal(t, "0899999999", string(patients[0].PhoneNumber))
+		assert.Equal(t, "HN000001", patients[1].PatientHN)
+		assert.ElementsMatch(t, []string{patients[0].ID, patients[1].ID}, links.ids, "both are relinked")
+	})
+
+	t.Run("Fail - A deleted patient is reported and stays deleted", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		require.NoError(t, svc.Sync(ctx, upstreamPatient(upstream.Hospital), upstream.Hospital))
+		patient := syncedPatients(t, svc.db, upstream.Hospital)[0]
+		require.NoError(t, svc.Delete(ctx, patient.ID, upstream.Hospital))
+
+		results, err := svc.Import(ctx, svc.db, upstream.Hospital, []*patientdto.CreatePatientRequest{
+			importRequest("1103702071811", ""),
+		})
+
+		require.NoError(t, err)
+		require.Error(t, results[0].Err)
+		assert.Equal(t, message.PatientDeleted, results[0].Err.Error())
+		patients := syncedPatients(t, svc.db, upstream.Hospital)
+		require.Len(t, patients, 1)
+		assert.NotNil(t, patients[0].DeletedAt)
+		assert.Equal(t, "ใจดี", patients[0].LastNameTH)
+	})
+
+	t.Run("Fail - Two requests for the same patient", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		data := upstreamPatient(upstream.Hospital)
+		data.PassportID = "AA1234567"
+		require.NoError(t, svc.Sync(ctx, data, upstream.Hospital))
+
+		results, err := svc.Import(ctx, svc.db, upstream.Hospital, []*patientdto.CreatePatientRequest{
+			importRequest("1103702071811", ""),
+			importRequest("", "AA1234567"),
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, results[0].Err)
+		require.Error(t, results[1].Err)
+		assert.Equal(t, message.PatientIdentityConflict, results[1].Err.Error())
+	})
+}
@@ -1,15 +1,30 @@
 package patient
 
 import (
+	"app/app/enum"
 	"app/app/model"
 	patientdto "app/app/modules/patient/dto"
 	"context"
-	"net/http"
+
+	"github.com/uptrace/bun"
 )
 
 type ServiceInterface interface {
-	GetPatient(ctx context.Context, id string) (*http.Response, error)
+	GetPatient(ctx context.Context, id string, hospital string, purpose enum.Purpose) (*patientdto.PatientResponse, error)
 	List(ctx context.Context, req *patientdto.ListPatientRequest, hospital string) ([]*model.Patient, int, error)
+	ListCursor(ctx context.Context, req *patientdto.ListPatientRequest, hospital string) ([]*model.Patient, *patientdto.PageCursors, error)
+	Export(ctx context.Context, req *patientdto.ListPatientRequest, hospital string, write func([]patientdto.PatientRecord) error) error
+	Create(ctx context.Context, req *patientdto.CreatePatientRequest, hospital string) (*model.Patient, error)
+	Get(ctx context.Context, id string, hospital string, purpose enum.Purpose) (*model.Patient, error)
+	Update(ctx context.Context, id string, req *patientdto.UpdatePatientRequest, hospital string) (*model.Patient, error)
+	Patch(ctx context.Context, id string, req *patientdto.PatchPatientRequest, hospital string) (*model.Patient, error)
+	Delete(ctx context.Context, id string, hospital string) error
 }
 
 var _ ServiceInterface = (*Service)(nil)
+
+// Linker relinks the patients in the master patient index after they were
+// written in db, see mpi.Service
+type Linker interface {
+	Link(ctx context.Context, db bun.IDB, ids ...string) error
+}
@@ -1,14 +1,20 @@
 package patient
 
-import "github.com/uptrace/bun"
+import (
+	"app/app/util/his"
+	"app/app/util/hn"
+
+	"github.com/uptrace/bun"
+)
 
 type Module struct {
 	Ctl *Controller
 	Svc *Service
 }
 
-func NewModule(db *bun.DB) *Module {
-	svc := NewService(db)
+func NewModule(db *bun.DB, registry *his.Registry, hnFormat *hn.Format, links Linker) *Module {
+	svc := NewService(db, registry, hnFormat)
+	svc.Links = links
 	return &Module{
 		Ctl: NewController(svc),
 		Svc: svc,
@@ -0,0 +1,164 @@
+package patient
+
+import (
+	"app/app/model"
+	patientdto "app/app/modules/patient/dto"
+	"app/app/util/cursor"
+	"app/app/util/fieldcrypt"
+	"app/app/util/sorting"
+	"bytes"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// useKeyring loads a keyring for the blind indexes of the identifiers
+func useKeyring(t *testing.T) {
+	keyring, err := fieldcrypt.New(map[int][]byte{1: bytes.Repeat([]byte{1}, 32)}, 0, bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	fieldcrypt.Use(keyring)
+	t.Cleanup(func() { fieldcrypt.Use(nil) })
+}
+
+func searchQuery(q string) string {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	query := db.NewSelect().Model((*model.Patient)(nil))
+	if rank := search(query, q); rank != nil {
+		order(query, []cursor.Key{*rank})
+	}
+	return query.String()
+}
+
+func TestFilter_Research(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	query := db.NewSelect().Model((*model.Patient)(nil))
+	filter(query, &patientdto.ListPatientRequest{Purpose: "research"})
+	sql := query.String()
+	assert.Contains(t, sql, `EXISTS (SELECT 1 FROM "patient_consents" AS "patient_consent"`)
+	assert.Contains(t, sql, `patient_consent.patient_id = "patient"."id"`)
+	assert.Contains(t, sql, `patient_consent.scope IN ('*', "patient"."hospital")`)
+
+	query = db.NewSelect().Model((*model.Patient)(nil))
+	filter(query, &patientdto.ListPatientRequest{Purpose: "treatment"})
+	assert.NotContains(t, query.String(), "patient_consents")
+}
+
+func TestSearch(t *testing.T) {
+	useKeyring(t)
+
+	t.Run("Full Thai name matches every word", func(t *testing.T) {
+		query := searchQuery("  สมชาย   ใจดี ")
+		assert.Contains(t, query, searchName+" LIKE '%สมชาย%' AND "+searchName+" LIKE '%ใจดี%'")
+		assert.Contains(t, query, "'สมชาย ใจดี' <% "+searchName)
+		assert.NotContains(t, query, "national_id_bidx =")
+	})
+
+	t.Run("Identifiers drop dashes", func(t *testing.T) {
+		query := searchQuery("1-1037-02071-81-1")
+		assert.Contains(t, query, "national_id_bidx = '"+model.PatientBlindIndex("national_id", "1103702071811")+"'")
+		assert.Contains(t, query, "phone_number_bidx = '"+model.PatientBlindIndex("phone_number", "1103702071811")+"'")
+		assert.NotContains(t, query, "1103702071811")
+	})
+
+	t.Run("Encrypted columns are not matched by prefix", func(t *testing.T) {
+		query := searchQuery("AA12")
+		assert.Contains(t, query, "passport_id_bidx = '"+model.PatientBlindIndex("passport_id", "AA12")+"'")
+		assert.Contains(t, query, "patient_hn LIKE 'AA12%'")
+		assert.NotContains(t, query, "passport_id LIKE")
+	})
+
+	t.Run("Short numbers are not matched as identifiers", func(t *testing.T) {
+		assert.NotContains(t, searchQuery("12"), "national_id_bidx =")
+	})
+
+	t.Run("Like wildcards are escaped", func(t *testing.T) {
+		assert.Contains(t, searchQuery("50%_x"), `LIKE '%50\%\_x%'`)
+	})
+
+	t.Run("Blank query adds nothing", func(t *testing.T) {
+		assert.NotContains(t, searchQuery("   "), "LIKE")
+	})
+}
+
+func TestSearchNameMatchesIndex(t *testing.T) {
+	migration, err := os.ReadFile("../../../database/migrations/sql/20261018000002_patient_search.tx.up.sql")
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(migration), "(("+searchName+") gin_trgm_ops)"),
+		"patients_search_name_trgm_idx must use searchName verbatim")
+}
+
+func TestOrder(t *testing.T) {
+	req := &patientdto.ListPatientRequest{Sort: "last_name_en,-date_of_birth"}
+	fields, err := req.SortFields()
+	require.NoError(t, err)
+
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	query := db.NewSelect().Model((*model.Patient)(nil))
+	order(query, sortKeys(fields))
+	assert.Contains(t, query.String(), `ORDER BY coalesce("last_name_en", '') ASC, "date_of_birth" DESC, "id" DESC`)
+}
+
+func TestKeyset(t *testing.T) {
+	fields, err := patientdto.PatientSort.Parse("-created_at")
+	require.NoError(t, err)
+	patient := &model.Patient{ID: "65e08e33-9f57-45fe-b725-82242e3581ad"}
+	patient.CreatedAt = 1700000000
+	values := sortValues(patient, fields)
+	assert.Equal(t, []string{"1700000000", patient.ID}, values)
+
+	where, args, err := cursor.After(sortKeys(fields), values)
+	require.NoError(t, err)
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	query := db.NewSelect().Model((*model.Patient)(nil)).Where(where, args...)
+	assert.Contains(t, query.String(),
+		`(("created_at" < '1700000000') OR ("created_at" = '1700000000' AND "id" < '65e08e33-9f57-45fe-b725-82242e3581ad'))`)
+}
+
+func TestValidSortValues(t *testing.T) {
+	fields, err := patientdto.PatientSort.Parse("last_name_en,-date_of_birth,created_at")
+	require.NoError(t, err)
+	id := "65e08e33-9f57-45fe-b725-82242e3581ad"
+
+	assert.True(t, validSortValues([]string{"Doe", "1990-05-17", "1700000000", id}, fields))
+	assert.False(t, validSortValues([]string{"Doe", "1990-13-45", "1700000000", id}, fields), "invalid date")
+	assert.False(t, validSortValues([]string{"Doe", "1990-05-17", "yesterday", id}, fields), "invalid timestamp")
+	assert.False(t, validSortValues([]string{"Doe", "1990-05-17", "1700000000", "1"}, fields), "invalid id")
+	assert.False(t, validSortValues([]string{"Doe", "1990-05-17", id}, fields), "missing value")
+}
+
+func TestSortColumns(t *testing.T) {
+	for name, column := range patientdto.PatientSort {
+		assert.Contains(t, sortColumns, column, "sort field %s has no cursor value", name)
+	}
+}
+
+func TestSortFields(t *testing.T) {
+	t.Run("Legacy sort_by and order_by", func(t *testing.T) {
+		req := &patientdto.ListPatientRequest{SortBy: "hn", OrderBy: "desc"}
+		fields, err := req.SortFields()
+		require.NoError(t, err)
+		require.Len(t, fields, 1)
+		assert.Equal(t, "patient_hn", fields[0].Column)
+		assert.True(t, fields[0].Desc)
+	})
+
+	t.Run("Sort wins over sort_by", func(t *testing.T) {
+		req := &patientdto.ListPatientRequest{Sort: "-created_at", SortBy: "created_at; DROP TABLE patients"}
+		fields, err := req.SortFields()
+		require.NoError(t, err)
+		assert.Equal(t, "created_at", fields[0].Column)
+	})
+
+	t.Run("Injected sort_by is rejected", func(t *testing.T) {
+		req := &patientdto.ListPatientRequest{SortBy: "created_at; DROP TABLE patients"}
+		_, err := req.SortFields()
+		assert.ErrorIs(t, err, sorting.ErrInvalidSort)
+	})
+}
@@ -1,36 +1,351 @@
 package patient
 
 import (
+	"app/app/enum"
+	"app/app/message"
 	"app/app/model"
+	"app/app/modules/consent"
 	patientdto "app/app/modules/patient/dto"
+	"app/app/util/cursor"
+	"app/app/util/fieldcrypt"
+	"app/app/util/his"
+	"app/app/util/hn"
+	"app/app/util/sorting"
+	"app/app/util/validate"
+	"app/internal/logger"
+	"database/sql"
+	"errors"
 	"fmt"
-	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/spf13/viper"
 	"github.com/uptrace/bun"
 	"golang.org/x/net/context"
 )
 
 type Service struct {
-	db *bun.DB
+	db  *bun.DB
+	his *his.Registry
+	hn  *hn.Format
+	// Links relinks written patients, they are not linked when it is nil
+	Links Linker
 }
 
-func NewService(db *bun.DB) *Service {
+func NewService(db *bun.DB, registry *his.Registry, hnFormat *hn.Format) *Service {
 	return &Service{
-		db: db,
+		db:  db,
+		his: registry,
+		hn:  hnFormat,
 	}
 }
 
-func (s *Service) GetPatient(ctx context.Context, id string) (*http.Response, error) {
-	url := fmt.Sprintf("https://hospital-a.api.co.th/patient/search/%s", id)
+// link relinks the patients in the master patient index
+func (s *Service) link(ctx context.Context, db bun.IDB, ids ...string) error {
+	if s.Links == nil || len(ids) == 0 {
+		return nil
+	}
+	return s.Links.Link(ctx, db, ids...)
+}
+
+// Consented keeps to the patients of the query that may be read for the
+// purpose: research needs the patient's research consent to its hospital. Every
+// read of patients goes through it or consented.
+func Consented(query *bun.SelectQuery, purpose enum.Purpose) *bun.SelectQuery {
+	if purpose != enum.PURPOSE_RESEARCH {
+		return query
+	}
+	return query.Where("EXISTS (?)", consent.Granted(query.DB(), bun.Ident("patient.id"), enum.CONSENT_RESEARCH, bun.Ident("patient.hospital"), purpose))
+}
+
+// consented fails with PatientConsentRequired when the patient may not be read
+// for the purpose, see Consented
+func (s *Service) consented(ctx context.Context, id string, hospital string, purpose enum.Purpose) error {
+	if purpose != enum.PURPOSE_RESEARCH {
+		return nil
+	}
+	ok, err := Consented(s.db.NewSelect().Model((*model.Patient)(nil)).WhereAllWithDeleted().Where("patient.id = ?", id).Where("patient.hospital = ?", hospital), purpose).Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New(message.PatientConsentRequired)
+	}
+	return nil
+}
+
+// GetPatient serves the patient from the local table while it is fresh,
+// otherwise it fetches the hospital HIS and stores the result. A deleted patient
+// is still served and refreshed: deleting it hides it from the registry, not
+// from the HIS lookup. A record read for research needs the patient's consent,
+// one that could not be stored has no patient to consent.
+func (s *Service) GetPatient(ctx context.Context, id string, hospital string, purpose enum.Purpose) (*patientdto.PatientResponse, error) {
+	data, err := s.lookup(ctx, id, hospital)
+	if err != nil {
+		return nil, err
+	}
+	if data.ID == "" && purpose == enum.PURPOSE_RESEARCH {
+		return nil, errors.New(message.PatientConsentRequired)
+	}
+	if err := s.consented(ctx, data.ID, data.Hospital, purpose); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// lookup is GetPatient without the consent check
+func (s *Service) lookup(ctx context.Context, id string, hospital string) (*patientdto.PatientResponse, error) {
+	adapter, err := s.his.Get(hospital)
+	if err != nil {
+		return nil, err
+	}
+	hospital = adapter.Hospital()
+
+	cached, err := s.cached(ctx, id, hospital)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && isFresh(cached) {
+		return toPatientResponse(cached), nil
+	}
+
+	record, err := adapter.FetchPatient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data := fromHISPatient(record)
+	// a record that does not name its hospital is not trusted to be the caller's
+	if data.Hospital != hospital {
+		return nil, errors.New(message.PatientHospitalMismatch)
+	}
+	if err := s.Sync(ctx, data, hospital); err != nil {
+		if err.Error() != message.PatientIdentityConflict {
+			return nil, err
+		}
+		// the record is still the hospital's answer, it is only not cached
+		logger.Errf("patient %s of %s not cached: %s", data.PatientHN, hospital, err)
+	}
+	return data, nil
+}
+
+// GetByIdentifier finds a patient of the hospital by national ID or passport ID
+func (s *Service) GetByIdentifier(ctx context.Context, id string, hospital string) (*model.Patient, error) {
+	return s.byIdentifier(ctx, s.db.NewSelect(), id, hospital)
+}
 
-	resp, err := http.Get(url)
+// cached is GetByIdentifier including the deleted patients
+func (s *Service) cached(ctx context.Context, id string, hospital string) (*model.Patient, error) {
+	return s.byIdentifier(ctx, s.db.NewSelect().WhereAllWithDeleted(), id, hospital)
+}
+
+func (s *Service) byIdentifier(ctx context.Context, query *bun.SelectQuery, id string, hospital string) (*model.Patient, error) {
+	patient := new(model.Patient)
+	err := query.
+		Model(patient).
+		Where("hospital = ?", hospital).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("national_id_bidx = ?", model.PatientBlindIndex("national_id", id)).
+				WhereOr("passport_id_bidx = ?", model.PatientBlindIndex("passport_id", id))
+		}).
+		Limit(1).
+		Scan(ctx)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
 		return nil, err
 	}
-	return resp, nil
+	return patient, nil
+}
+
+// Sync stores an upstream record on the patient of the hospital with its
+// national ID or passport ID, or adds it when there is none. It fails with
+// PatientIdentityConflict when the record would take the identifier or HN of
+// another patient, those are left for staff to merge or correct. data.ID is set
+// to the stored patient.
+func (s *Service) Sync(ctx context.Context, data *patientdto.PatientResponse, hospital string) error {
+	patient := fromPatientResponse(data, hospital)
+	if patient.NationalID == "" && patient.PassportID == "" {
+		// nothing to key the record on, serve it without caching
+		return nil
+	}
+	now := time.Now().Unix()
+	patient.SyncedAt = now
+	patient.UpdatedAt = now
+
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		matches, err := syncMatches(ctx, tx, patient)
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(matches) == 0:
+			_, err = tx.NewInsert().Model(patient).Returning("id").Exec(ctx)
+			if isUniqueViolation(err) {
+				// added by a concurrent lookup or write
+				return errors.New(message.PatientIdentityConflict)
+			}
+			if err != nil {
+				return err
+			}
+			return s.link(ctx, tx, patient.ID)
+		case len(matches) > 1, !matches[0].sameIdentity(patient):
+			return errors.New(message.PatientIdentityConflict)
+		}
+		patient.ID = matches[0].ID
+		_, err = tx.NewUpdate().
+			Model(patient).
+			Column(syncColumns...).
+			WherePK().
+			WhereAllWithDeleted().
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		return s.link(ctx, tx, patient.ID)
+	})
+	if err != nil {
+		return err
+	}
+	data.ID = patient.ID
+	return nil
+}
+
+var syncColumns = []string{
+	"first_name_th", "middle_name_th", "last_name_th",
+	"first_name_en", "middle_name_en", "last_name_en",
+	"date_of_birth", "patient_hn", "national_id", "passport_id",
+	"phone_number", "email", "gender", "synced_at", "updated_at",
+	"national_id_bidx", "passport_id_bidx", "phone_number_bidx", "email_bidx",
+}
+
+// syncMatch is a patient holding one of the identifiers or the HN of a record
+type syncMatch struct {
+	ID              string `bun:"id"`
+	PatientHN       string `bun:"patient_hn"`
+	NationalIDIndex string `bun:"national_id_bidx"`
+	PassportIDIndex string `bun:"passport_id_bidx"`
+}
+
+// sameIdentity tells whether the match is the patient of the record, it shares
+// an identifier with it rather than only the HN
+func (m syncMatch) sameIdentity(patient *model.Patient) bool {
+	return (patient.NationalIDIndex != "" && m.NationalIDIndex == patient.NationalIDIndex) ||
+		(patient.PassportIDIndex != "" && m.PassportIDIndex == patient.PassportIDIndex)
+}
+
+// syncMatches locks the patients of the hospital, deleted ones included, that
+// hold the national ID, passport ID or HN of the record
+func syncMatches(ctx context.Context, tx bun.Tx, patient *model.Patient) ([]syncMatch, error) {
+	patient.NationalIDIndex = model.PatientBlindIndex("national_id", string(patient.NationalID))
+	patient.PassportIDIndex = model.PatientBlindIndex("passport_id", string(patient.PassportID))
+
+	var matches []syncMatch
+	err := tx.NewSelect().
+		Model((*model.Patient)(nil)).
+		Column("id", "patient_hn", "national_id_bidx", "passport_id_bidx").
+		WhereAllWithDeleted().
+		Where("hospital = ?", patient.Hospital).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			if patient.NationalID != "" {
+				q = q.WhereOr("national_id_bidx = ?", patient.NationalIDIndex)
+			}
+			if patient.PassportID != "" {
+				q = q.WhereOr("passport_id_bidx = ?", patient.PassportIDIndex)
+			}
+			if patient.PatientHN != "" {
+				q = q.WhereOr("patient_hn = ?", patient.PatientHN)
+			}
+			return q
+		}).
+		For("UPDATE").
+		Scan(ctx, &matches)
+	return matches, err
+}
+
+// isUniqueViolation tells whether the statement broke a unique constraint
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isFresh(patient *model.Patient) bool {
+	ttl := viper.GetDuration("HIS_CACHE_TTL")
+	if ttl <= 0 || patient.SyncedAt == 0 {
+		return false
+	}
+	return time.Since(time.Unix(patient.SyncedAt, 0)) < ttl
+}
 
+func toPatientResponse(patient *model.Patient) *patientdto.PatientResponse {
+	return &patientdto.PatientResponse{
+		ID:           patient.ID,
+		FirstNameTH:  patient.FirstNameTH,
+		MiddleNameTH: patient.MiddleNameTH,
+		LastNameTH:   patient.LastNameTH,
+		FirstNameEN:  patient.FirstNameEN,
+		MiddleNameEN: patient.MiddleNameEN,
+		LastNameEN:   patient.LastNameEN,
+		DateOfBirth:  patient.DateOfBirth.Format(validate.DateLayout),
+		PatientHN:    patient.PatientHN,
+		NationalID:   string(patient.NationalID),
+		PassportID:   string(patient.PassportID),
+		PhoneNumber:  string(patient.PhoneNumber),
+		Email:        string(patient.Email),
+		Gender:       patient.Gender,
+		Hospital:     patient.Hospital,
+	}
+}
+
+func fromHISPatient(record *his.Patient) *patientdto.PatientResponse {
+	return &patientdto.PatientResponse{
+		FirstNameTH:  record.FirstNameTH,
+		MiddleNameTH: record.MiddleNameTH,
+		LastNameTH:   record.LastNameTH,
+		FirstNameEN:  record.FirstNameEN,
+		MiddleNameEN: record.MiddleNameEN,
+		LastNameEN:   record.LastNameEN,
+		DateOfBirth:  record.DateOfBirth,
+		PatientHN:    record.PatientHN,
+		NationalID:   record.NationalID,
+		PassportID:   record.PassportID,
+		PhoneNumber:  record.PhoneNumber,
+		Email:        record.Email,
+		Gender:       record.Gender,
+		Hospital:     record.Hospital,
+	}
+}
+
+func fromPatientResponse(data *patientdto.PatientResponse, hospital string) *model.Patient {
+	return &model.Patient{
+		FirstNameTH:  data.FirstNameTH,
+		MiddleNameTH: data.MiddleNameTH,
+		LastNameTH:   data.LastNameTH,
+		FirstNameEN:  data.FirstNameEN,
+		MiddleNameEN: data.MiddleNameEN,
+		LastNameEN:   data.LastNameEN,
+		DateOfBirth:  parseDate(data.DateOfBirth),
+		PatientHN:    data.PatientHN,
+		NationalID:   fieldcrypt.EncryptedString(data.NationalID),
+		PassportID:   fieldcrypt.EncryptedString(data.PassportID),
+		PhoneNumber:  fieldcrypt.EncryptedString(data.PhoneNumber),
+		Email:        fieldcrypt.EncryptedString(data.Email),
+		Gender:       data.Gender,
+		Hospital:     hospital,
+	}
+}
+
+func parseDate(value string) time.Time {
+	for _, layout := range []string{validate.DateLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
 
 func (s *Service) List(ctx context.Context, req *patientdto.ListPatientRequest, hospital string) ([]*model.Patient, int, error) {
@@ -43,14 +358,231 @@ func (s *Service) List(ctx context.Context, req *patientdto.ListPatientRequest,
 	query := s.db.NewSelect().
 		Model(&resp).
 		Where("hospital = ?", hospital)
+	filter(query, req)
+
+	// the search rank comes first, the requested sort orders equally ranked patients
+	fields, err := req.SortFields()
+	if err != nil {
+		return resp, 0, err
+	}
+	keys := sortKeys(fields)
+	if rank := search(query, req.Q); rank != nil {
+		keys = append([]cursor.Key{*rank}, keys...)
+	}
+	order(query, keys)
+
+	total, err := query.Count(ctx)
+	if err != nil {
+		return resp, 0, err
+	}
+	if total == 0 {
+		return resp, 0, nil
+	}
+
+	err = query.
+		Offset(offset).
+		Limit(limit).
+		Scan(ctx, &resp)
+	if err != nil {
+		return resp, 0, err
+	}
+
+	return resp, total, nil
+}
+
+// ListCursor pages with keyset conditions on the sort keys instead of OFFSET, so
+// deep pages stay fast and rows are neither skipped nor repeated when patients
+// are added or removed between pages
+func (s *Service) ListCursor(ctx context.Context, req *patientdto.ListPatientRequest, hospital string) ([]*model.Patient, *patientdto.PageCursors, error) {
+	resp := []*model.Patient{}
+	page := &patientdto.PageCursors{}
+	// the search rank is computed per query and cannot be part of a cursor
+	if req.Q != "" {
+		return resp, page, errors.New(message.CursorWithSearch)
+	}
+	fields, err := req.SortFields()
+	if err != nil {
+		return resp, page, err
+	}
+	sort := sorting.Format(fields)
+	keys := sortKeys(fields)
+
+	var at *cursor.Cursor
+	if req.Cursor != "" {
+		at, err = cursor.Decode(req.Cursor, sort)
+		if err != nil || !validSortValues(at.Values, fields) {
+			return resp, page, errors.New(message.InvalidCursor)
+		}
+	}
+	backward := at != nil && at.Before
+
+	query := s.db.NewSelect().
+		Model(&resp).
+		Where("hospital = ?", hospital)
+	filter(query, req)
+
+	if req.WithTotal {
+		total, err := query.Count(ctx)
+		if err != nil {
+			return resp, page, err
+		}
+		page.Total = &total
+	}
+
+	// a prev cursor walks the list in reverse and flips the rows afterwards
+	walk := keys
+	if backward {
+		walk = cursor.Reverse(keys)
+	}
+	if at != nil {
+		where, args, err := cursor.After(walk, at.Values)
+		if err != nil {
+			return resp, page, errors.New(message.InvalidCursor)
+		}
+		query.Where(where, args...)
+	}
+	order(query, walk)
+
+	// one extra row tells whether there is another page
+	err = query.Limit(req.Size+1).Scan(ctx, &resp)
+	if err != nil {
+		return resp, page, err
+	}
+	more := len(resp) > req.Size
+	if more {
+		resp = resp[:req.Size]
+	}
+	if backward {
+		slices.Reverse(resp)
+	}
+	if len(resp) == 0 {
+		return resp, page, nil
+	}
+
+	// going forward there is a previous page once a cursor was followed, going
+	// backward there is always a next one, the page the prev cursor came from
+	if more && !backward || backward {
+		page.Next = cursor.Cursor{Sort: sort, Values: sortValues(resp[len(resp)-1], fields)}.Encode()
+	}
+	if more && backward || at != nil && !backward {
+		page.Prev = cursor.Cursor{Sort: sort, Values: sortValues(resp[0], fields), Before: true}.Encode()
+	}
+	return resp, page, nil
+}
+
+// exportBatchSize is how many patients Export reads per query
+const exportBatchSize = 1000
+
+// Export hands every patient matching the list filters to write, a batch at a
+// time. The batches follow the requested sort by keyset conditions rather than
+// one long query, so no connection is held while write is slow, and q filters
+// without ranking since the rank cannot be part of a keyset.
+func (s *Service) Export(ctx context.Context, req *patientdto.ListPatientRequest, hospital string, write func([]patientdto.PatientRecord) error) error {
+	fields, err := req.SortFields()
+	if err != nil {
+		return err
+	}
+	keys := sortKeys(fields)
+
+	var after []string
+	for {
+		batch := []*model.Patient{}
+		query := s.db.NewSelect().
+			Model(&batch).
+			Where("hospital = ?", hospital)
+		filter(query, req)
+		search(query, req.Q)
+		if after != nil {
+			where, args, err := cursor.After(keys, after)
+			if err != nil {
+				return err
+			}
+			query.Where(where, args...)
+		}
+		order(query, keys)
+		if err := query.Limit(exportBatchSize).Scan(ctx); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		records := make([]patientdto.PatientRecord, 0, len(batch))
+		for _, patient := range batch {
+			records = append(records, ToPatientRecord(patient))
+		}
+		if err := write(records); err != nil {
+			return err
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		after = sortValues(batch[len(batch)-1], fields)
+	}
+}
+
+// ToPatientRecord is the patient as it is exported, the dates as text
+func ToPatientRecord(patient *model.Patient) patientdto.PatientRecord {
+	record := patientdto.PatientRecord{
+		ID:           patient.ID,
+		PatientHN:    patient.PatientHN,
+		NationalID:   string(patient.NationalID),
+		PassportID:   string(patient.PassportID),
+		FirstNameTH:  patient.FirstNameTH,
+		MiddleNameTH: patient.MiddleNameTH,
+		LastNameTH:   patient.LastNameTH,
+		FirstNameEN:  patient.FirstNameEN,
+		MiddleNameEN: patient.MiddleNameEN,
+		LastNameEN:   patient.LastNameEN,
+		Gender:       patient.Gender,
+		PhoneNumber:  string(patient.PhoneNumber),
+		Email:        string(patient.Email),
+		CreatedAt:    time.Unix(patient.CreatedAt, 0).UTC().Format(time.RFC3339),
+		UpdatedAt:    time.Unix(patient.UpdatedAt, 0).UTC().Format(time.RFC3339),
+	}
+	if !patient.DateOfBirth.IsZero() {
+		record.DateOfBirth = patient.DateOfBirth.Format(validate.DateLayout)
+	}
+	return record
+}
+
+// ToPatientDetail is the patient as the API returns it, before Shape
+func ToPatientDetail(patient *model.Patient) patientdto.PatientDetail {
+	detail := patientdto.PatientDetail{
+		ID:           patient.ID,
+		FirstNameTH:  patient.FirstNameTH,
+		MiddleNameTH: patient.MiddleNameTH,
+		LastNameTH:   patient.LastNameTH,
+		FirstNameEN:  patient.FirstNameEN,
+		MiddleNameEN: patient.MiddleNameEN,
+		LastNameEN:   patient.LastNameEN,
+		PatientHN:    patient.PatientHN,
+		NationalID:   string(patient.NationalID),
+		PassportID:   string(patient.PassportID),
+		PhoneNumber:  string(patient.PhoneNumber),
+		Email:        string(patient.Email),
+		Gender:       patient.Gender,
+		Hospital:     patient.Hospital,
+		SyncedAt:     patient.SyncedAt,
+		CreatedAt:    patient.CreatedAt,
+		UpdatedAt:    patient.UpdatedAt,
+		DeletedAt:    patient.DeletedAt,
+	}
+	if !patient.DateOfBirth.IsZero() {
+		detail.DateOfBirth = patient.DateOfBirth.Format(validate.DateLayout)
+	}
+	return detail
+}
+
+// filter applies the filters of the request, the free text search is left to search
+func filter(query *bun.SelectQuery, req *patientdto.ListPatientRequest) {
+	// the identifiers and contact details are encrypted, they match exactly on their blind index
 	if req.NationalID != "" {
-		nationalID := fmt.Sprint(strings.ToLower(req.NationalID) + "%")
-		query.Where("LOWER(national_id) LIKE ?", nationalID)
+		query.Where("national_id_bidx = ?", model.PatientBlindIndex("national_id", req.NationalID))
 	}
 
 	if req.PassportID != "" {
-		passportID := fmt.Sprint(strings.ToLower(req.PassportID) + "%")
-		query.Where("LOWER(passport_id) LIKE ?", passportID)
+		query.Where("passport_id_bidx = ?", model.PatientBlindIndex("passport_id", req.PassportID))
 	}
 
 	if req.FirstName != "" {
@@ -75,32 +607,555 @@ func (s *Service) List(ctx context.Context, req *patientdto.ListPatientRequest,
 		}
 	}
 	if req.Email != "" {
-		email := fmt.Sprint(strings.ToLower(req.Email) + "%")
-		query.Where("LOWER(email) LIKE ?", email)
+		query.Where("email_bidx = ?", model.PatientBlindIndex("email", req.Email))
 	}
 
 	if req.PhoneNumber != "" {
-		phoneNumber := fmt.Sprint(strings.ToLower(req.PhoneNumber) + "%")
-		query.Where("LOWER(phone_number) LIKE ?", phoneNumber)
+		query.Where("phone_number_bidx = ?", model.PatientBlindIndex("phone_number", req.PhoneNumber))
 	}
 
-	total, err := query.Count(ctx)
+	if req.HN != "" {
+		query.Where("patient_hn = ?", strings.ToUpper(strings.TrimSpace(req.HN)))
+	}
+
+	Consented(query, req.Purpose)
+}
+
+func (s *Service) Create(ctx context.Context, req *patientdto.CreatePatientRequest, hospital string) (*model.Patient, error) {
+	exists, err := s.existIdentity(ctx, req.NationalID, req.PassportID, hospital, "")
 	if err != nil {
-		return resp, 0, err
+		return nil, err
 	}
-	if total == 0 {
-		return resp, 0, nil
+	if exists {
+		return nil, errors.New(message.PatientAlreadyExists)
 	}
-	order := fmt.Sprintf("%s %s", req.SortBy, req.OrderBy)
 
-	err = query.
-		Offset(offset).
-		Limit(limit).
-		Order(order).
-		Scan(ctx, &resp)
+	patient := &model.Patient{Hospital: hospital}
+	applyRequest(patient, req)
+	err = s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		patientHN, err := s.hn.Next(ctx, tx, hospital)
+		if err != nil {
+			return err
+		}
+		patient.PatientHN = patientHN
+		_, err = tx.NewInsert().
+			Model(patient).
+			Returning("*").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		return s.link(ctx, tx, patient.ID)
+	})
 	if err != nil {
-		return resp, 0, err
+		return nil, err
 	}
+	return patient, nil
+}
 
-	return resp, total, nil
+// Import upserts the requests in tx. Existing patients are matched by national
+// ID or passport ID and updated, the others are inserted with HNs from a single
+// counter update. A request that matches two patients, a patient another
+// request already matched or a deleted patient is not saved and gets an error
+// in its result, deleted patients are not brought back by an import.
+func (s *Service) Import(ctx context.Context, tx bun.IDB, hospital string, reqs []*patientdto.CreatePatientRequest) ([]patientdto.ImportResult, error) {
+	results := make([]patientdto.ImportResult, len(reqs))
+	// patients are matched on the blind indexes, the identifiers are encrypted
+	nationalIDs, passportIDs := []string{}, []string{}
+	for _, req := range reqs {
+		if req.NationalID != "" {
+			nationalIDs = append(nationalIDs, model.PatientBlindIndex("national_id", req.NationalID))
+		}
+		if req.PassportID != "" {
+			passportIDs = append(passportIDs, model.PatientBlindIndex("passport_id", req.PassportID))
+		}
+	}
+
+	existing := []*model.Patient{}
+	err := tx.NewSelect().
+		Model(&existing).
+		WhereAllWithDeleted().
+		Where("hospital = ?", hospital).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			if len(nationalIDs) > 0 {
+				q.WhereOr("national_id_bidx IN (?)", bun.In(nationalIDs))
+			}
+			if len(passportIDs) > 0 {
+				q.WhereOr("passport_id_bidx IN (?)", bun.In(passportIDs))
+			}
+			return q
+		}).
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	byNationalID := map[string]*model.Patient{}
+	byPassportID := map[string]*model.Patient{}
+	for _, patient := range existing {
+		if patient.NationalIDIndex != "" {
+			byNationalID[patient.NationalIDIndex] = patient
+		}
+		if patient.PassportIDIndex != "" {
+			byPassportID[patient.PassportIDIndex] = patient
+		}
+	}
+
+	matched := map[*model.Patient]bool{}
+	created := []*model.Patient{}
+	updated := []string{}
+	for i, req := range reqs {
+		byNational := byNationalID[model.PatientBlindIndex("national_id", req.NationalID)]
+		byPassport := byPassportID[model.PatientBlindIndex("passport_id", req.PassportID)]
+		if req.NationalID == "" {
+			byNational = nil
+		}
+		if req.PassportID == "" {
+			byPassport = nil
+		}
+		if byNational != nil && byPassport != nil && byNational != byPassport {
+			results[i].Err = errors.New(message.PatientIdentityConflict)
+			continue
+		}
+		patient := byNational
+		if patient == nil {
+			patient = byPassport
+		}
+		if patient == nil {
+			patient = &model.Patient{Hospital: hospital}
+			applyRequest(patient, req)
+			created = append(created, patient)
+			results[i].Created = true
+			continue
+		}
+		if matched[patient] {
+			results[i].Err = errors.New(message.PatientIdentityConflict)
+			continue
+		}
+		matched[patient] = true
+		if patient.DeletedAt != nil {
+			results[i].Err = errors.New(message.PatientDeleted)
+			continue
+		}
+
+		applyRequest(patient, req)
+		patient.SetUpdateNow()
+		_, err := tx.NewUpdate().
+			Model(patient).
+			ExcludeColumn("id", "hospital", "created_at", "deleted_at").
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, patient.ID)
+	}
+	if err := s.link(ctx, tx, updated...); err != nil {
+		return nil, err
+	}
+
+	if len(created) == 0 {
+		return results, nil
+	}
+	hns, err := s.hn.Allocate(ctx, tx, hospital, len(created))
+	if err != nil {
+		return nil, err
+	}
+	for i, patient := range created {
+		patient.PatientHN = hns[i]
+	}
+	_, err = tx.NewInsert().
+		Model(&created).
+		Returning("id").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(created))
+	for _, patient := range created {
+		ids = append(ids, patient.ID)
+	}
+	if err := s.link(ctx, tx, ids...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Get returns the live patient of the hospital when it may be read for the
+// purpose, see Consented
+func (s *Service) Get(ctx context.Context, id string, hospital string, purpose enum.Purpose) (*model.Patient, error) {
+	patient, err := s.get(ctx, id, hospital)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.consented(ctx, patient.ID, patient.Hospital, purpose); err != nil {
+		return nil, err
+	}
+	return patient, nil
+}
+
+// get returns the live patient of the hospital, for writing it
+func (s *Service) get(ctx context.Context, id string, hospital string) (*model.Patient, error) {
+	patient := new(model.Patient)
+	err := s.db.NewSelect().
+		Model(patient).
+		Where("id = ?", id).
+		Where("hospital = ?", hospital).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.PatientNotFound)
+		}
+		return nil, err
+	}
+	return patient, nil
+}
+
+func (s *Service) Update(ctx context.Context, id string, req *patientdto.UpdatePatientRequest, hospital string) (*model.Patient, error) {
+	patient, err := s.get(ctx, id, hospital)
+	if err != nil {
+		return nil, err
+	}
+	return s.save(ctx, patient, &req.CreatePatientRequest)
+}
+
+// Patch merges the present fields into the stored patient and validates the result as a whole
+func (s *Service) Patch(ctx context.Context, id string, req *patientdto.PatchPatientRequest, hospital string) (*model.Patient, error) {
+	patient, err := s.get(ctx, id, hospital)
+	if err != nil {
+		return nil, err
+	}
+	merged := toCreateRequest(patient)
+	req.Apply(merged)
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+	return s.save(ctx, patient, merged)
+}
+
+func (s *Service) Delete(ctx context.Context, id string, hospital string) error {
+	return s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewDelete().
+			Model((*model.Patient)(nil)).
+			Where("id = ?", id).
+			Where("hospital = ?", hospital).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return errors.New(message.PatientNotFound)
+		}
+		return s.link(ctx, tx, id)
+	})
+}
+
+func (s *Service) save(ctx context.Context, patient *model.Patient, req *patientdto.CreatePatientRequest) (*model.Patient, error) {
+	exists, err := s.existIdentity(ctx, req.NationalID, req.PassportID, patient.Hospital, patient.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New(message.PatientAlreadyExists)
+	}
+
+	applyRequest(patient, req)
+	patient.SetUpdateNow()
+	err = s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewUpdate().
+			Model(patient).
+			ExcludeColumn("id", "hospital", "created_at", "deleted_at").
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		return s.link(ctx, tx, patient.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patient, nil
+}
+
+// existIdentity reports whether another patient of the hospital holds the national ID or passport ID.
+// Deleted patients count, the unique constraints on the identifiers include them.
+func (s *Service) existIdentity(ctx context.Context, nationalID, passportID, hospital, excludeID string) (bool, error) {
+	query := s.db.NewSelect().
+		Model((*model.Patient)(nil)).
+		WhereAllWithDeleted().
+		Where("hospital = ?", hospital).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			if nationalID != "" {
+				q.WhereOr("national_id_bidx = ?", model.PatientBlindIndex("national_id", nationalID))
+			}
+			if passportID != "" {
+				q.WhereOr("passport_id_bidx = ?", model.PatientBlindIndex("passport_id", passportID))
+			}
+			return q
+		})
+	if excludeID != "" {
+		query.Where("id != ?", excludeID)
+	}
+	return query.Exists(ctx)
+}
+
+func applyRequest(patient *model.Patient, req *patientdto.CreatePatientRequest) {
+	dob, _ := validate.DateOfBirth(req.DateOfBirth)
+	patient.FirstNameTH = req.FirstNameTH
+	patient.MiddleNameTH = req.MiddleNameTH
+	patient.LastNameTH = req.LastNameTH
+	patient.FirstNameEN = req.FirstNameEN
+	patient.MiddleNameEN = req.MiddleNameEN
+	patient.LastNameEN = req.LastNameEN
+	patient.DateOfBirth = dob
+	patient.NationalID = fieldcrypt.EncryptedString(req.NationalID)
+	patient.PassportID = fieldcrypt.EncryptedString(req.PassportID)
+	patient.PhoneNumber = fieldcrypt.EncryptedString(req.PhoneNumber)
+	patient.Email = fieldcrypt.EncryptedString(req.Email)
+	patient.Gender = req.Gender
+}
+
+func toCreateRequest(patient *model.Patient) *patientdto.CreatePatientRequest {
+	return &patientdto.CreatePatientRequest{
+		FirstNameTH:  patient.FirstNameTH,
+		MiddleNameTH: patient.MiddleNameTH,
+		LastNameTH:   patient.LastNameTH,
+		FirstNameEN:  patient.FirstNameEN,
+		MiddleNameEN: patient.MiddleNameEN,
+		LastNameEN:   patient.LastNameEN,
+		DateOfBirth:  patient.DateOfBirth.Format(validate.DateLayout),
+		NationalID:   string(patient.NationalID),
+		PassportID:   string(patient.PassportID),
+		PhoneNumber:  string(patient.PhoneNumber),
+		Email:        string(patient.Email),
+		Gender:       patient.Gender,
+	}
+}
+
+// encryptedColumns are the patient columns stored with fieldcrypt, each with a
+// <column>_bidx blind index
+var encryptedColumns = []string{"national_id", "passport_id", "phone_number", "email"}
+
+// Unindexed counts the patients, deleted ones included, with an encrypted
+// column but no blind index for it, rows stored before the patient_encryption
+// migration that Reencrypt has not rewritten yet
+func (s *Service) Unindexed(ctx context.Context) (int, error) {
+	return s.db.NewSelect().
+		Model((*model.Patient)(nil)).
+		WhereAllWithDeleted().
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			for _, column := range encryptedColumns {
+				q.WhereOr("? IS NOT NULL AND ? IS NULL", bun.Ident(column), bun.Ident(column+"_bidx"))
+			}
+			return q
+		}).
+		Count(ctx)
+}
+
+// reencryptBatchSize is how many patients Reencrypt rewrites per transaction
+const reencryptBatchSize = 500
+
+// Reencrypt rewrites the encrypted columns and blind indexes of the patients,
+// deleted ones included, that hold plaintext or values under a key other than
+// the active one. all rewrites every patient, which a new blind index key
+// needs. It returns how many patients were rewritten.
+func (s *Service) Reencrypt(ctx context.Context, all bool) (int, error) {
+	keyring := fieldcrypt.Current()
+	if keyring == nil {
+		return 0, fieldcrypt.ErrNoKeyring
+	}
+	columns := append([]string{}, encryptedColumns...)
+	for _, column := range encryptedColumns {
+		columns = append(columns, column+"_bidx")
+	}
+
+	total, after := 0, ""
+	for {
+		batch := []*model.Patient{}
+		query := s.db.NewSelect().
+			Model(&batch).
+			WhereAllWithDeleted().
+			Order("id ASC").
+			Limit(reencryptBatchSize)
+		if after != "" {
+			query.Where("id > ?", after)
+		}
+		if !all {
+			query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				for _, column := range encryptedColumns {
+					q.WhereOr("? NOT LIKE ?", bun.Ident(column), keyring.Prefix()+"%")
+				}
+				return q
+			})
+		}
+		if err := query.Scan(ctx); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		// the values were decrypted on scan, the update encrypts them with the
+		// active key and BeforeAppendModel recomputes the blind indexes
+		err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			for _, patient := range batch {
+				_, err := tx.NewUpdate().
+					Model(patient).
+					Column(columns...).
+					WhereAllWithDeleted().
+					WherePK().
+					Exec(ctx)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += len(batch)
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// sortColumns reads the sort columns of a patient the way cursors keep them,
+// text columns sort coalesced so NULL and ” (which both scan to "") sort alike
+var sortColumns = map[string]func(p *model.Patient) string{
+	"first_name_th": func(p *model.Patient) string { return p.FirstNameTH },
+	"last_name_th":  func(p *model.Patient) string { return p.LastNameTH },
+	"first_name_en": func(p *model.Patient) string { return p.FirstNameEN },
+	"last_name_en":  func(p *model.Patient) string { return p.LastNameEN },
+	"patient_hn":    func(p *model.Patient) string { return p.PatientHN },
+	"gender":        func(p *model.Patient) string { return p.Gender },
+	"date_of_birth": func(p *model.Patient) string { return p.DateOfBirth.Format(validate.DateLayout) },
+	"created_at":    func(p *model.Patient) string { return strconv.FormatInt(p.CreatedAt, 10) },
+	"updated_at":    func(p *model.Patient) string { return strconv.FormatInt(p.UpdatedAt, 10) },
+}
+
+// notNullColumns are compared as they are
+var notNullColumns = map[string]bool{"date_of_birth": true, "created_at": true, "updated_at": true}
+
+// sortKeys turns whitelisted fields into sort keys ending with id, which keeps
+// pages stable when the sort columns tie. id follows the direction of the last
+// field so an index on (hospital, column, id) can be scanned either way.
+func sortKeys(fields []sorting.Field) []cursor.Key {
+	keys := make([]cursor.Key, 0, len(fields)+1)
+	desc := false
+	for _, field := range fields {
+		var expr any = bun.Ident(field.Column)
+		if !notNullColumns[field.Column] {
+			expr = bun.SafeQuery("coalesce(?, '')", bun.Ident(field.Column))
+		}
+		keys = append(keys, cursor.Key{Expr: expr, Desc: field.Desc})
+		desc = field.Desc
+	}
+	return append(keys, cursor.Key{Expr: bun.Ident("id"), Desc: desc})
+}
+
+// sortValues are the sort keys of the patient, in the order of sortKeys
+func sortValues(p *model.Patient, fields []sorting.Field) []string {
+	values := make([]string, 0, len(fields)+1)
+	for _, field := range fields {
+		values = append(values, sortColumns[field.Column](p))
+	}
+	return append(values, p.ID)
+}
+
+// sortParsers check the cursor values of the columns that are not text, a
+// tampered value would otherwise fail the query with a cast error
+var sortParsers = map[string]func(value string) error{
+	"date_of_birth": func(value string) error {
+		_, err := time.Parse(validate.DateLayout, value)
+		return err
+	},
+	"created_at": func(value string) error {
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err
+	},
+	"updated_at": func(value string) error {
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err
+	},
+}
+
+// validSortValues tells whether the values of a cursor fit the sort keys of
+// fields, in the order of sortValues
+func validSortValues(values []string, fields []sorting.Field) bool {
+	if len(values) != len(fields)+1 {
+		return false
+	}
+	for i, field := range fields {
+		if parse := sortParsers[field.Column]; parse != nil && parse(values[i]) != nil {
+			return false
+		}
+	}
+	_, err := uuid.Parse(values[len(fields)])
+	return err == nil
+}
+
+func order(query *bun.SelectQuery, keys []cursor.Key) {
+	for _, key := range keys {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		query.OrderExpr("? "+direction, key.Expr)
+	}
+}
+
+// searchName is the expression of the patients_search_name_trgm_idx index, it has
+// to stay verbatim for the index to be used
+const searchName = `lower(coalesce(first_name_th, '') || coalesce(' ' || nullif(middle_name_th, ''), '') || ' ' || coalesce(last_name_th, '') || ' ' || coalesce(first_name_en, '') || coalesce(' ' || nullif(middle_name_en, ''), '') || ' ' || coalesce(last_name_en, ''))`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// search filters on q and returns the rank of the patients, nil without q:
+// exact identifiers first, then HN prefixes, names containing every
+// word of q, and names that are only similar (typos), any part of the Thai or
+// English full name, each ordered by trigram word similarity
+func search(query *bun.SelectQuery, q string) *cursor.Key {
+	terms := strings.Fields(strings.ToLower(q))
+	if len(terms) == 0 {
+		return nil
+	}
+	text := strings.Join(terms, " ")
+	// identifiers are matched without the spaces and dashes people type into them
+	token := strings.Join(terms, "")
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, token)
+
+	names := make([]string, 0, len(terms))
+	nameArgs := make([]any, 0, len(terms))
+	for _, term := range terms {
+		names = append(names, searchName+" LIKE ?")
+		nameArgs = append(nameArgs, "%"+likeEscaper.Replace(term)+"%")
+	}
+	nameMatch := "(" + strings.Join(names, " AND ") + ")"
+
+	// the encrypted identifiers and contact details only match whole, on their
+	// blind indexes, the HN also matches by prefix
+	upper := strings.ToUpper(token)
+	exact := []string{"patient_hn = ?", "passport_id_bidx = ?", "email_bidx = ?"}
+	exactArgs := []any{upper, model.PatientBlindIndex("passport_id", token), model.PatientBlindIndex("email", token)}
+	// a couple of digits would match most of the table
+	if len(digits) >= 3 {
+		exact = append(exact, "national_id_bidx = ?", "phone_number_bidx = ?")
+		exactArgs = append(exactArgs, model.PatientBlindIndex("national_id", digits), model.PatientBlindIndex("phone_number", digits))
+	}
+	exactMatch := "(" + strings.Join(exact, " OR ") + ")"
+	prefixMatch := "(patient_hn LIKE ?)"
+	prefixArgs := []any{likeEscaper.Replace(upper) + "%"}
+	fuzzyMatch := "? <% " + searchName
+
+	whereArgs := append(append(append(append([]any{}, nameArgs...), text), exactArgs...), prefixArgs...)
+	query.Where(nameMatch+" OR "+fuzzyMatch+" OR "+exactMatch+" OR "+prefixMatch, whereArgs...)
+
+	rankArgs := append(append(append(append([]any{}, exactArgs...), prefixArgs...), nameArgs...), text)
+	rank := bun.SafeQuery("CASE WHEN "+exactMatch+" THEN 3 WHEN "+prefixMatch+" THEN 2 WHEN "+nameMatch+" THEN 1 ELSE 0 END + word_similarity(?, "+searchName+")", rankArgs...)
+	return &cursor.Key{Expr: rank, Desc: true}
 }
@@ -0,0 +1,220 @@
+package patient
+
+import (
+	"app/app/enum"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	patientdto "app/app/modules/patient/dto"
+	"app/app/util/his"
+	"app/app/util/his/histest"
+	"app/app/util/hn"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+// newTestService is a Service on the test database for a hospital of its own,
+// answered by a stand-in HIS
+func newTestService(t *testing.T) (*Service, *histest.Server) {
+	t.Helper()
+	db := testhelper.DB(t)
+	hospital := testhelper.Hospital(t, db)
+	upstream := histest.NewServer(t, hospital, nil)
+	format, err := hn.Parse("HN{seq:6}")
+	require.NoError(t, err)
+	return NewService(db, his.NewRegistry(upstream.Adapter(t)), format), upstream
+}
+
+func syncedPatients(t *testing.T, db *bun.DB, hospital string) []*model.Patient {
+	t.Helper()
+	var patients []*model.Patient
+	err := db.NewSelect().
+		Model(&patients).
+		WhereAllWithDeleted().
+		Where("hospital = ?", hospital).
+		Order("created_at", "id").
+		Scan(context.Background())
+	require.NoError(t, err)
+	return patients
+}
+
+func upstreamPatient(hospital string) *patientdto.PatientResponse {
+	return &patientdto.PatientResponse{
+		FirstNameTH: "สมชาย",
+		LastNameTH:  "ใจดี",
+		DateOfBirth: "1990-05-17",
+		PatientHN:   "HIS-0001",
+		NationalID:  "1103702071811",
+		PhoneNumber: "0812345678",
+		Gender:      "M",
+		Hospital:    hospital,
+	}
+}
+
+func TestService_Sync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Inserts the record and refreshes it by national ID", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		data := upstreamPatient(upstream.Hospital)
+		require.NoError(t, svc.Sync(ctx, data, upstream.Hospital))
+
+		inserted := data.ID
+		data.PhoneNumber = "0899999999"
+		require.NoError(t, svc.Sync(ctx, data, upstream.Hospital))
+
+		patients := syncedPatients(t, svc.db, upstream.Hospital)
+		require.Len(t, patients, 1)
+		assert.Equal(t, patients[0].ID, inserted)
+		assert.Equal(t, patients[0].ID, data.ID)
+		assert.Equal(t, "0899999999", string(patients[0].PhoneNumber))
+		assert.NotZero(t, patients[0].SyncedAt)
+	})
+
+	t.Run("Success - A deleted patient is refreshed and stays deleted", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		data := upstreamPatient(upstream.Hospital)
+		require.NoError(t, svc.Sync(ctx, data, upstream.Hospital))
+		patient := syncedPatients(t, svc.db, upstream.Hospital)[0]
+		require.NoError(t, svc.Delete(ctx, patient.ID, upstream.Hospital))
+
+		data.LastNameTH = "ใจงาม"
+		require.NoError(t, svc.Sync(ctx, data, upstream.Hospital))
+
+		patients := syncedPatients(t, svc.db, upstream.Hospital)
+		require.Len(t, patients, 1, "no second row for the deleted patient")
+		assert.Equal(t, "ใจงาม", patients[0].LastNameTH)
+		assert.NotNil(t, patients[0].DeletedAt)
+	})
+
+	t.Run("Fail - HN of another patient", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		require.NoError(t, svc.Sync(ctx, upstreamPatient(upstream.Hospital), upstream.Hospital))
+
+		other := upstreamPatient(upstream.Hospital)
+		other.NationalID = "3100600123457"
+		err := svc.Sync(ctx, other, upstream.Hospital)
+
+		require.Error(t, err)
+		assert.Equal(t, message.PatientIdentityConflict, err.Error())
+		assert.Len(t, syncedPatients(t, svc.db, upstream.Hospital), 1)
+	})
+
+	t.Run("Fail - National ID and passport ID of two patients", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		require.NoError(t, svc.Sync(ctx, upstreamPatient(upstream.Hospital), upstream.Hospital))
+		traveller := upstreamPatient(upstream.Hospital)
+		traveller.NationalID = ""
+		traveller.PassportID = "AA1234567"
+		traveller.PatientHN = "HIS-0002"
+		require.NoError(t, svc.Sync(ctx, traveller, upstream.Hospital))
+
+		both := upstreamPatient(upstream.Hospital)
+		both.PassportID = "AA1234567"
+		err := svc.Sync(ctx, both, upstream.Hospital)
+
+		require.Error(t, err)
+		assert.Equal(t, message.PatientIdentityConflict, err.Error())
+	})
+}
+
+func TestService_GetPatient(t *testing.T) {
+	ctx := context.Background()
+	viper.Set("HIS_CACHE_TTL", time.Hour)
+	t.Cleanup(func() { viper.Set("HIS_CACHE_TTL", nil) })
+
+	t.Run("Success - Served from the cache once synced, deleted or not", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		upstream.SetPatient("1103702071811", upstreamPatient(upstream.Hospital))
+
+		_, err := svc.GetPatient(ctx, "1103702071811", upstream.Hospital, enum.PURPOSE_TREATMENT)
+		require.NoError(t, err)
+		patient := syncedPatients(t, svc.db, upstream.Hospital)[0]
+		require.NoError(t, svc.Delete(ctx, patient.ID, upstream.Hospital))
+
+		data, err := svc.GetPatient(ctx, "1103702071811", upstream.Hospital, enum.PURPOSE_TREATMENT)
+		require.NoError(t, err)
+		assert.Equal(t, "HIS-0001", data.PatientHN)
+		assert.Len(t, upstream.Requests(), 1, "the deleted patient is still cached")
+	})
+
+	t.Run("Success - A conflicting record is served without caching", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		require.NoError(t, svc.Sync(ctx, upstreamPatient(upstream.Hospital), upstream.Hospital))
+		other := upstreamPatient(upstream.Hospital)
+		other.NationalID = "3100600123457"
+		upstream.SetPatient("3100600123457", other)
+
+		data, err := svc.GetPatient(ctx, "3100600123457", upstream.Hospital, enum.PURPOSE_TREATMENT)
+
+		require.NoError(t, err)
+		assert.Equal(t, "3100600123457", data.NationalID)
+		assert.Len(t, syncedPatients(t, svc.db, upstream.Hospital), 1)
+	})
+
+	t.Run("Fail - Research needs the patient's consent", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		upstream.SetPatient("1103702071811", upstreamPatient(upstream.Hospital))
+
+		_, err := svc.GetPatient(ctx, "1103702071811", upstream.Hospital, enum.PURPOSE_RESEARCH)
+		require.Error(t, err)
+		assert.Equal(t, message.PatientConsentRequired, err.Error())
+		patient := syncedPatients(t, svc.db, upstream.Hospital)[0]
+		_, err = svc.Get(ctx, patient.ID, upstream.Hospital, enum.PURPOSE_RESEARCH)
+		require.Error(t, err)
+		assert.Equal(t, message.PatientConsentRequired, err.Error())
+
+		_, err = svc.db.NewInsert().Model(&model.PatientConsent{
+			Hospital:  upstream.Hospital,
+			PatientID: patient.ID,
+			Type:      enum.CONSENT_RESEARCH,
+			Scope:     enum.ConsentScopeAll,
+			ValidFrom: time.Now().Add(-time.Hour).Unix(),
+		}).Exec(ctx)
+		require.NoError(t, err)
+		_, err = svc.GetPatient(ctx, "1103702071811", upstream.Hospital, enum.PURPOSE_RESEARCH)
+		assert.NoError(t, err)
+		_, err = svc.Get(ctx, patient.ID, upstream.Hospital, enum.PURPOSE_RESEARCH)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Fail - Upstream record without a hospital", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		data := upstreamPatient("")
+		upstream.SetPatient("1103702071811", data)
+
+		_, err := svc.GetPatient(ctx, "1103702071811", upstream.Hospital, enum.PURPOSE_TREATMENT)
+
+		require.Error(t, err)
+		assert.Equal(t, message.PatientHospitalMismatch, err.Error())
+		assert.Empty(t, syncedPatients(t, svc.db, upstream.Hospital))
+	})
+}
+
+func TestService_Create(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Fail - National ID of a deleted patient", func(t *testing.T) {
+		svc, upstream := newTestService(t)
+		req := &patientdto.CreatePatientRequest{
+			FirstNameTH: "สมชาย",
+			DateOfBirth: "1990-05-17",
+			NationalID:  "1103702071811",
+			Gender:      "1",
+		}
+		patient, err := svc.Create(ctx, req, upstream.Hospital)
+		require.NoError(t, err)
+		require.NoError(t, svc.Delete(ctx, patient.ID, upstream.Hospital))
+
+		_, err = svc.Create(ctx, req, upstream.Hospital)
+
+		require.Error(t, err)
+		assert.Equal(t, message.PatientAlreadyExists, err.Error())
+	})
+}
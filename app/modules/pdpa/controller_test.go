@@ -0,0 +1,144 @@
+package pdpa
+
+import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	patientdto "app/app/modules/patient/dto"
+	pdpadto "app/app/modules/pdpa/dto"
+	"app/app/util/jwt"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// PDPAMockService for testing
+type PDPAMockService struct {
+	mock.Mock
+}
+
+func (m *PDPAMockService) Report(ctx context.Context, id string, hospital, staffID string) (*pdpadto.Report, error) {
+	args := m.Called(ctx, id, hospital, staffID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pdpadto.Report), args.Error(1)
+}
+
+func (m *PDPAMockService) Erase(ctx context.Context, id string, req *pdpadto.ErasePatientRequest, hospital, staffID string) (*model.PDPARequest, error) {
+	args := m.Called(ctx, id, req, hospital, staffID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PDPARequest), args.Error(1)
+}
+
+const (
+	patientID = "0b9e8f3a-4c2d-4f51-9a47-6f1d2c3b4a59"
+	mergedID  = "65e08e33-9f57-45fe-b725-82242e3581ad"
+)
+
+var claims = &jwt.Claims{Data: jwt.ClaimData{
+	ID:          "staff-1",
+	Hospital:    "hospital-a",
+	Role:        "admin",
+	Permissions: []string{"patient:pdpa"},
+}}
+
+func TestPDPAController_Report(t *testing.T) {
+	t.Run("Success - Report unmasked with the merged patients", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(PDPAMockService)
+		mockService.On("Report", mock.Anything, patientID, "hospital-a", "staff-1").Return(&pdpadto.Report{
+			Patient:        patientdto.PatientDetail{ID: patientID, NationalID: "1103702071811"},
+			MergedPatients: []patientdto.PatientDetail{{ID: mergedID}},
+			Merges: []pdpadto.MergeRecord{{
+				PatientMerge: &model.PatientMerge{SurvivorID: patientID, MergedID: mergedID},
+				Snapshot:     json.RawMessage(`{"passport_id":"AA1234567"}`),
+			}},
+			Requests: []*model.PDPARequest{{PatientID: patientID, Type: enum.PDPA_ACCESS}},
+		}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/"+patientID+"/pdpa/report", nil, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Report(c)
+
+		assert.Equal(t, 200, w.Code)
+		body := w.Body.String()
+		assert.Contains(t, body, "1103702071811", "the subject sees their data unmasked")
+		assert.Contains(t, body, `"snapshot":{"passport_id":"AA1234567"}`)
+		assert.Contains(t, body, `"type":"access"`)
+		assert.Equal(t, []string{patientID, mergedID}, helper.GetAuditPatients(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Patient not found", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(PDPAMockService)
+		mockService.On("Report", mock.Anything, patientID, "hospital-a", "staff-1").Return(nil, errors.New(message.PatientNotFound))
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("GET", "/patient/"+patientID+"/pdpa/report", nil, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Report(c)
+
+		assert.Equal(t, 404, w.Code)
+	})
+}
+
+func TestPDPAController_Erase(t *testing.T) {
+	req := &pdpadto.ErasePatientRequest{Reason: "Erasure requested in writing"}
+
+	t.Run("Success - Patient and merged patients erased", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(PDPAMockService)
+		mockService.On("Erase", mock.Anything, patientID, req, "hospital-a", "staff-1").
+			Return(&model.PDPARequest{PatientID: patientID, Type: enum.PDPA_ERASURE, PatientIDs: []string{patientID, mergedID}, Reason: req.Reason}, nil)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/pdpa/erase", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Erase(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `"type":"erasure"`)
+		assert.Equal(t, []string{patientID, mergedID}, helper.GetAuditPatients(c))
+		assert.Equal(t, req.Reason, helper.GetAuditReason(c))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Already erased", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(PDPAMockService)
+		mockService.On("Erase", mock.Anything, patientID, req, "hospital-a", "staff-1").Return(nil, errors.New(message.PatientAlreadyErased))
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/pdpa/erase", req, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Erase(c)
+
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), message.PatientAlreadyErased)
+	})
+
+	t.Run("Fail - Reason missing", func(t *testing.T) {
+		testhelper.UseJSONNaming(t)
+		mockService := new(PDPAMockService)
+
+		controller := NewController(mockService)
+		c, w := testhelper.NewContextWithClaims("POST", "/patient/"+patientID+"/pdpa/erase", map[string]string{}, claims)
+		c.Params = gin.Params{{Key: "id", Value: patientID}}
+		controller.Erase(c)
+
+		assert.Equal(t, 400, w.Code)
+		mockService.AssertNotCalled(t, "Erase", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
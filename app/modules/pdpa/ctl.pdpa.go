@@ -0,0 +1,90 @@
+package pdpa
+
+import (
+	"app/app/helper"
+	"app/app/message"
+	pdpadto "app/app/modules/pdpa/dto"
+	"app/app/response"
+	"app/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller struct {
+	Service ServiceInterface
+}
+
+func NewController(svc ServiceInterface) *Controller {
+	return &Controller{
+		Service: svc,
+	}
+}
+
+// Report answers the PDPA access request of the patient of the uri with every
+// piece of data held about them, unmasked
+func (c *Controller) Report(ctx *gin.Context) {
+	id := new(pdpadto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, err := c.Service.Report(ctx, id.ID, user.Data.Hospital, user.Data.ID)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	for _, merged := range data.MergedPatients {
+		helper.AuditPatients(ctx, merged.ID)
+	}
+	response.Success(ctx, data)
+}
+
+// Erase anonymizes the patient of the uri on a PDPA erasure request
+func (c *Controller) Erase(ctx *gin.Context) {
+	id := new(pdpadto.PatientIDRequest)
+	if err := ctx.BindUri(id); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	req := new(pdpadto.ErasePatientRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	helper.AuditPatients(ctx, id.ID)
+	helper.AuditReason(ctx, req.Reason)
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	data, err := c.Service.Erase(ctx, id.ID, req, user.Data.Hospital, user.Data.ID)
+	if err != nil {
+		logger.Err(err)
+		respondError(ctx, err)
+		return
+	}
+	// the patients merged into it were erased too
+	helper.AuditPatients(ctx, data.PatientIDs[1:]...)
+	response.Success(ctx, data)
+}
+
+// respondError maps the message of a service error onto its status code
+func respondError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case message.PatientNotFound:
+		response.NotFound(ctx, err.Error(), nil)
+	case message.PatientAlreadyErased:
+		response.BadRequest(ctx, err.Error(), nil)
+	default:
+		response.InternalError(ctx, err.Error(), nil)
+	}
+}
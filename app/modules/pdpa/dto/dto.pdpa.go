@@ -0,0 +1,39 @@
+package pdpadto
+
+import (
+	"app/app/model"
+	patientdto "app/app/modules/patient/dto"
+	"encoding/json"
+)
+
+type PatientIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// ErasePatientRequest erases a patient, the reason is kept with the request
+type ErasePatientRequest struct {
+	Reason string `json:"reason" binding:"required,min=10,max=500"`
+}
+
+// Report is every piece of data held about a patient: the patient, the
+// patients merged into it and what refers to any of them, the import errors
+// reported under their identifiers included. The access log only names the
+// patients of the report, and holds the lookups made by their identifiers.
+type Report struct {
+	GeneratedAt    int64                      `json:"generated_at"`
+	Patient        patientdto.PatientDetail   `json:"patient"`
+	MergedPatients []patientdto.PatientDetail `json:"merged_patients"`
+	Merges         []MergeRecord              `json:"merges"`
+	Links          []*model.PatientLink       `json:"links"`
+	RecordRequests []*model.RecordRequest     `json:"record_requests"`
+	Consents       []*model.PatientConsent    `json:"consents"`
+	AccessLog      []*model.AuditLog          `json:"access_log"`
+	ImportErrors   []*model.ImportJobError    `json:"import_errors"`
+	Requests       []*model.PDPARequest       `json:"pdpa_requests"`
+}
+
+// MergeRecord is a merge with the snapshot of the merged patient it kept
+type MergeRecord struct {
+	*model.PatientMerge
+	Snapshot json.RawMessage `json:"snapshot"`
+}
@@ -0,0 +1,14 @@
+package pdpa
+
+import (
+	"app/app/model"
+	pdpadto "app/app/modules/pdpa/dto"
+	"context"
+)
+
+type ServiceInterface interface {
+	Report(ctx context.Context, id string, hospital, staffID string) (*pdpadto.Report, error)
+	Erase(ctx context.Context, id string, req *pdpadto.ErasePatientRequest, hospital, staffID string) (*model.PDPARequest, error)
+}
+
+var _ ServiceInterface = (*Service)(nil)
@@ -0,0 +1,26 @@
+package pdpa
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestAnonymize(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	query := anonymize(db, []string{"p-1", "p-2"}, time.Unix(1767225600, 0)).String()
+	for _, column := range anonymized {
+		assert.Contains(t, query, `"`+column+`" = NULL`)
+	}
+	assert.Contains(t, query, "date_of_birth = DATE_TRUNC('year', date_of_birth)::date")
+	assert.Contains(t, query, "anonymized_at = 1767225600")
+	assert.Contains(t, query, "deleted_at = COALESCE(deleted_at, ")
+	assert.Contains(t, query, "id IN ('p-1', 'p-2')")
+	assert.NotContains(t, query, "deleted_at\" IS NULL", "deleted patients are erased too")
+	assert.NotContains(t, query, "gender", "the gender is kept for statistics")
+}
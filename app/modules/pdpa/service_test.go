@@ -0,0 +1,174 @@
+package pdpa
+
+import (
+	"app/app/enum"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	pdpadto "app/app/modules/pdpa/dto"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+// person is a patient of the hospital with a patient merged into it, an entry
+// of the access log for each, a lookup by national ID from before the log kept
+// route templates and an import error reported under the national ID
+type person struct {
+	patient, merged *model.Patient
+	lookup, read    *model.AuditLog
+	other           *model.AuditLog
+	importError     *model.ImportJobError
+}
+
+func insert(t *testing.T, db bun.IDB, row any) {
+	t.Helper()
+	_, err := db.NewInsert().Model(row).Returning("*").Exec(context.Background())
+	require.NoError(t, err)
+}
+
+func newPerson(t *testing.T, db bun.IDB, hospital string) *person {
+	t.Helper()
+	p := &person{
+		patient: &model.Patient{
+			FirstNameTH: "สมชาย",
+			LastNameTH:  "ใจดี",
+			DateOfBirth: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC),
+			PatientHN:   "HN000001",
+			NationalID:  "1103702071811",
+			Email:       "somchai@example.com",
+			Gender:      "1",
+			Hospital:    hospital,
+		},
+	}
+	insert(t, db, p.patient)
+	now := time.Now()
+	p.merged = &model.Patient{
+		FirstNameEN: "Somchai",
+		DateOfBirth: p.patient.DateOfBirth,
+		PatientHN:   "HN000002",
+		Gender:      "1",
+		Hospital:    hospital,
+		MergedInto:  p.patient.ID,
+		SoftDelete:  model.SoftDelete{DeletedAt: &now},
+	}
+	insert(t, db, p.merged)
+
+	p.lookup = &model.AuditLog{RequestID: "lookup", Hospital: hospital, Action: enum.AUDIT_PATIENT_LOOKUP, Method: "GET", Path: "/patient/search/1103702071811", Status: 200}
+	p.read = &model.AuditLog{RequestID: "read", Hospital: hospital, Action: enum.AUDIT_PATIENT_LIST, PatientIDs: []string{p.patient.ID}, Query: map[string][]string{"email": {"somchai@example.com"}}, Method: "GET", Path: "/patient", Status: 200}
+	p.other = &model.AuditLog{RequestID: "other", Hospital: hospital, Action: enum.AUDIT_PATIENT_LOOKUP, Method: "GET", Path: "/patient/search/1234567890121", Status: 200}
+	for _, entry := range []*model.AuditLog{p.lookup, p.read, p.other} {
+		insert(t, db, entry)
+	}
+
+	job := &model.ImportJob{Hospital: hospital, Format: "csv", Total: 1, Failed: 1}
+	insert(t, db, job)
+	p.importError = &model.ImportJobError{JobID: job.ID, Row: 2, Identifier: "1103-70207-18-11", Message: message.PatientIdentityConflict}
+	insert(t, db, p.importError)
+	return p
+}
+
+func TestService_Report(t *testing.T) {
+	ctx := context.Background()
+	db := testhelper.DB(t)
+
+	t.Run("Success - Reports the records, lookups and import errors of the person", func(t *testing.T) {
+		hospital := testhelper.Hospital(t, db)
+		p := newPerson(t, db, hospital)
+
+		report, err := NewService(db).Report(ctx, p.merged.ID, hospital, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, p.patient.ID, report.Patient.ID, "a merged patient stands for the survivor")
+		if assert.Len(t, report.MergedPatients, 1) {
+			assert.Equal(t, p.merged.ID, report.MergedPatients[0].ID)
+		}
+		ids := []int64{}
+		for _, entry := range report.AccessLog {
+			ids = append(ids, entry.ID)
+		}
+		assert.ElementsMatch(t, []int64{p.lookup.ID, p.read.ID}, ids)
+		if assert.Len(t, report.ImportErrors, 1) {
+			assert.Equal(t, p.importError.ID, report.ImportErrors[0].ID)
+		}
+		if assert.Len(t, report.Requests, 1) {
+			assert.Equal(t, p.patient.ID, report.Requests[0].PatientID)
+		}
+	})
+
+	t.Run("Fail - Patient of another hospital", func(t *testing.T) {
+		p := newPerson(t, db, testhelper.Hospital(t, db))
+
+		_, err := NewService(db).Report(ctx, p.patient.ID, testhelper.Hospital(t, db), "")
+
+		require.Error(t, err)
+		assert.Equal(t, message.PatientNotFound, err.Error())
+	})
+}
+
+func TestService_Erase(t *testing.T) {
+	ctx := context.Background()
+	db := testhelper.DB(t)
+	req := &pdpadto.ErasePatientRequest{Reason: "Erasure requested in writing"}
+
+	t.Run("Success - Erases the person and redacts their identifiers", func(t *testing.T) {
+		hospital := testhelper.Hospital(t, db)
+		p := newPerson(t, db, hospital)
+
+		request, err := NewService(db).Erase(ctx, p.merged.ID, req, hospital, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, p.patient.ID, request.PatientID, "a merged patient erases the survivor")
+		assert.ElementsMatch(t, []string{p.patient.ID, p.merged.ID}, request.PatientIDs)
+
+		erased := new(model.Patient)
+		require.NoError(t, db.NewSelect().Model(erased).WhereAllWithDeleted().Where("id = ?", p.patient.ID).Scan(ctx))
+		assert.NotZero(t, erased.AnonymizedAt)
+		assert.Empty(t, erased.NationalID)
+		assert.NotNil(t, erased.DeletedAt)
+
+		entries := map[int64]*model.AuditLog{}
+		logs := []*model.AuditLog{}
+		require.NoError(t, db.NewSelect().Model(&logs).Where("hospital = ?", hospital).Scan(ctx))
+		for _, entry := range logs {
+			entries[entry.ID] = entry
+		}
+		assert.Equal(t, "/patient/search/"+redacted, entries[p.lookup.ID].Path)
+		assert.Equal(t, map[string][]string{"email": {redacted}}, entries[p.read.ID].Query)
+		assert.Equal(t, []string{p.patient.ID}, entries[p.read.ID].PatientIDs, "only the path and query change")
+		assert.Equal(t, p.other.Path, entries[p.other.ID].Path)
+
+		importError := new(model.ImportJobError)
+		require.NoError(t, db.NewSelect().Model(importError).Where("id = ?", p.importError.ID).Scan(ctx))
+		assert.Empty(t, importError.Identifier)
+	})
+
+	t.Run("Fail - Already erased", func(t *testing.T) {
+		hospital := testhelper.Hospital(t, db)
+		p := newPerson(t, db, hospital)
+		_, err := NewService(db).Erase(ctx, p.patient.ID, req, hospital, "")
+		require.NoError(t, err)
+
+		_, err = NewService(db).Erase(ctx, p.patient.ID, req, hospital, "")
+
+		require.Error(t, err)
+		assert.Equal(t, message.PatientAlreadyErased, err.Error())
+	})
+
+	t.Run("Fail - The audit log is not updated outside an erasure", func(t *testing.T) {
+		hospital := testhelper.Hospital(t, db)
+		p := newPerson(t, db, hospital)
+
+		_, err := db.NewUpdate().
+			Model((*model.AuditLog)(nil)).
+			Set("path = ?", redacted).
+			Where("id = ?", p.lookup.ID).
+			Exec(ctx)
+
+		assert.ErrorContains(t, err, "append-only")
+	})
+}
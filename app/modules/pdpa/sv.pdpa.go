@@ -0,0 +1,411 @@
+package pdpa
+
+import (
+	"app/app/enum"
+	"app/app/message"
+	"app/app/model"
+	"app/app/modules/patient"
+	patientdto "app/app/modules/patient/dto"
+	pdpadto "app/app/modules/pdpa/dto"
+	"app/app/util/fieldcrypt"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+type Service struct {
+	db *bun.DB
+	// Links relinks written patients, they are not linked when it is nil
+	Links patient.Linker
+}
+
+func NewService(db *bun.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// patients returns the patient of the hospital, deleted or not, and the
+// patients merged into it: the records of the person a request covers. A
+// patient merged into another stands for the survivor, which holds its data.
+func (s *Service) patients(ctx context.Context, db bun.IDB, id string, hospital string) (*model.Patient, []*model.Patient, error) {
+	p, err := s.patient(ctx, db, id, hospital)
+	if err != nil {
+		return nil, nil, err
+	}
+	// merging moves the patients merged into the duplicate to the survivor,
+	// so a survivor was never merged into another
+	if p.MergedInto != "" {
+		p, err = s.patient(ctx, db, p.MergedInto, hospital)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	merged := []*model.Patient{}
+	err = db.NewSelect().
+		Model(&merged).
+		Where("merged_into = ?", p.ID).
+		Where("hospital = ?", hospital).
+		WhereAllWithDeleted().
+		Order("created_at ASC", "id ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+	return p, merged, nil
+}
+
+// patient returns the patient of the hospital, deleted or not
+func (s *Service) patient(ctx context.Context, db bun.IDB, id string, hospital string) (*model.Patient, error) {
+	p := new(model.Patient)
+	err := db.NewSelect().
+		Model(p).
+		Where("id = ?", id).
+		Where("hospital = ?", hospital).
+		WhereAllWithDeleted().
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.PatientNotFound)
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func patientIDs(p *model.Patient, merged []*model.Patient) []string {
+	ids := []string{p.ID}
+	for _, m := range merged {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+// identifiers are the identifiers and contact details the patients are held
+// under, which requests may have named them by. Values that would not survive
+// being looked for in the JSON of a query are left out.
+func identifiers(p *model.Patient, merged []*model.Patient) []string {
+	values := []string{}
+	for _, patient := range append([]*model.Patient{p}, merged...) {
+		for _, value := range []fieldcrypt.EncryptedString{patient.NationalID, patient.PassportID, patient.PhoneNumber, patient.Email} {
+			if value != "" && !strings.ContainsAny(string(value), `"\`) && !slices.Contains(values, string(value)) {
+				values = append(values, string(value))
+			}
+		}
+	}
+	return values
+}
+
+// mentions is the condition of an audit entry whose path or query holds a
+// value, the lookups by identifier logged before the log kept route templates
+// and redacted queries
+const mentions = "strpos(path, ?) > 0 OR strpos(query::text, ?) > 0"
+
+// redacted replaces the identifiers of an erased patient in the audit log, as
+// the audit middleware replaces the query parameters it leaves out
+const redacted = "[redacted]"
+
+// importErrors returns the errors of the hospital's imports reported under a
+// national ID or passport of the patients. The identifiers are encrypted, they
+// are compared by blind index.
+func importErrors(ctx context.Context, db bun.IDB, hospital string, p *model.Patient, merged []*model.Patient) ([]*model.ImportJobError, error) {
+	indexes := map[string]bool{}
+	for _, patient := range append([]*model.Patient{p}, merged...) {
+		for _, index := range []string{patient.NationalIDIndex, patient.PassportIDIndex} {
+			if index != "" {
+				indexes[index] = true
+			}
+		}
+	}
+	resp := []*model.ImportJobError{}
+	if len(indexes) == 0 {
+		return resp, nil
+	}
+	all := []*model.ImportJobError{}
+	err := db.NewSelect().
+		Model(&all).
+		Where("identifier IS NOT NULL").
+		Where("job_id IN (?)", db.NewSelect().
+			Model((*model.ImportJob)(nil)).
+			Column("id").
+			Where("hospital = ?", hospital)).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for _, e := range all {
+		identifier := string(e.Identifier)
+		if indexes[model.PatientBlindIndex("national_id", identifier)] || indexes[model.PatientBlindIndex("passport_id", identifier)] {
+			resp = append(resp, e)
+		}
+	}
+	return resp, nil
+}
+
+// Report reports every piece of data held about the patient of the hospital
+// and records the access request
+func (s *Service) Report(ctx context.Context, id string, hospital, staffID string) (*pdpadto.Report, error) {
+	p, merged, err := s.patients(ctx, s.db, id, hospital)
+	if err != nil {
+		return nil, err
+	}
+	ids := patientIDs(p, merged)
+	report := &pdpadto.Report{
+		GeneratedAt:    time.Now().Unix(),
+		Patient:        patient.ToPatientDetail(p),
+		MergedPatients: make([]patientdto.PatientDetail, 0, len(merged)),
+		Merges:         []pdpadto.MergeRecord{},
+		Links:          []*model.PatientLink{},
+		RecordRequests: []*model.RecordRequest{},
+		Consents:       []*model.PatientConsent{},
+		AccessLog:      []*model.AuditLog{},
+		Requests:       []*model.PDPARequest{},
+	}
+	report.ImportErrors, err = importErrors(ctx, s.db, hospital, p, merged)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range merged {
+		report.MergedPatients = append(report.MergedPatients, patient.ToPatientDetail(m))
+	}
+
+	merges := []*model.PatientMerge{}
+	err = s.db.NewSelect().
+		Model(&merges).
+		Where("survivor_id IN (?) OR merged_id IN (?)", bun.In(ids), bun.In(ids)).
+		Order("created_at ASC", "id ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for _, merge := range merges {
+		record := pdpadto.MergeRecord{PatientMerge: merge}
+		if merge.Snapshot != "" && json.Valid([]byte(merge.Snapshot)) {
+			record.Snapshot = json.RawMessage(merge.Snapshot)
+		}
+		report.Merges = append(report.Merges, record)
+	}
+
+	queries := []*bun.SelectQuery{
+		s.db.NewSelect().
+			Model(&report.Links).
+			Where("patient_id IN (?)", bun.In(ids)),
+		s.db.NewSelect().
+			Model(&report.RecordRequests).
+			Where("patient_id IN (?) OR source_patient_id IN (?)", bun.In(ids), bun.In(ids)).
+			Order("created_at ASC", "id ASC"),
+		s.db.NewSelect().
+			Model(&report.Consents).
+			Where("patient_id IN (?)", bun.In(ids)).
+			Order("created_at ASC", "id ASC"),
+		s.db.NewSelect().
+			Model(&report.AccessLog).
+			WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				q.Where("patient_ids && ?::uuid[]", pgdialect.Array(ids))
+				for _, value := range identifiers(p, merged) {
+					q.WhereOr("hospital = ? AND ("+mentions+")", hospital, value, value)
+				}
+				return q
+			}).
+			Order("created_at ASC", "id ASC"),
+		s.db.NewSelect().
+			Model(&report.Requests).
+			Where("patient_id IN (?)", bun.In(ids)).
+			Order("created_at ASC", "id ASC"),
+	}
+	for _, query := range queries {
+		if err := query.Scan(ctx); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	// the entries name other patients of the same requests, they are not the subject's
+	for _, entry := range report.AccessLog {
+		entry.PatientIDs = slices.DeleteFunc(entry.PatientIDs, func(id string) bool {
+			return !slices.Contains(ids, id)
+		})
+	}
+
+	request := &model.PDPARequest{
+		Hospital:    hospital,
+		PatientID:   p.ID,
+		Type:        enum.PDPA_ACCESS,
+		PatientIDs:  ids,
+		RequestedBy: staffID,
+	}
+	_, err = s.db.NewInsert().
+		Model(request).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Requests = append(report.Requests, request)
+	return report, nil
+}
+
+// anonymized are the patient columns an erasure empties, the identifiers,
+// contact details and names together with their blind indexes and the HN
+var anonymized = []string{
+	"first_name_th", "middle_name_th", "last_name_th",
+	"first_name_en", "middle_name_en", "last_name_en",
+	"patient_hn", "national_id", "passport_id", "phone_number", "email",
+	"national_id_bidx", "passport_id_bidx", "phone_number_bidx", "email_bidx",
+	"synced_at",
+}
+
+// anonymize empties the anonymized columns of the patients, deleted or not,
+// keeps the year of birth only and soft-deletes them
+func anonymize(db bun.IDB, ids []string, now time.Time) *bun.UpdateQuery {
+	query := db.NewUpdate().
+		Model((*model.Patient)(nil)).
+		WhereAllWithDeleted().
+		Where("id IN (?)", bun.In(ids))
+	for _, column := range anonymized {
+		query.Set("? = NULL", bun.Ident(column))
+	}
+	return query.
+		Set("date_of_birth = DATE_TRUNC('year', date_of_birth)::date").
+		Set("anonymized_at = ?", now.Unix()).
+		Set("deleted_at = COALESCE(deleted_at, ?)", now).
+		Set("updated_at = ?", now.Unix())
+}
+
+// erasureReason is the withdrawal reason of the consents of erased patients
+const erasureReason = "Patient erased on a PDPA request"
+
+// Erase anonymizes the patient of the hospital and the patients merged into
+// it, a patient merged into another erases the survivor. The rows stay, so
+// everything referring to them holds, with the gender, hospital, creation time
+// and the year of birth left for statistics. The patients are soft-deleted,
+// leave the master patient index, their consents are withdrawn, pending
+// requests for their records denied and merge snapshots of them dropped. Their
+// identifiers are redacted from the paths and queries of the audit log and
+// from the import errors; the audit entries themselves stay until the
+// retention purge.
+func (s *Service) Erase(ctx context.Context, id string, req *pdpadto.ErasePatientRequest, hospital, staffID string) (*model.PDPARequest, error) {
+	request := &model.PDPARequest{
+		Hospital:    hospital,
+		PatientID:   id,
+		Type:        enum.PDPA_ERASURE,
+		Reason:      req.Reason,
+		RequestedBy: staffID,
+	}
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		p, merged, err := s.patients(ctx, tx, id, hospital)
+		if err != nil {
+			return err
+		}
+		if p.AnonymizedAt != 0 {
+			return errors.New(message.PatientAlreadyErased)
+		}
+		ids := patientIDs(p, merged)
+		request.PatientID = p.ID
+		request.PatientIDs = ids
+		now := time.Now()
+		// read before the identifiers are gone
+		values := identifiers(p, merged)
+		reported, err := importErrors(ctx, tx, hospital, p, merged)
+		if err != nil {
+			return err
+		}
+
+		if _, err := anonymize(tx, ids, now).Exec(ctx); err != nil {
+			return err
+		}
+
+		// only these transactions set app.audit_redact, which the append-only
+		// trigger of audit_logs requires for an update of the path and query
+		if len(values) > 0 {
+			if _, err := tx.ExecContext(ctx, "SET LOCAL app.audit_redact = 'on'"); err != nil {
+				return err
+			}
+		}
+		for _, value := range values {
+			_, err := tx.NewUpdate().
+				Model((*model.AuditLog)(nil)).
+				Set("path = replace(path, ?, ?)", value, redacted).
+				Set("query = replace(query::text, ?, ?)::jsonb", value, redacted).
+				Where("hospital = ?", hospital).
+				Where(mentions, value, value).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		if len(reported) > 0 {
+			_, err = tx.NewUpdate().
+				Model(&reported).
+				Set("identifier = NULL").
+				WherePK().
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*model.PatientMerge)(nil)).
+			Set("snapshot = NULL").
+			Where("merged_id IN (?)", bun.In(ids)).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		// the erased records leave their groups, the records linked through
+		// them alone are regrouped
+		if s.Links != nil {
+			if err := s.Links.Link(ctx, tx, ids...); err != nil {
+				return err
+			}
+		}
+		_, err = tx.NewDelete().
+			Model((*model.PatientLink)(nil)).
+			Where("patient_id IN (?)", bun.In(ids)).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.NewUpdate().
+			Model((*model.PatientConsent)(nil)).
+			Set("revoked_at = ?", now.Unix()).
+			Set("revoked_by = ?", sql.NullString{String: staffID, Valid: staffID != ""}).
+			Set("revoke_reason = ?", erasureReason).
+			Set("updated_at = ?", now.Unix()).
+			Where("patient_id IN (?)", bun.In(ids)).
+			Where("revoked_at IS NULL").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.NewUpdate().
+			Model((*model.RecordRequest)(nil)).
+			Set("status = ?", enum.RECORD_REQUEST_DENIED).
+			Set("decided_by = ?", sql.NullString{String: staffID, Valid: staffID != ""}).
+			Set("decided_at = ?", now.Unix()).
+			Set("updated_at = ?", now.Unix()).
+			Where("source_patient_id IN (?)", bun.In(ids)).
+			Where("status = ?", enum.RECORD_REQUEST_PENDING).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewInsert().
+			Model(request).
+			Returning("*").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
@@ -1,7 +1,11 @@
 package staff
 
 import (
+	"app/app/enum"
+	"app/app/helper"
+	"app/app/message"
 	staffdto "app/app/modules/staff/dto"
+	"app/app/util/jwt"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -11,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -20,14 +25,26 @@ type StaffMockService struct {
 	mock.Mock
 }
 
-func (m *StaffMockService) Create(ctx context.Context, req *staffdto.CreateStaffRequest) error {
-	args := m.Called(ctx, req)
+func (m *StaffMockService) Create(ctx context.Context, req *staffdto.CreateStaffRequest, granted []string) error {
+	args := m.Called(ctx, req, granted)
 	return args.Error(0)
 }
 
-func (m *StaffMockService) Login(ctx context.Context, req *staffdto.LoginStaffRequest) (string, error) {
+func (m *StaffMockService) Login(ctx context.Context, req *staffdto.LoginStaffRequest) (*staffdto.TokenResponse, error) {
+	args := m.Called(ctx, req)
+	token, _ := args.Get(0).(*staffdto.TokenResponse)
+	return token, args.Error(1)
+}
+
+func (m *StaffMockService) Refresh(ctx context.Context, req *staffdto.RefreshTokenRequest) (*staffdto.TokenResponse, error) {
 	args := m.Called(ctx, req)
-	return args.String(0), args.Error(1)
+	token, _ := args.Get(0).(*staffdto.TokenResponse)
+	return token, args.Error(1)
+}
+
+func (m *StaffMockService) Logout(ctx context.Context, claims *jwt.Claims) error {
+	args := m.Called(ctx, claims)
+	return args.Error(0)
 }
 
 func (m *StaffMockService) ExistUsername(ctx context.Context, username string) (bool, error) {
@@ -35,6 +52,11 @@ func (m *StaffMockService) ExistUsername(ctx context.Context, username string) (
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *StaffMockService) BootstrapAdmin(ctx context.Context, req *staffdto.BootstrapAdminRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
 // Helper function to create mock context
 func createStaffMockContext(method, url string, body interface{}) (*gin.Context, *httptest.ResponseRecorder) {
 	gin.SetMode(gin.TestMode)
@@ -54,8 +76,26 @@ func createStaffMockContext(method, url string, body interface{}) (*gin.Context,
 	return c, w
 }
 
+func createStaffMockContextWithClaims(method, url string, body interface{}, claims *jwt.Claims) (*gin.Context, *httptest.ResponseRecorder) {
+	c, w := createStaffMockContext(method, url, body)
+	if claims != nil {
+		helper.SetUserInClaims(c, claims)
+	}
+	return c, w
+}
+
 // 🎯 Staff Controller Tests - Success & Fail Only
 func TestStaffController_Create(t *testing.T) {
+	adminClaims := &jwt.Claims{
+		Data: jwt.ClaimData{
+			ID:          "admin-1",
+			Username:    "admin",
+			Hospital:    "hospital-a",
+			Role:        string(enum.ROLE_ADMIN),
+			Permissions: []string{string(enum.PERMISSION_STAFF_MANAGE)},
+		},
+	}
+
 	t.Run("Success - Create Staff", func(t *testing.T) {
 		// Setup
 		mockService := new(StaffMockService)
@@ -65,12 +105,12 @@ func TestStaffController_Create(t *testing.T) {
 			Hospital: "hospital-a",
 		}
 		
-		mockService.On("Create", mock.Anything, createReq).Return(nil)
+		mockService.On("Create", mock.Anything, createReq, adminClaims.Data.Permissions).Return(nil)
 
 		controller := NewController(mockService)
 
 		// Execute
-		c, w := createStaffMockContext("POST", "/staff", createReq)
+		c, w := createStaffMockContextWithClaims("POST", "/staff", createReq, adminClaims)
 		controller.Create(c)
 
 		// Assert
@@ -88,12 +128,12 @@ func TestStaffController_Create(t *testing.T) {
 			Hospital: "hospital-a",
 		}
 		
-		mockService.On("Create", mock.Anything, createReq).Return(errors.New("database error"))
+		mockService.On("Create", mock.Anything, createReq, adminClaims.Data.Permissions).Return(errors.New("database error"))
 
 		controller := NewController(mockService)
 
 		// Execute
-		c, w := createStaffMockContext("POST", "/staff", createReq)
+		c, w := createStaffMockContextWithClaims("POST", "/staff", createReq, adminClaims)
 		controller.Create(c)
 
 		// Assert
@@ -108,13 +148,160 @@ func TestStaffController_Create(t *testing.T) {
 		controller := NewController(mockService)
 
 		// Execute - empty request body
-		c, w := createStaffMockContext("POST", "/staff", map[string]string{})
+		c, w := createStaffMockContextWithClaims("POST", "/staff", map[string]string{}, adminClaims)
 		controller.Create(c)
 
 		// Assert
 		assert.Equal(t, 400, w.Code)
 		t.Log("❌ PASS: Invalid request body returned status 400")
 	})
+
+	t.Run("Fail - Other Hospital", func(t *testing.T) {
+		// Setup
+		viper.Set("HTTP_JSON_NAMING", "snake_case")
+		t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+		mockService := new(StaffMockService)
+		createReq := &staffdto.CreateStaffRequest{
+			Username: "testuser",
+			Password: "password123",
+			Hospital: "hospital-b",
+		}
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContextWithClaims("POST", "/staff", createReq, adminClaims)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 403, w.Code)
+		t.Log("❌ PASS: Creating staff for another hospital returned status 403")
+		mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Fail - Inactive Hospital", func(t *testing.T) {
+		// Setup
+		viper.Set("HTTP_JSON_NAMING", "snake_case")
+		t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+		mockService := new(StaffMockService)
+		createReq := &staffdto.CreateStaffRequest{
+			Username: "testuser",
+			Password: "password123",
+			Hospital: "hospital-a",
+		}
+		mockService.On("Create", mock.Anything, createReq, adminClaims.Data.Permissions).Return(errors.New(message.HospitalInactive))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContextWithClaims("POST", "/staff", createReq, adminClaims)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		t.Log("❌ PASS: Creating staff for an inactive hospital returned status 400")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Username Taken", func(t *testing.T) {
+		// Setup
+		viper.Set("HTTP_JSON_NAMING", "snake_case")
+		t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+		mockService := new(StaffMockService)
+		createReq := &staffdto.CreateStaffRequest{
+			Username: "testuser",
+			Password: "password123",
+			Hospital: "hospital-a",
+		}
+		mockService.On("Create", mock.Anything, createReq, adminClaims.Data.Permissions).Return(errors.New(message.StaffAlreadyExists))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContextWithClaims("POST", "/staff", createReq, adminClaims)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), message.StaffAlreadyExists)
+		t.Log("❌ PASS: Creating staff with a taken username returned status 400")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Unknown Role", func(t *testing.T) {
+		// Setup
+		viper.Set("HTTP_JSON_NAMING", "snake_case")
+		t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+		mockService := new(StaffMockService)
+		createReq := &staffdto.CreateStaffRequest{
+			Username: "testuser",
+			Password: "password123",
+			Hospital: "hospital-a",
+			Role:     "surgeon",
+		}
+		mockService.On("Create", mock.Anything, createReq, adminClaims.Data.Permissions).Return(errors.New(message.RoleNotFound))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContextWithClaims("POST", "/staff", createReq, adminClaims)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), message.RoleNotFound)
+		t.Log("❌ PASS: Creating staff with an unknown role returned status 400")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Role Beyond The Caller's Permissions", func(t *testing.T) {
+		// Setup
+		viper.Set("HTTP_JSON_NAMING", "snake_case")
+		t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+		mockService := new(StaffMockService)
+		createReq := &staffdto.CreateStaffRequest{
+			Username: "testuser",
+			Password: "password123",
+			Hospital: "hospital-a",
+			Role:     string(enum.ROLE_SYSTEM_ADMIN),
+		}
+		mockService.On("Create", mock.Anything, createReq, adminClaims.Data.Permissions).Return(errors.New(message.StaffRoleForbidden))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContextWithClaims("POST", "/staff", createReq, adminClaims)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 403, w.Code)
+		assert.Contains(t, w.Body.String(), message.StaffRoleForbidden)
+		t.Log("❌ PASS: Creating staff with a role beyond the caller's permissions returned status 403")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Unauthorized", func(t *testing.T) {
+		// Setup
+		viper.Set("HTTP_JSON_NAMING", "snake_case")
+		t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+		mockService := new(StaffMockService)
+		createReq := &staffdto.CreateStaffRequest{
+			Username: "testuser",
+			Password: "password123",
+			Hospital: "hospital-a",
+		}
+
+		controller := NewController(mockService)
+
+		// Execute - no claims in context
+		c, w := createStaffMockContext("POST", "/staff", createReq)
+		controller.Create(c)
+
+		// Assert
+		assert.Equal(t, 401, w.Code)
+		t.Log("❌ PASS: Anonymous staff creation returned status 401")
+		mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestStaffController_Login(t *testing.T) {
@@ -129,7 +316,10 @@ func TestStaffController_Login(t *testing.T) {
 			},
 		}
 		
-		mockToken := "eyJhbGciOiJIUzUxMiIsInR5cCI6IkpXVCJ9..."
+		mockToken := &staffdto.TokenResponse{
+			AccessToken:  "eyJhbGciOiJIUzUxMiIsInR5cCI6IkpXVCJ9...",
+			RefreshToken: "refresh-token",
+		}
 		mockService.On("Login", mock.Anything, loginReq).Return(mockToken, nil)
 
 		controller := NewController(mockService)
@@ -155,7 +345,7 @@ func TestStaffController_Login(t *testing.T) {
 			},
 		}
 		
-		mockService.On("Login", mock.Anything, loginReq).Return("", errors.New("invalid credentials"))
+		mockService.On("Login", mock.Anything, loginReq).Return(nil, errors.New("invalid credentials"))
 
 		controller := NewController(mockService)
 
@@ -184,14 +374,116 @@ func TestStaffController_Login(t *testing.T) {
 	})
 }
 
+func TestStaffController_Refresh(t *testing.T) {
+	t.Run("Success - Refresh Token", func(t *testing.T) {
+		// Setup
+		mockService := new(StaffMockService)
+		refreshReq := &staffdto.RefreshTokenRequest{RefreshToken: "refresh-token"}
+		mockToken := &staffdto.TokenResponse{
+			AccessToken:  "eyJhbGciOiJIUzUxMiIsInR5cCI6IkpXVCJ9...",
+			RefreshToken: "next-refresh-token",
+		}
+		mockService.On("Refresh", mock.Anything, refreshReq).Return(mockToken, nil)
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContext("POST", "/staff/refresh", refreshReq)
+		controller.Refresh(c)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Invalid Refresh Token", func(t *testing.T) {
+		viper.Set("HTTP_JSON_NAMING", "snake_case")
+		t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+
+		// Setup
+		mockService := new(StaffMockService)
+		refreshReq := &staffdto.RefreshTokenRequest{RefreshToken: "reused-token"}
+		mockService.On("Refresh", mock.Anything, refreshReq).Return(nil, errors.New(message.InvalidRefreshToken))
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContext("POST", "/staff/refresh", refreshReq)
+		controller.Refresh(c)
+
+		// Assert
+		assert.Equal(t, 401, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - Invalid Request Body", func(t *testing.T) {
+		// Setup
+		mockService := new(StaffMockService)
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContext("POST", "/staff/refresh", map[string]string{})
+		controller.Refresh(c)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+	})
+}
+
+func TestStaffController_Logout(t *testing.T) {
+	claims := &jwt.Claims{
+		Data: jwt.ClaimData{
+			ID:       "staff-1",
+			Username: "nurse",
+			Hospital: "hospital-a",
+			Role:     string(enum.ROLE_NURSE),
+		},
+	}
+
+	t.Run("Success - Logout Staff", func(t *testing.T) {
+		// Setup
+		mockService := new(StaffMockService)
+		mockService.On("Logout", mock.Anything, claims).Return(nil)
+
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContextWithClaims("POST", "/staff/logout", nil, claims)
+		controller.Logout(c)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Fail - No Token", func(t *testing.T) {
+		viper.Set("HTTP_JSON_NAMING", "snake_case")
+		t.Cleanup(func() { viper.Set("HTTP_JSON_NAMING", nil) })
+
+		// Setup
+		mockService := new(StaffMockService)
+		controller := NewController(mockService)
+
+		// Execute
+		c, w := createStaffMockContext("POST", "/staff/logout", nil)
+		controller.Logout(c)
+
+		// Assert
+		assert.Equal(t, 401, w.Code)
+		mockService.AssertNotCalled(t, "Logout", mock.Anything, mock.Anything)
+	})
+}
+
 // 📊 Test Summary
 func TestStaffController_Summary(t *testing.T) {
 	t.Log("🧪 Staff Controller Test Summary")
 	t.Log("===================================")
 	t.Log("✅ Create Staff - Success Cases")
-	t.Log("❌ Create Staff - Fail Cases")
+	t.Log("❌ Create Staff - Fail Cases (incl. other hospital, no token)")
 	t.Log("✅ Login Staff - Success Cases")
 	t.Log("❌ Login Staff - Fail Cases")
+	t.Log("✅ Refresh / Logout - Success Cases")
+	t.Log("❌ Refresh / Logout - Fail Cases (incl. reused token)")
 	t.Log("🎯 Focus: Success/Fail scenarios only")
 	t.Log("📁 File: ctl.staff.test.go")
 }
@@ -1,6 +1,7 @@
 package staff
 
 import (
+	"app/app/helper"
 	"app/app/message"
 	staffdto "app/app/modules/staff/dto"
 	"app/app/response"
@@ -26,10 +27,28 @@ func (c *Controller) Create(ctx *gin.Context) {
 		response.BadRequest(ctx, message.InvalidRequest, nil)
 		return
 	}
-	err := c.Service.Create(ctx, req)
+	// admins can only add staff to their own hospital
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	if req.Hospital != user.Data.Hospital {
+		response.Forbidden(ctx, message.StaffHospitalMismatch, nil)
+		return
+	}
+	// nor hand out permissions they do not hold themselves
+	err := c.Service.Create(ctx, req, user.Data.Permissions)
 	if err != nil {
 		logger.Err(err)
-		response.InternalError(ctx, err.Error(), nil)
+		switch err.Error() {
+		case message.StaffRoleForbidden:
+			response.Forbidden(ctx, err.Error(), nil)
+		case message.HospitalNotFound, message.HospitalInactive,
+			message.StaffAlreadyExists, message.RoleNotFound:
+			response.BadRequest(ctx, err.Error(), nil)
+		default:
+			response.InternalError(ctx, err.Error(), nil)
+		}
 		return
 	}
 	response.Success(ctx, nil)
@@ -45,8 +64,49 @@ func (c *Controller) Login(ctx *gin.Context) {
 	token, err := c.Service.Login(ctx, req)
 	if err != nil {
 		logger.Err(err)
+		if err.Error() == message.HospitalInactive || err.Error() == message.HospitalNotFound {
+			response.Forbidden(ctx, message.HospitalInactive, nil)
+			return
+		}
 		response.InternalError(ctx, err.Error(), nil)
 		return
 	}
 	response.Success(ctx, token)
 }
+
+func (c *Controller) Refresh(ctx *gin.Context) {
+	req := new(staffdto.RefreshTokenRequest)
+	if err := ctx.Bind(req); err != nil {
+		logger.Err(err)
+		response.BadRequest(ctx, message.InvalidRequest, nil)
+		return
+	}
+	token, err := c.Service.Refresh(ctx, req)
+	if err != nil {
+		logger.Err(err)
+		switch err.Error() {
+		case message.InvalidRefreshToken, message.StaffNotFound:
+			response.Unauthorized(ctx, message.InvalidRefreshToken, nil)
+			return
+		case message.HospitalInactive, message.HospitalNotFound:
+			response.Forbidden(ctx, message.HospitalInactive, nil)
+			return
+		}
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	response.Success(ctx, token)
+}
+
+func (c *Controller) Logout(ctx *gin.Context) {
+	user, ok := helper.CurrentStaff(ctx)
+	if !ok {
+		return
+	}
+	if err := c.Service.Logout(ctx, user); err != nil {
+		logger.Err(err)
+		response.InternalError(ctx, err.Error(), nil)
+		return
+	}
+	response.Success(ctx, nil)
+}
@@ -4,8 +4,29 @@ type CreateStaffRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 	Hospital string `json:"hospital" binding:"required"`
+	Role     string `json:"role"`
 }
 
 type LoginStaffRequest struct {
 	CreateStaffRequest
 }
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresAt        int64  `json:"expires_at"`
+	RefreshExpiresAt int64  `json:"refresh_expires_at"`
+}
+
+// BootstrapAdminRequest creates the first admin of a hospital from the CLI,
+// or the first system admin when System is set
+type BootstrapAdminRequest struct {
+	Username string
+	Password string
+	Hospital string
+	System   bool
+}
@@ -2,14 +2,18 @@ package staff
 
 import (
 	staffdto "app/app/modules/staff/dto"
+	"app/app/util/jwt"
 	"context"
 )
 
 // ServiceInterface defines the interface for staff service operations
 type ServiceInterface interface {
-	Create(ctx context.Context, req *staffdto.CreateStaffRequest) error
-	Login(ctx context.Context, req *staffdto.LoginStaffRequest) (string, error)
+	Create(ctx context.Context, req *staffdto.CreateStaffRequest, granted []string) error
+	Login(ctx context.Context, req *staffdto.LoginStaffRequest) (*staffdto.TokenResponse, error)
+	Refresh(ctx context.Context, req *staffdto.RefreshTokenRequest) (*staffdto.TokenResponse, error)
+	Logout(ctx context.Context, claims *jwt.Claims) error
 	ExistUsername(ctx context.Context, username string) (bool, error)
+	BootstrapAdmin(ctx context.Context, req *staffdto.BootstrapAdminRequest) error
 }
 
 var _ ServiceInterface = (*Service)(nil)
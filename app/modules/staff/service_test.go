@@ -0,0 +1,82 @@
+package staff
+
+import (
+	"app/app/enum"
+	"app/app/helper/testhelper"
+	"app/app/message"
+	"app/app/model"
+	staffdto "app/app/modules/staff/dto"
+	"app/database/rbac"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Create(t *testing.T) {
+	ctx := context.Background()
+	db := testhelper.DB(t)
+	require.NoError(t, rbac.Sync(ctx, db))
+	svc := NewService(db)
+	admin := []string{}
+	for _, permission := range enum.DefaultRolePermissions()[enum.ROLE_ADMIN] {
+		admin = append(admin, string(permission))
+	}
+	request := func(hospital string, role enum.Role) *staffdto.CreateStaffRequest {
+		return &staffdto.CreateStaffRequest{
+			Username: "staff-" + uuid.NewString()[:8],
+			Password: "password123",
+			Hospital: hospital,
+			Role:     string(role),
+		}
+	}
+
+	t.Run("Success - Role Within The Caller's Permissions", func(t *testing.T) {
+		req := request(testhelper.Hospital(t, db), enum.ROLE_DOCTOR)
+
+		require.NoError(t, svc.Create(ctx, req, admin))
+
+		staff, err := svc.GetStaffByUsername(ctx, req.Username)
+		require.NoError(t, err)
+		assert.Equal(t, string(enum.ROLE_DOCTOR), staff.Role)
+	})
+
+	t.Run("Fail - System Admin", func(t *testing.T) {
+		req := request(testhelper.Hospital(t, db), enum.ROLE_SYSTEM_ADMIN)
+		granted := append([]string{string(enum.PERMISSION_HOSPITAL_MANAGE)}, admin...)
+
+		err := svc.Create(ctx, req, granted)
+
+		require.Error(t, err)
+		assert.Equal(t, message.StaffRoleForbidden, err.Error())
+		exists, err := svc.ExistUsername(ctx, req.Username)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("Fail - Role Beyond The Caller's Permissions", func(t *testing.T) {
+		req := request(testhelper.Hospital(t, db), enum.ROLE_ADMIN)
+
+		err := svc.Create(ctx, req, []string{string(enum.PERMISSION_STAFF_MANAGE)})
+
+		require.Error(t, err)
+		assert.Equal(t, message.StaffRoleForbidden, err.Error())
+	})
+
+	t.Run("Success - Bootstrap Still Creates The First Admin", func(t *testing.T) {
+		hospital := testhelper.Hospital(t, db)
+		req := &staffdto.BootstrapAdminRequest{Username: "admin-" + uuid.NewString()[:8], Password: "password123", Hospital: hospital}
+
+		require.NoError(t, svc.BootstrapAdmin(ctx, req))
+
+		exists, err := db.NewSelect().
+			Model((*model.Staff)(nil)).
+			Where("hospital = ?", hospital).
+			Where("role = ?", enum.ROLE_ADMIN).
+			Exists(ctx)
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+}
@@ -1,6 +1,7 @@
 package staff
 
 import (
+	"app/app/enum"
 	"app/app/message"
 	"app/app/model"
 	staffdto "app/app/modules/staff/dto"
@@ -9,7 +10,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
@@ -23,7 +27,30 @@ func NewService(db *bun.DB) *Service {
 	}
 }
 
-func (s *Service) Create(ctx context.Context, req *staffdto.CreateStaffRequest) error {
+// Create adds staff with a role whose permissions are all in granted, the
+// permissions of the staff creating it. A system admin is never created here,
+// see BootstrapAdmin.
+func (s *Service) Create(ctx context.Context, req *staffdto.CreateStaffRequest, granted []string) error {
+	role := req.Role
+	if role == "" {
+		role = string(enum.ROLE_READ_ONLY)
+	}
+	if role == string(enum.ROLE_SYSTEM_ADMIN) {
+		return errors.New(message.StaffRoleForbidden)
+	}
+	permissions, err := s.GetPermissions(ctx, role)
+	if err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return errors.New(message.StaffRoleForbidden)
+		}
+	}
+	return s.create(ctx, req)
+}
+
+func (s *Service) create(ctx context.Context, req *staffdto.CreateStaffRequest) error {
 	// Check if username already exists
 	exists, err := s.ExistUsername(ctx, req.Username)
 	if err != nil {
@@ -32,6 +59,20 @@ func (s *Service) Create(ctx context.Context, req *staffdto.CreateStaffRequest)
 	if exists {
 		return errors.New(message.StaffAlreadyExists)
 	}
+	if err := s.CheckHospital(ctx, s.db, req.Hospital); err != nil {
+		return err
+	}
+	role := req.Role
+	if role == "" {
+		role = string(enum.ROLE_READ_ONLY)
+	}
+	roleExists, err := s.ExistRole(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !roleExists {
+		return errors.New(message.RoleNotFound)
+	}
 	//hashpassword
 	hash, err := hashing.HashPassword(req.Password)
 	if err != nil {
@@ -42,6 +83,7 @@ func (s *Service) Create(ctx context.Context, req *staffdto.CreateStaffRequest)
 		Username: req.Username,
 		Password: string(hash),
 		Hospital: req.Hospital,
+		Role:     role,
 	}
 	_, err = s.db.NewInsert().
 		Model(data).
@@ -52,6 +94,54 @@ func (s *Service) Create(ctx context.Context, req *staffdto.CreateStaffRequest)
 	return nil
 }
 
+// BootstrapAdmin creates the first admin of a hospital and refuses once the hospital has one.
+// A system admin is refused once any system admin exists.
+func (s *Service) BootstrapAdmin(ctx context.Context, req *staffdto.BootstrapAdminRequest) error {
+	role := enum.ROLE_ADMIN
+	query := s.db.NewSelect().
+		Model((*model.Staff)(nil))
+	if req.System {
+		role = enum.ROLE_SYSTEM_ADMIN
+	} else {
+		query.Where("hospital = ?", req.Hospital)
+	}
+	exists, err := query.
+		Where("role = ?", role).
+		Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New(message.HospitalAdminExists)
+	}
+	return s.create(ctx, &staffdto.CreateStaffRequest{
+		Username: req.Username,
+		Password: req.Password,
+		Hospital: req.Hospital,
+		Role:     string(role),
+	})
+}
+
+// CheckHospital fails unless the hospital exists and is active
+func (s *Service) CheckHospital(ctx context.Context, db bun.IDB, code string) error {
+	hospital := new(model.Hospital)
+	err := db.NewSelect().
+		Model(hospital).
+		Column("status").
+		Where("code = ?", code).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New(message.HospitalNotFound)
+		}
+		return err
+	}
+	if hospital.Status != enum.STATUS_ACTIVE {
+		return errors.New(message.HospitalInactive)
+	}
+	return nil
+}
+
 func (s *Service) ExistUsername(ctx context.Context, username string) (bool, error) {
 	ex, err := s.db.NewSelect().
 		Model((*model.Staff)(nil)).
@@ -60,6 +150,25 @@ func (s *Service) ExistUsername(ctx context.Context, username string) (bool, err
 	return ex, err
 }
 
+func (s *Service) ExistRole(ctx context.Context, role string) (bool, error) {
+	return s.db.NewSelect().
+		Model((*model.Role)(nil)).
+		Where("code = ?", role).
+		Exists(ctx)
+}
+
+// GetPermissions returns the permission codes granted to the role
+func (s *Service) GetPermissions(ctx context.Context, role string) ([]string, error) {
+	permissions := []string{}
+	err := s.db.NewSelect().
+		Model((*model.RolePermission)(nil)).
+		Column("permission").
+		Where("role = ?", role).
+		Order("permission ASC").
+		Scan(ctx, &permissions)
+	return permissions, err
+}
+
 func (s *Service) GetStaffByUsername(ctx context.Context, username string) (*model.Staff, error) {
 	staff := new(model.Staff)
 	err := s.db.NewSelect().
@@ -75,31 +184,252 @@ func (s *Service) GetStaffByUsername(ctx context.Context, username string) (*mod
 	return staff, nil
 }
 
-func (s *Service) Login(ctx context.Context, req *staffdto.LoginStaffRequest) (string, error) {
+func (s *Service) GetStaffByID(ctx context.Context, db bun.IDB, id string) (*model.Staff, error) {
+	staff := new(model.Staff)
+	err := db.NewSelect().
+		Model(staff).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(message.StaffNotFound)
+		}
+		return nil, err
+	}
+	return staff, nil
+}
+
+func (s *Service) Login(ctx context.Context, req *staffdto.LoginStaffRequest) (*staffdto.TokenResponse, error) {
 	// Find staff by username
 	staff, err := s.GetStaffByUsername(ctx, req.Username)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	// Verify password
 	if !hashing.CheckPasswordHash(staff.Password, req.Password) {
-		return "", errors.New(message.InvalidCredentials)
+		return nil, errors.New(message.InvalidCredentials)
 	}
 
 	// Verify hospital
 	if staff.Hospital != req.Hospital {
-		return "", errors.New(message.InvalidCredentials)
+		return nil, errors.New(message.InvalidCredentials)
+	}
+	if err := s.CheckHospital(ctx, s.db, staff.Hospital); err != nil {
+		return nil, err
+	}
+
+	//Create tokens, a login starts a new refresh token family
+	resp, _, err := s.issueTokens(ctx, s.db, staff, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already rotated
+// means it leaked, so the whole family and its access tokens are revoked.
+func (s *Service) Refresh(ctx context.Context, req *staffdto.RefreshTokenRequest) (*staffdto.TokenResponse, error) {
+	var (
+		resp   *staffdto.TokenResponse
+		reused bool
+	)
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		current := new(model.RefreshToken)
+		err := tx.NewSelect().
+			Model(current).
+			Where("token_hash = ?", jwt.HashRefreshToken(req.RefreshToken)).
+			For("UPDATE").
+			Scan(ctx)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New(message.InvalidRefreshToken)
+			}
+			return err
+		}
+		if current.RevokedAt != 0 {
+			reused = true
+			return s.revokeFamily(ctx, tx, current.FamilyID)
+		}
+		if current.ExpiresAt <= time.Now().Unix() {
+			return errors.New(message.InvalidRefreshToken)
+		}
+
+		// reload the staff so role and permission changes reach the new token
+		staff, err := s.GetStaffByID(ctx, tx, current.StaffID)
+		if err != nil {
+			return err
+		}
+		if err := s.CheckHospital(ctx, tx, staff.Hospital); err != nil {
+			return err
+		}
+		var next *model.RefreshToken
+		resp, next, err = s.issueTokens(ctx, tx, staff, current.FamilyID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.NewUpdate().
+			Model(current).
+			Set("revoked_at = ?", time.Now().Unix()).
+			Set("replaced_by = ?", next.ID).
+			Set("updated_at = EXTRACT(EPOCH FROM NOW())").
+			WherePK().
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, errors.New(message.InvalidRefreshToken)
+	}
+	return resp, nil
+}
+
+// Logout revokes the access token and the refresh token family it was issued with
+func (s *Service) Logout(ctx context.Context, claims *jwt.Claims) error {
+	return s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		revoked := &model.RevokedToken{
+			Uuid:      claims.Uuid,
+			StaffID:   claims.Data.ID,
+			ExpiresAt: claims.ExpiresAt.Unix(),
+		}
+		_, err := tx.NewInsert().
+			Model(revoked).
+			On("CONFLICT DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		familyID := ""
+		err = tx.NewSelect().
+			Model((*model.RefreshToken)(nil)).
+			Column("family_id").
+			Where("access_uuid = ?", claims.Uuid).
+			Limit(1).
+			Scan(ctx, &familyID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s.revokeFamily(ctx, tx, familyID)
+	})
+}
+
+// IsRevoked tells whether the access token was revoked by a logout or a reused refresh token
+func (s *Service) IsRevoked(ctx context.Context, uuid string) (bool, error) {
+	return s.db.NewSelect().
+		Model((*model.RevokedToken)(nil)).
+		Where("uuid = ?", uuid).
+		Exists(ctx)
+}
+
+// PurgeExpiredTokens deletes tokens that can no longer be used
+func (s *Service) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	now := time.Now().Unix()
+	var total int64
+	for _, table := range []any{(*model.RefreshToken)(nil), (*model.RevokedToken)(nil)} {
+		res, err := s.db.NewDelete().
+			Model(table).
+			Where("expires_at < ?", now).
+			Exec(ctx)
+		if err != nil {
+			return total, err
+		}
+		affected, _ := res.RowsAffected()
+		total += affected
+	}
+	return total, nil
+}
+
+func (s *Service) issueTokens(ctx context.Context, db bun.IDB, staff *model.Staff, familyID string) (*staffdto.TokenResponse, *model.RefreshToken, error) {
+	permissions, err := s.GetPermissions(ctx, staff.Role)
+	if err != nil {
+		return nil, nil, err
 	}
 	claim := jwt.ClaimData{
-		ID:       staff.ID,
-		Username: staff.Username,
-		Hospital: staff.Hospital,
+		ID:          staff.ID,
+		Username:    staff.Username,
+		Hospital:    staff.Hospital,
+		Role:        staff.Role,
+		Permissions: permissions,
+	}
+	accessToken, claims, err := jwt.CreateToken(claim)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, hash, err := jwt.NewRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	data := &model.RefreshToken{
+		StaffID:    staff.ID,
+		FamilyID:   familyID,
+		TokenHash:  hash,
+		AccessUuid: claims.Uuid,
+		ExpiresAt:  jwt.GenerateRefreshExpires().Unix(),
+	}
+	_, err = db.NewInsert().
+		Model(data).
+		Returning("id").
+		Exec(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
-	//Create token
-	token, _, err := jwt.CreateToken(claim)
+
+	return &staffdto.TokenResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresAt:        claims.ExpiresAt.Unix(),
+		RefreshExpiresAt: data.ExpiresAt,
+	}, data, nil
+}
+
+// revokeFamily revokes every refresh token of the family and blocks the access
+// tokens issued with them until they would have expired anyway
+func (s *Service) revokeFamily(ctx context.Context, db bun.IDB, familyID string) error {
+	tokens := []*model.RefreshToken{}
+	err := db.NewSelect().
+		Model(&tokens).
+		Where("family_id = ?", familyID).
+		Scan(ctx)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return token, nil
+	now := time.Now().Unix()
+	_, err = db.NewUpdate().
+		Model((*model.RefreshToken)(nil)).
+		Set("revoked_at = ?", now).
+		Set("updated_at = EXTRACT(EPOCH FROM NOW())").
+		Where("family_id = ?", familyID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	accessDuration := int64(jwt.GenerateExpires().Sub(time.Now()).Seconds())
+	for _, token := range tokens {
+		expiresAt := token.CreatedAt + accessDuration
+		if expiresAt <= now {
+			continue
+		}
+		revoked := &model.RevokedToken{
+			Uuid:      token.AccessUuid,
+			StaffID:   token.StaffID,
+			ExpiresAt: expiresAt,
+		}
+		_, err := db.NewInsert().
+			Model(revoked).
+			On("CONFLICT DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
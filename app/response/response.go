@@ -209,6 +209,42 @@ func SuccessWithPaginate(ctx *gin.Context, data any, page, size, count int) {
 
 }
 
+// CursorPagination describes a cursor paginated page, Next and Prev are empty at
+// the ends of the list and Total is only set when it was asked for
+type CursorPagination struct {
+	Size  int    `json:"size"`
+	Next  string `json:"next"`
+	Prev  string `json:"prev"`
+	Total *int   `json:"total,omitempty"`
+}
+
+func SuccessWithCursor(ctx *gin.Context, data any, pagination CursorPagination) {
+	response := ResponsePaginate0{
+		Code:       200,
+		Message:    "Success",
+		Data:       data,
+		Pagination: pagination,
+	}
+
+	marshalled := NewConventionalMarshaller(response)
+	ctx.JSON(http.StatusOK, marshalled)
+}
+
+// PageSize keeps a requested page size between 1 and PAGINATION_MAX_SIZE
+func PageSize(size int) int {
+	max := viper.GetInt("PAGINATION_MAX_SIZE")
+	if max < 1 {
+		max = 100
+	}
+	switch {
+	case size < 1:
+		return 1
+	case size > max:
+		return max
+	}
+	return size
+}
+
 func Forbidden(ctx *gin.Context, message any, data any) {
 	response := Response{
 		Code:    403,
@@ -219,3 +255,36 @@ func Forbidden(ctx *gin.Context, message any, data any) {
 	marshalled := NewConventionalMarshaller(response)
 	ctx.JSON(http.StatusForbidden, marshalled)
 }
+
+func BadGateway(ctx *gin.Context, message any, data any) {
+	response := Response{
+		Code:    502,
+		Message: message.(string), // Set the message directly here
+		Data:    data,
+	}
+
+	marshalled := NewConventionalMarshaller(response)
+	ctx.JSON(http.StatusBadGateway, marshalled)
+}
+
+func ServiceUnavailable(ctx *gin.Context, message any, data any) {
+	response := Response{
+		Code:    503,
+		Message: message.(string), // Set the message directly here
+		Data:    data,
+	}
+
+	marshalled := NewConventionalMarshaller(response)
+	ctx.JSON(http.StatusServiceUnavailable, marshalled)
+}
+
+func GatewayTimeout(ctx *gin.Context, message any, data any) {
+	response := Response{
+		Code:    504,
+		Message: message.(string), // Set the message directly here
+		Data:    data,
+	}
+
+	marshalled := NewConventionalMarshaller(response)
+	ctx.JSON(http.StatusGatewayTimeout, marshalled)
+}
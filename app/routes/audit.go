@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"app/app/enum"
+	"app/app/middleware"
+	"app/app/modules"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Audit(router *gin.RouterGroup) {
+	module := modules.New()
+	amd := middleware.AuthMiddleware(module.Staff.Svc)
+	audit := middleware.Audit(module.Audit.Svc)
+	group := router.Group("", amd, audit(enum.AUDIT_LOG_READ), middleware.RequirePermission(enum.PERMISSION_AUDIT_READ))
+	{
+		group.GET("", module.Audit.Ctl.List)
+	}
+}
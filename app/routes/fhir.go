@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"app/app/enum"
+	"app/app/middleware"
+	"app/app/modules"
+
+	"github.com/gin-gonic/gin"
+)
+
+func FHIR(router *gin.RouterGroup) {
+	module := modules.New()
+	amd := middleware.AuthMiddleware(module.Staff.Svc)
+	audit := middleware.Audit(module.Audit.Svc)
+	read := middleware.RequirePermission(enum.PERMISSION_PATIENT_READ)
+	fhir := router.Group("", amd)
+	{
+		fhir.GET("/Patient", audit(enum.AUDIT_PATIENT_LIST), read, module.FHIR.Ctl.SearchPatient)
+		fhir.POST("/Patient/_search", audit(enum.AUDIT_PATIENT_LIST), read, module.FHIR.Ctl.SearchPatient)
+		fhir.GET("/Patient/:id", audit(enum.AUDIT_PATIENT_READ), read, module.FHIR.Ctl.ReadPatient)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"app/app/enum"
+	"app/app/middleware"
+	"app/app/modules"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Hospital(router *gin.RouterGroup) {
+	module := modules.New()
+	amd := middleware.AuthMiddleware(module.Staff.Svc)
+	hospital := router.Group("", amd, middleware.RequirePermission(enum.PERMISSION_HOSPITAL_MANAGE))
+	{
+		hospital.GET("", module.Hospital.Ctl.List)
+		hospital.POST("/create", module.Hospital.Ctl.Create)
+		hospital.GET("/:code", module.Hospital.Ctl.Get)
+		hospital.PUT("/:code", module.Hospital.Ctl.Update)
+	}
+}
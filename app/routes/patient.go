@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"app/app/enum"
 	"app/app/middleware"
 	"app/app/modules"
 
@@ -9,10 +10,35 @@ import (
 
 func Patient(router *gin.RouterGroup) {
 	module := modules.New()
-	amd := middleware.AuthMiddleware()
+	amd := middleware.AuthMiddleware(module.Staff.Svc)
+	audit := middleware.Audit(module.Audit.Svc)
 	patient := router.Group("")
 	{
-		patient.GET("/search/:id", module.Patient.Ctl.GetPatient)
-		patient.GET("/search", amd, module.Patient.Ctl.List)
+		patient.GET("/search/:id", amd, audit(enum.AUDIT_PATIENT_LOOKUP), middleware.RequirePermission(enum.PERMISSION_PATIENT_LOOKUP), module.Patient.Ctl.GetPatient)
+		patient.GET("/search", amd, audit(enum.AUDIT_PATIENT_LIST), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ), module.Patient.Ctl.List)
+		patient.GET("/export", amd, audit(enum.AUDIT_PATIENT_EXPORT), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ, enum.PERMISSION_PATIENT_EXPORT), module.Patient.Ctl.Export)
+		patient.POST("/create", amd, audit(enum.AUDIT_PATIENT_CREATE), middleware.RequirePermission(enum.PERMISSION_PATIENT_CREATE), module.Patient.Ctl.Create)
+		patient.POST("/import", amd, audit(enum.AUDIT_PATIENT_IMPORT), middleware.RequirePermission(enum.PERMISSION_PATIENT_CREATE, enum.PERMISSION_PATIENT_UPDATE), module.Importer.Ctl.Create)
+		patient.GET("/import/:id", amd, audit(enum.AUDIT_PATIENT_IMPORT), middleware.RequirePermission(enum.PERMISSION_PATIENT_CREATE, enum.PERMISSION_PATIENT_UPDATE), module.Importer.Ctl.Get)
+		patient.GET("/import/:id/errors", amd, audit(enum.AUDIT_PATIENT_IMPORT), middleware.RequirePermission(enum.PERMISSION_PATIENT_CREATE, enum.PERMISSION_PATIENT_UPDATE), module.Importer.Ctl.Errors)
+		patient.GET("/duplicates", amd, audit(enum.AUDIT_PATIENT_DUPLICATES), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ, enum.PERMISSION_PATIENT_MERGE), module.Merge.Ctl.Duplicates)
+		patient.GET("/record-requests/incoming", amd, audit(enum.AUDIT_MPI_REQUEST), middleware.RequirePermission(enum.PERMISSION_MPI_RESPOND), module.MPI.Ctl.Incoming)
+		patient.GET("/record-requests/outgoing", amd, audit(enum.AUDIT_MPI_REQUEST), middleware.RequirePermission(enum.PERMISSION_MPI_REQUEST), module.MPI.Ctl.Outgoing)
+		patient.PUT("/record-requests/:id", amd, audit(enum.AUDIT_MPI_RESPOND), middleware.RequirePermission(enum.PERMISSION_MPI_RESPOND), module.MPI.Ctl.Decide)
+		patient.GET("/record-requests/:id/record", amd, audit(enum.AUDIT_MPI_RECORD), middleware.RequirePermission(enum.PERMISSION_MPI_REQUEST), module.MPI.Ctl.Record)
+		patient.GET("/:id", amd, audit(enum.AUDIT_PATIENT_READ), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ), module.Patient.Ctl.Get)
+		patient.POST("/:id/unmask", amd, audit(enum.AUDIT_PATIENT_UNMASK), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ, enum.PERMISSION_PATIENT_BREAK_GLASS), module.Patient.Ctl.Unmask)
+		patient.POST("/:id/merge", amd, audit(enum.AUDIT_PATIENT_MERGE), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ, enum.PERMISSION_PATIENT_MERGE), module.Merge.Ctl.Merge)
+		patient.GET("/:id/merges", amd, audit(enum.AUDIT_PATIENT_READ), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ), module.Merge.Ctl.History)
+		patient.GET("/:id/links", amd, audit(enum.AUDIT_MPI_LINKS), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ, enum.PERMISSION_MPI_REQUEST), module.MPI.Ctl.Links)
+		patient.POST("/:id/record-requests", amd, audit(enum.AUDIT_MPI_REQUEST), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ, enum.PERMISSION_MPI_REQUEST), module.MPI.Ctl.Request)
+		patient.GET("/:id/consents", amd, audit(enum.AUDIT_CONSENT_READ), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ), module.Consent.Ctl.List)
+		patient.POST("/:id/consents", amd, audit(enum.AUDIT_CONSENT_CAPTURE), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ, enum.PERMISSION_CONSENT_MANAGE), module.Consent.Ctl.Capture)
+		patient.POST("/:id/consents/:consent_id/withdraw", amd, audit(enum.AUDIT_CONSENT_WITHDRAW), middleware.RequirePermission(enum.PERMISSION_PATIENT_READ, enum.PERMISSION_CONSENT_MANAGE), module.Consent.Ctl.Withdraw)
+		patient.GET("/:id/pdpa/report", amd, audit(enum.AUDIT_PDPA_ACCESS), middleware.RequirePermission(enum.PERMISSION_PATIENT_PDPA), module.PDPA.Ctl.Report)
+		patient.POST("/:id/pdpa/erase", amd, audit(enum.AUDIT_PDPA_ERASE), middleware.RequirePermission(enum.PERMISSION_PATIENT_PDPA), module.PDPA.Ctl.Erase)
+		patient.PUT("/:id", amd, audit(enum.AUDIT_PATIENT_UPDATE), middleware.RequirePermission(enum.PERMISSION_PATIENT_UPDATE), module.Patient.Ctl.Update)
+		patient.PATCH("/:id", amd, audit(enum.AUDIT_PATIENT_UPDATE), middleware.RequirePermission(enum.PERMISSION_PATIENT_UPDATE), module.Patient.Ctl.Patch)
+		patient.DELETE("/:id", amd, audit(enum.AUDIT_PATIENT_DELETE), middleware.RequirePermission(enum.PERMISSION_PATIENT_DELETE), module.Patient.Ctl.Delete)
 	}
 }
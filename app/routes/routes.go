@@ -4,6 +4,7 @@ package routes
 import (
 	"net/http"
 
+	"app/app/middleware"
 	"app/internal/logger"
 
 	"github.com/gin-contrib/cors"
@@ -22,6 +23,7 @@ func Router(app *gin.Engine) {
 	})
 
 	// Middleware
+	app.Use(middleware.RequestID())
 	app.Use(otelgin.Middleware(viper.GetString("APP_NAME")))
 	app.Use(cors.New(cors.Config{
 		AllowAllOrigins:        true,
@@ -40,5 +42,8 @@ func Router(app *gin.Engine) {
 	// Define groups of routes under /api/v1
 	Patient(apiV1.Group("/patient"))
 	Staff(apiV1.Group("/staff"))
+	Hospital(apiV1.Group("/hospital"))
+	FHIR(apiV1.Group("/fhir"))
+	Audit(apiV1.Group("/audit"))
 
 }
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"app/app/enum"
+	"app/app/middleware"
 	"app/app/modules"
 
 	"github.com/gin-gonic/gin"
@@ -8,9 +10,12 @@ import (
 
 func Staff(router *gin.RouterGroup) {
 	module := modules.New()
+	amd := middleware.AuthMiddleware(module.Staff.Svc)
 	staff := router.Group("")
 	{
-		staff.POST("/create", module.Staff.Ctl.Create)
+		staff.POST("/create", amd, middleware.RequirePermission(enum.PERMISSION_STAFF_MANAGE), module.Staff.Ctl.Create)
 		staff.POST("/login", module.Staff.Ctl.Login)
+		staff.POST("/refresh", module.Staff.Ctl.Refresh)
+		staff.POST("/logout", amd, module.Staff.Ctl.Logout)
 	}
 }
@@ -0,0 +1,84 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid-cursor")
+
+// Cursor marks a position in a sorted list, it is handed to clients as an
+// opaque string
+type Cursor struct {
+	// Sort is the sort the cursor was made for, it is rejected under another one
+	Sort string `json:"s"`
+	// Values are the sort keys of the row the cursor points at
+	Values []string `json:"v"`
+	// Before pages towards the start of the list
+	Before bool `json:"b,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode reads a cursor made by Encode for the sort
+func Decode(raw, sort string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := new(Cursor)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort || len(c.Values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Key is one sort key of a keyset, Expr is anything bun can format, a bun.Ident
+// or bun.Safe
+type Key struct {
+	Expr any
+	Desc bool
+}
+
+// Reverse flips the direction of every key
+func Reverse(keys []Key) []Key {
+	reversed := make([]Key, len(keys))
+	for i, key := range keys {
+		reversed[i] = Key{Expr: key.Expr, Desc: !key.Desc}
+	}
+	return reversed
+}
+
+// After returns the condition matching the rows that come after values when
+// ordered by keys, expanded to (a > ?) OR (a = ? AND b > ?) ... so each key can
+// have its own direction
+func After(keys []Key, values []string) (string, []any, error) {
+	if len(keys) != len(values) {
+		return "", nil, ErrInvalidCursor
+	}
+	ors := make([]string, 0, len(keys))
+	args := []any{}
+	for i, key := range keys {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, "? = ?")
+			args = append(args, keys[j].Expr, values[j])
+		}
+		op := ">"
+		if key.Desc {
+			op = "<"
+		}
+		ands = append(ands, "? "+op+" ?")
+		args = append(args, key.Expr, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args, nil
+}
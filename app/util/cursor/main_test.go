@@ -0,0 +1,44 @@
+package cursor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		c := Cursor{Sort: "-created_at", Values: []string{"1700000000", "65e08e33-9f57-45fe-b725-82242e3581ad"}, Before: true}
+		decoded, err := Decode(c.Encode(), "-created_at")
+		require.NoError(t, err)
+		assert.Equal(t, c, *decoded)
+	})
+
+	t.Run("Other sort", func(t *testing.T) {
+		c := Cursor{Sort: "-created_at", Values: []string{"1700000000"}}
+		_, err := Decode(c.Encode(), "created_at")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("Garbage", func(t *testing.T) {
+		_, err := Decode("not a cursor!", "created_at")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestAfter(t *testing.T) {
+	keys := []Key{{Expr: "a"}, {Expr: "b", Desc: true}, {Expr: "id"}}
+
+	where, args, err := After(keys, []string{"1", "2", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, "((? > ?) OR (? = ? AND ? < ?) OR (? = ? AND ? = ? AND ? > ?))", where)
+	assert.Equal(t, []any{"a", "1", "a", "1", "b", "2", "a", "1", "b", "2", "id", "3"}, args)
+
+	where, _, err = After(Reverse(keys), []string{"1", "2", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, "((? < ?) OR (? = ? AND ? > ?) OR (? = ? AND ? = ? AND ? < ?))", where)
+
+	_, _, err = After(keys, []string{"1"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,32 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// Table writes rows of text cells as they come, nothing is kept in memory
+type Table interface {
+	Row(cells []string) error
+	// Close writes what the format needs after the last row, it does not close
+	// the underlying writer
+	Close() error
+}
+
+type csvTable struct {
+	writer *csv.Writer
+}
+
+// NewCSV writes an RFC 4180 CSV
+func NewCSV(w io.Writer) Table {
+	return &csvTable{writer: csv.NewWriter(w)}
+}
+
+func (t *csvTable) Row(cells []string) error {
+	return t.writer.Write(cells)
+}
+
+func (t *csvTable) Close() error {
+	t.writer.Flush()
+	return t.writer.Error()
+}
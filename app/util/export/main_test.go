@@ -0,0 +1,53 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSV(t *testing.T) {
+	out := new(bytes.Buffer)
+	table := NewCSV(out)
+	require.NoError(t, table.Row([]string{"national_id", "first_name_th"}))
+	require.NoError(t, table.Row([]string{"1103702071811", "สมชาย, จูเนียร์"}))
+	require.NoError(t, table.Close())
+	assert.Equal(t, "national_id,first_name_th\n1103702071811,\"สมชาย, จูเนียร์\"\n", out.String())
+}
+
+func TestXLSX(t *testing.T) {
+	out := new(bytes.Buffer)
+	table, err := NewXLSX(out, "Patients & more")
+	require.NoError(t, err)
+	require.NoError(t, table.Row([]string{"national_id", "first_name_th"}))
+	require.NoError(t, table.Row([]string{"=1+1", "<สมชาย>\x00"}))
+	require.NoError(t, table.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, _ := io.ReadAll(reader)
+		files[file.Name] = string(content)
+	}
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files["xl/workbook.xml"], `name="Patients &amp; more"`)
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="B1" t="inlineStr"><is><t xml:space="preserve">first_name_th</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">=1+1</t></is></c>`)
+	assert.Contains(t, sheet, "&lt;สมชาย&gt;�")
+	assert.True(t, bytes.HasSuffix([]byte(sheet), []byte("</sheetData></worksheet>")))
+}
+
+func TestColumn(t *testing.T) {
+	assert.Equal(t, "A", column(0))
+	assert.Equal(t, "Z", column(25))
+	assert.Equal(t, "AA", column(26))
+	assert.Equal(t, "BA", column(52))
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// the package parts around the single worksheet, written before it
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+const (
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+type xlsxTable struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	row     int
+}
+
+// NewXLSX writes a single sheet Office Open XML workbook. Cells are inline
+// strings, so the sheet streams without a shared string table and values are
+// never read as formulas.
+func NewXLSX(w io.Writer, sheet string) (Table, error) {
+	archive := zip.NewWriter(w)
+	parts := append(xlsxParts[:len(xlsxParts):len(xlsxParts)], struct {
+		name    string
+		content string
+	}{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, escape(sheet))})
+	for _, part := range parts {
+		entry, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return nil, err
+		}
+	}
+	entry, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(entry, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxTable{archive: archive, sheet: entry}, nil
+}
+
+func (t *xlsxTable) Row(cells []string) error {
+	t.row++
+	line := `<row r="` + strconv.Itoa(t.row) + `">`
+	for i, cell := range cells {
+		line += `<c r="` + column(i) + strconv.Itoa(t.row) + `" t="inlineStr"><is><t xml:space="preserve">` + escape(cell) + `</t></is></c>`
+	}
+	_, err := io.WriteString(t.sheet, line+`</row>`)
+	return err
+}
+
+func (t *xlsxTable) Close() error {
+	if _, err := io.WriteString(t.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return t.archive.Close()
+}
+
+// column returns the letters of the zero based column, A to Z then AA onwards
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// escape escapes XML text, characters XML cannot hold become U+FFFD
+func escape(value string) string {
+	var b xmlBuffer
+	xml.EscapeText(&b, []byte(value))
+	return string(b)
+}
+
+type xmlBuffer []byte
+
+func (b *xmlBuffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
@@ -0,0 +1,55 @@
+package fhir
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// BundleWriter streams a Bundle entry by entry, so a large search result is
+// never held in memory. Close writes the total and ends the Bundle.
+type BundleWriter struct {
+	w       io.Writer
+	entries int
+}
+
+func NewBundleWriter(w io.Writer, bundleType string) (*BundleWriter, error) {
+	head, err := json.Marshal(bundleType)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, `{"resourceType":"Bundle","type":`+string(head)); err != nil {
+		return nil, err
+	}
+	return &BundleWriter{w: w}, nil
+}
+
+// Add writes an entry, the entry array is opened by the first one since FHIR
+// JSON does not allow empty arrays
+func (b *BundleWriter) Add(fullURL string, resource any) error {
+	raw, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	entry, err := json.Marshal(BundleEntry{FullURL: fullURL, Resource: raw, Search: &BundleSearch{Mode: "match"}})
+	if err != nil {
+		return err
+	}
+
+	prefix := ","
+	if b.entries == 0 {
+		prefix = `,"entry":[`
+	}
+	b.entries++
+	_, err = b.w.Write(append([]byte(prefix), entry...))
+	return err
+}
+
+func (b *BundleWriter) Close() error {
+	end := `,"total":` + strconv.Itoa(b.entries) + `}`
+	if b.entries > 0 {
+		end = "]" + end
+	}
+	_, err := io.WriteString(b.w, end)
+	return err
+}
@@ -0,0 +1,170 @@
+package fhir
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The HL7 v2-0203 identifier types used to tell patient identifiers apart
+const (
+	IdentifierTypeSystem = "http://terminology.hl7.org/CodeSystem/v2-0203"
+
+	IdentifierNational = "NI"
+	IdentifierThai     = "NNTHA"
+	IdentifierPassport = "PPN"
+	IdentifierHN       = "MR"
+)
+
+var ErrInvalidResource = errors.New("invalid-fhir-resource")
+
+// Bundle is the subset of a FHIR R4 Bundle the service reads and writes
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type,omitempty"`
+	Total        *int          `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type BundleEntry struct {
+	FullURL  string          `json:"fullUrl,omitempty"`
+	Resource json.RawMessage `json:"resource"`
+	Search   *BundleSearch   `json:"search,omitempty"`
+}
+
+// BundleSearch tells why an entry is in a searchset, "match" or "include"
+type BundleSearch struct {
+	Mode string `json:"mode"`
+}
+
+// Patient is the subset of a FHIR R4 Patient that maps onto model.Patient
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id,omitempty"`
+	Meta         *Meta          `json:"meta,omitempty"`
+	Identifier   []Identifier   `json:"identifier,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+	Gender       string         `json:"gender,omitempty"`
+	BirthDate    string         `json:"birthDate,omitempty"`
+}
+
+type Meta struct {
+	LastUpdated string `json:"lastUpdated,omitempty"`
+}
+
+type Identifier struct {
+	Type   *CodeableConcept `json:"type,omitempty"`
+	System string           `json:"system,omitempty"`
+	Value  string           `json:"value"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Coding struct {
+	System string `json:"system,omitempty"`
+	Code   string `json:"code"`
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+type ContactPoint struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+	Use    string `json:"use,omitempty"`
+}
+
+// ParsePatients reads a Bundle of Patient entries, a single Patient or newline
+// delimited Patients (bulk data NDJSON). Entries of other resource types are skipped.
+func ParsePatients(data []byte) ([]Patient, error) {
+	data = bytes.TrimSpace(data)
+	head := resourceHead{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&head); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResource, err)
+	}
+	// more than one value is NDJSON
+	if decoder.More() {
+		return parseNDJSON(data)
+	}
+
+	switch head.ResourceType {
+	case "Patient":
+		patient := Patient{}
+		if err := json.Unmarshal(data, &patient); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidResource, err)
+		}
+		return []Patient{patient}, nil
+	case "Bundle":
+		bundle := Bundle{}
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidResource, err)
+		}
+		patients := make([]Patient, 0, len(bundle.Entry))
+		for i, entry := range bundle.Entry {
+			patient, ok, err := decodePatient(entry.Resource)
+			if err != nil {
+				return nil, fmt.Errorf("%w: entry %d: %s", ErrInvalidResource, i, err)
+			}
+			if ok {
+				patients = append(patients, *patient)
+			}
+		}
+		return patients, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported resourceType %q", ErrInvalidResource, head.ResourceType)
+	}
+}
+
+func parseNDJSON(data []byte) ([]Patient, error) {
+	patients := []Patient{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for line := 1; decoder.More(); line++ {
+		raw := json.RawMessage{}
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidResource, line, err)
+		}
+		patient, ok, err := decodePatient(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidResource, line, err)
+		}
+		if ok {
+			patients = append(patients, *patient)
+		}
+	}
+	return patients, nil
+}
+
+type resourceHead struct {
+	ResourceType string `json:"resourceType"`
+}
+
+// decodePatient decodes the resource when it is a Patient
+func decodePatient(raw json.RawMessage) (*Patient, bool, error) {
+	head := resourceHead{}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, false, err
+	}
+	if head.ResourceType != "Patient" {
+		return nil, false, nil
+	}
+	patient := new(Patient)
+	if err := json.Unmarshal(raw, patient); err != nil {
+		return nil, false, err
+	}
+	return patient, true, nil
+}
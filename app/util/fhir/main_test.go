@@ -0,0 +1,160 @@
+package fhir
+
+import (
+	patientdto "app/app/modules/patient/dto"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bundle = `{
+  "resourceType": "Bundle",
+  "type": "collection",
+  "entry": [
+    {"resource": {"resourceType": "Organization", "name": "Hospital A"}},
+    {"resource": {
+      "resourceType": "Patient",
+      "identifier": [
+        {"type": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/v2-0203", "code": "NNTHA"}]}, "value": "1103702071811"},
+        {"type": {"coding": [{"code": "MR"}]}, "value": "HN2024000001"}
+      ],
+      "name": [
+        {"family": "ใจดี", "given": ["สมชาย"]},
+        {"use": "official", "family": "Jaidee", "given": ["Somchai", "Lee"]}
+      ],
+      "telecom": [{"system": "phone", "value": "0812345678"}, {"system": "email", "value": "somchai@example.com"}],
+      "gender": "male",
+      "birthDate": "1990-01-01"
+    }}
+  ]
+}`
+
+func TestParsePatients(t *testing.T) {
+	t.Run("Bundle skips other resources", func(t *testing.T) {
+		patients, err := ParsePatients([]byte(bundle))
+		require.NoError(t, err)
+		require.Len(t, patients, 1)
+		assert.Equal(t, "1990-01-01", patients[0].BirthDate)
+	})
+
+	t.Run("Single patient", func(t *testing.T) {
+		patients, err := ParsePatients([]byte(`{"resourceType": "Patient", "gender": "female"}`))
+		require.NoError(t, err)
+		require.Len(t, patients, 1)
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		patients, err := ParsePatients([]byte("{\"resourceType\":\"Patient\"}\n{\"resourceType\":\"Patient\"}\n"))
+		require.NoError(t, err)
+		assert.Len(t, patients, 2)
+	})
+
+	t.Run("Other resource", func(t *testing.T) {
+		_, err := ParsePatients([]byte(`{"resourceType": "Observation"}`))
+		assert.ErrorIs(t, err, ErrInvalidResource)
+	})
+
+	t.Run("Not JSON", func(t *testing.T) {
+		_, err := ParsePatients([]byte(`first_name_th,last_name_th`))
+		assert.ErrorIs(t, err, ErrInvalidResource)
+	})
+}
+
+func TestToPatientRequest(t *testing.T) {
+	patients, err := ParsePatients([]byte(bundle))
+	require.NoError(t, err)
+
+	req := ToPatientRequest(patients[0])
+	assert.Equal(t, "1103702071811", req.NationalID)
+	assert.Equal(t, "สมชาย", req.FirstNameTH)
+	assert.Equal(t, "ใจดี", req.LastNameTH)
+	assert.Equal(t, "Somchai", req.FirstNameEN)
+	assert.Equal(t, "Lee", req.MiddleNameEN)
+	assert.Equal(t, "Jaidee", req.LastNameEN)
+	assert.Equal(t, "0812345678", req.PhoneNumber)
+	assert.Equal(t, "somchai@example.com", req.Email)
+	assert.Equal(t, "1", req.Gender)
+	assert.NoError(t, req.Validate())
+}
+
+func TestGender(t *testing.T) {
+	assert.Equal(t, "2", Gender("Female"))
+	assert.Equal(t, "9", Gender("9"))
+	assert.Equal(t, "other", AdministrativeGender("9"))
+	assert.Equal(t, "unknown", AdministrativeGender("x"))
+}
+
+func TestFromRecord(t *testing.T) {
+	patient := FromRecord(patientdto.PatientRecord{
+		ID:           "65e08e33-9f57-45fe-b725-82242e3581ad",
+		PatientHN:    "HN2024000001",
+		NationalID:   "1103702071811",
+		FirstNameTH:  "สมชาย",
+		LastNameTH:   "ใจดี",
+		FirstNameEN:  "Somchai",
+		MiddleNameEN: "Lee",
+		LastNameEN:   "Jaidee",
+		DateOfBirth:  "1990-01-01",
+		Gender:       "1",
+		PhoneNumber:  "0812345678",
+		UpdatedAt:    "2024-01-01T00:00:00Z",
+	})
+	assert.Equal(t, "male", patient.Gender)
+	assert.Equal(t, "2024-01-01T00:00:00Z", patient.Meta.LastUpdated)
+	require.Len(t, patient.Identifier, 2)
+	assert.Equal(t, IdentifierHN, identifierType(patient.Identifier[0]))
+	assert.Equal(t, IdentifierThai, identifierType(patient.Identifier[1]))
+	assert.Equal(t, []string{"Somchai", "Lee"}, patient.Name[1].Given)
+
+	// what is exported reads back as the same patient
+	req := ToPatientRequest(patient)
+	assert.Equal(t, "1103702071811", req.NationalID)
+	assert.Equal(t, "ใจดี", req.LastNameTH)
+	assert.Equal(t, "Lee", req.MiddleNameEN)
+	assert.Equal(t, "0812345678", req.PhoneNumber)
+}
+
+func TestBundleWriter(t *testing.T) {
+	out := new(bytes.Buffer)
+	writer, err := NewBundleWriter(out, "searchset")
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	assert.JSONEq(t, `{"resourceType":"Bundle","type":"searchset","total":0}`, out.String())
+
+	out.Reset()
+	writer, err = NewBundleWriter(out, "searchset")
+	require.NoError(t, err)
+	require.NoError(t, writer.Add("urn:uuid:1", Patient{ResourceType: "Patient", ID: "1"}))
+	require.NoError(t, writer.Add("urn:uuid:2", Patient{ResourceType: "Patient", ID: "2"}))
+	require.NoError(t, writer.Close())
+
+	patients, err := ParsePatients(out.Bytes())
+	require.NoError(t, err)
+	require.Len(t, patients, 2)
+	assert.Equal(t, "2", patients[1].ID)
+	assert.Contains(t, out.String(), `"total":2`)
+}
+
+func TestParseDate(t *testing.T) {
+	param, err := ParseDate("1990")
+	require.NoError(t, err)
+	assert.Equal(t, PrefixEq, param.Prefix)
+	assert.Equal(t, "1990-01-01", param.Start.Format("2006-01-02"))
+	assert.Equal(t, "1991-01-01", param.End.Format("2006-01-02"))
+
+	param, err = ParseDate("ge1990-02")
+	require.NoError(t, err)
+	assert.Equal(t, PrefixGe, param.Prefix)
+	assert.Equal(t, "1990-03-01", param.End.Format("2006-01-02"))
+
+	param, err = ParseDate("lt1990-12-31")
+	require.NoError(t, err)
+	assert.Equal(t, "1991-01-01", param.End.Format("2006-01-02"))
+
+	for _, value := range []string{"", "90", "1990-13", "sa1990", "1990-01-01T00:00"} {
+		_, err := ParseDate(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,34 @@
+package fhir
+
+// The issue types of an OperationOutcome used by the API
+const (
+	IssueInvalid      = "invalid"
+	IssueNotSupported = "not-supported"
+	IssueNotFound     = "not-found"
+	IssueLogin        = "login"
+	IssueForbidden    = "forbidden"
+	IssueException    = "exception"
+)
+
+// OperationOutcome is how a FHIR server reports errors
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+type OperationOutcomeIssue struct {
+	Severity    string           `json:"severity"`
+	Code        string           `json:"code"`
+	Details     *CodeableConcept `json:"details,omitempty"`
+	Diagnostics string           `json:"diagnostics,omitempty"`
+}
+
+// NewOperationOutcome reports a single error, text is the service message
+// (e.g. patient-not-found) and diagnostics the detail for the client developer
+func NewOperationOutcome(code, text, diagnostics string) OperationOutcome {
+	issue := OperationOutcomeIssue{Severity: "error", Code: code, Diagnostics: diagnostics}
+	if text != "" {
+		issue.Details = &CodeableConcept{Text: text}
+	}
+	return OperationOutcome{ResourceType: "OperationOutcome", Issue: []OperationOutcomeIssue{issue}}
+}
@@ -0,0 +1,152 @@
+package fhir
+
+import (
+	"app/app/enum"
+	patientdto "app/app/modules/patient/dto"
+	"strings"
+	"unicode"
+)
+
+// genders maps FHIR administrative genders onto ISO 5218 codes
+var genders = map[string]enum.Gender{
+	"unknown": enum.GENDER_UNKNOWN,
+	"male":    enum.GENDER_MALE,
+	"female":  enum.GENDER_FEMALE,
+	"other":   enum.GENDER_NOT_APPLICABLE,
+}
+
+// Gender returns the ISO 5218 code of a FHIR administrative gender, codes that
+// already are ISO 5218 are returned as they are
+func Gender(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if gender, ok := genders[value]; ok {
+		return string(gender)
+	}
+	return value
+}
+
+// AdministrativeGender returns the FHIR administrative gender of an ISO 5218 code
+func AdministrativeGender(code string) string {
+	for gender, iso := range genders {
+		if string(iso) == code {
+			return gender
+		}
+	}
+	return "unknown"
+}
+
+// identifierType returns the v2-0203 code of the identifier
+func identifierType(identifier Identifier) string {
+	if identifier.Type == nil {
+		return ""
+	}
+	for _, coding := range identifier.Type.Coding {
+		if coding.System == "" || coding.System == IdentifierTypeSystem {
+			return coding.Code
+		}
+	}
+	return ""
+}
+
+// isThai tells whether the name is written in Thai script
+func isThai(name string) bool {
+	for _, r := range name {
+		if unicode.Is(unicode.Thai, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToPatientRequest maps a FHIR Patient onto a create request. Names in Thai
+// script fill the Thai names, others the English ones, the first given name is
+// the first name and the rest the middle name.
+func ToPatientRequest(patient Patient) *patientdto.CreatePatientRequest {
+	req := &patientdto.CreatePatientRequest{
+		DateOfBirth: patient.BirthDate,
+		Gender:      Gender(patient.Gender),
+	}
+	for _, identifier := range patient.Identifier {
+		value := strings.TrimSpace(identifier.Value)
+		switch identifierType(identifier) {
+		case IdentifierNational, IdentifierThai:
+			req.NationalID = value
+		case IdentifierPassport:
+			req.PassportID = value
+		}
+	}
+	for _, name := range patient.Name {
+		if name.Use == "old" || name.Use == "maiden" {
+			continue
+		}
+		first, middle := "", ""
+		if len(name.Given) > 0 {
+			first = name.Given[0]
+			middle = strings.Join(name.Given[1:], " ")
+		}
+		if isThai(name.Family + first) {
+			if req.FirstNameTH == "" && req.LastNameTH == "" {
+				req.FirstNameTH, req.MiddleNameTH, req.LastNameTH = first, middle, name.Family
+			}
+			continue
+		}
+		if req.FirstNameEN == "" && req.LastNameEN == "" {
+			req.FirstNameEN, req.MiddleNameEN, req.LastNameEN = first, middle, name.Family
+		}
+	}
+	for _, telecom := range patient.Telecom {
+		switch {
+		case telecom.System == "phone" && req.PhoneNumber == "":
+			req.PhoneNumber = telecom.Value
+		case telecom.System == "email" && req.Email == "":
+			req.Email = telecom.Value
+		}
+	}
+	return req
+}
+
+// FromRecord maps an exported patient onto a FHIR Patient, the HN, national ID
+// and passport become typed identifiers and the middle names further given names
+func FromRecord(record patientdto.PatientRecord) Patient {
+	patient := Patient{
+		ResourceType: "Patient",
+		ID:           record.ID,
+		Gender:       AdministrativeGender(record.Gender),
+		BirthDate:    record.DateOfBirth,
+	}
+	if record.UpdatedAt != "" {
+		patient.Meta = &Meta{LastUpdated: record.UpdatedAt}
+	}
+	identifiers := []struct{ code, value string }{
+		{IdentifierHN, record.PatientHN},
+		{IdentifierThai, record.NationalID},
+		{IdentifierPassport, record.PassportID},
+	}
+	for _, identifier := range identifiers {
+		if identifier.value == "" {
+			continue
+		}
+		patient.Identifier = append(patient.Identifier, Identifier{
+			Type:  &CodeableConcept{Coding: []Coding{{System: IdentifierTypeSystem, Code: identifier.code}}},
+			Value: identifier.value,
+		})
+	}
+	names := [][3]string{
+		{record.FirstNameTH, record.MiddleNameTH, record.LastNameTH},
+		{record.FirstNameEN, record.MiddleNameEN, record.LastNameEN},
+	}
+	for _, name := range names {
+		if name[0] == "" && name[2] == "" {
+			continue
+		}
+		given := strings.Fields(name[0] + " " + name[1])
+		patient.Name = append(patient.Name, HumanName{Family: name[2], Given: given})
+	}
+	if record.PhoneNumber != "" {
+		patient.Telecom = append(patient.Telecom, ContactPoint{System: "phone", Value: record.PhoneNumber})
+	}
+	if record.Email != "" {
+		patient.Telecom = append(patient.Telecom, ContactPoint{System: "email", Value: record.Email})
+	}
+	return patient
+}
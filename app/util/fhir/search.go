@@ -0,0 +1,69 @@
+package fhir
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The comparison prefixes of ordered search parameters
+const (
+	PrefixEq = "eq"
+	PrefixNe = "ne"
+	PrefixLt = "lt"
+	PrefixGt = "gt"
+	PrefixLe = "le"
+	PrefixGe = "ge"
+)
+
+var prefixes = []string{PrefixEq, PrefixNe, PrefixLt, PrefixGt, PrefixLe, PrefixGe}
+
+// DateParam is a date search value: the comparison and the range the date
+// covers, [Start, End). A partial date like 1990 or 1990-05 covers all its days.
+type DateParam struct {
+	Prefix string
+	Start  time.Time
+	End    time.Time
+}
+
+// ParseDate reads a date search value, [prefix]YYYY[-MM[-DD]], eq by default
+func ParseDate(value string) (DateParam, error) {
+	param := DateParam{Prefix: PrefixEq}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			param.Prefix = prefix
+			value = value[len(prefix):]
+			break
+		}
+	}
+	layouts := []struct {
+		layout string
+		next   func(time.Time) time.Time
+	}{
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	}
+	for _, l := range layouts {
+		if len(value) != len(l.layout) {
+			continue
+		}
+		start, err := time.Parse(l.layout, value)
+		if err != nil {
+			break
+		}
+		param.Start, param.End = start, l.next(start)
+		return param, nil
+	}
+	return param, fmt.Errorf("invalid date %q, expected [eq|ne|lt|gt|le|ge]YYYY[-MM[-DD]]", value)
+}
+
+// Token splits a token search value, system|code, into its parts. hasSystem
+// tells "|code" (no system) apart from "code" (any system).
+func Token(value string) (system, code string, hasSystem bool) {
+	system, code, hasSystem = strings.Cut(value, "|")
+	if !hasSystem {
+		return "", value, false
+	}
+	return system, code, true
+}
@@ -0,0 +1,273 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/viper"
+)
+
+// Values are encrypted with envelope encryption: every value gets its own data
+// key, and the data key is stored next to it wrapped by a versioned key
+// encryption key from config. The stored form is
+//
+//	enc:v<version>:<wrapped data key>:<nonce and ciphertext>
+//
+// Values without the prefix are plaintext written before the column was
+// encrypted, they read as they are until they are re-encrypted.
+const prefix = "enc:"
+
+// keySize is the size of the key encryption keys and the data keys, AES-256
+const keySize = 32
+
+var (
+	ErrNoKeyring  = errors.New("fieldcrypt: no keyring loaded")
+	ErrUnknownKey = errors.New("fieldcrypt: unknown key version")
+	ErrMalformed  = errors.New("fieldcrypt: malformed encrypted value")
+)
+
+// Keyring holds the key encryption keys by version, the active one encrypts,
+// and the key of the blind indexes
+type Keyring struct {
+	keys   map[int][]byte
+	active int
+	index  []byte
+}
+
+// New checks the keys, an active version of 0 picks the highest version
+func New(keys map[int][]byte, active int, indexKey []byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("fieldcrypt: no encryption keys")
+	}
+	for version, key := range keys {
+		if version <= 0 {
+			return nil, fmt.Errorf("fieldcrypt: key version %d: versions start at 1", version)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("fieldcrypt: key version %d: want %d bytes, got %d", version, keySize, len(key))
+		}
+	}
+	if active == 0 {
+		for version := range keys {
+			active = max(active, version)
+		}
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active version %d", ErrUnknownKey, active)
+	}
+	if len(indexKey) < keySize {
+		return nil, fmt.Errorf("fieldcrypt: blind index key: want at least %d bytes, got %d", keySize, len(indexKey))
+	}
+	return &Keyring{keys: keys, active: active, index: indexKey}, nil
+}
+
+// Parse reads keys written as "version:base64 key" pairs separated by commas,
+// e.g. "1:q2V0...,2:Zm9v...", and a base64 blind index key
+func Parse(keys string, active int, indexKey string) (*Keyring, error) {
+	parsed := map[int][]byte{}
+	for _, pair := range strings.Split(keys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		version, encoded, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("fieldcrypt: key %q: missing version", pair)
+		}
+		v, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: key version %q: %w", version, err)
+		}
+		if _, ok := parsed[v]; ok {
+			return nil, fmt.Errorf("fieldcrypt: key version %d given twice", v)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: key version %d: %w", v, err)
+		}
+		parsed[v] = key
+	}
+	index, err := base64.StdEncoding.DecodeString(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: blind index key: %w", err)
+	}
+	return New(parsed, active, index)
+}
+
+// Load parses FIELD_ENCRYPTION_KEYS, FIELD_ENCRYPTION_KEY_VERSION and BLIND_INDEX_KEY,
+// there are no default keys
+func Load() (*Keyring, error) {
+	for _, name := range []string{"FIELD_ENCRYPTION_KEYS", "BLIND_INDEX_KEY"} {
+		if strings.TrimSpace(viper.GetString(name)) == "" {
+			return nil, fmt.Errorf("fieldcrypt: %s is not set", name)
+		}
+	}
+	return Parse(
+		viper.GetString("FIELD_ENCRYPTION_KEYS"),
+		viper.GetInt("FIELD_ENCRYPTION_KEY_VERSION"),
+		viper.GetString("BLIND_INDEX_KEY"),
+	)
+}
+
+var current atomic.Pointer[Keyring]
+
+// Use makes the keyring the one EncryptedString values and BlindIndex use
+func Use(keyring *Keyring) {
+	current.Store(keyring)
+}
+
+// Current is the keyring of Use, nil before it was called
+func Current() *Keyring {
+	return current.Load()
+}
+
+// Active is the version new values are encrypted with
+func (k *Keyring) Active() int {
+	return k.active
+}
+
+// Prefix starts every value encrypted with the active key, LIKE Prefix()+"%"
+// finds the values that are up to date
+func (k *Keyring) Prefix() string {
+	return prefix + "v" + strconv.Itoa(k.active) + ":"
+}
+
+// Encrypt seals plaintext under a new data key wrapped by the active key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	version := "v" + strconv.Itoa(k.active)
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(version))
+	if err != nil {
+		return "", err
+	}
+	return k.Prefix() +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value of Encrypt, plaintext without the prefix is returned as it is
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !Encrypted(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	version, err := parseVersion(parts[0])
+	if err != nil {
+		return "", err
+	}
+	key, ok := k.keys[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownKey, version)
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	dataKey, err := open(key, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex is a keyed hash of the value for exact-match lookups of encrypted
+// columns, the kind keeps equal values of different columns apart. An empty
+// value has no index.
+func (k *Keyring) BlindIndex(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.index)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Encrypted reports whether a stored value is encrypted rather than plaintext
+func Encrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Version is the key version of an encrypted value, false for plaintext
+func Version(value string) (int, bool) {
+	if !Encrypted(value) {
+		return 0, false
+	}
+	version, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	v, err := parseVersion(version)
+	return v, err == nil
+}
+
+func parseVersion(version string) (int, error) {
+	v, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || !strings.HasPrefix(version, "v") {
+		return 0, ErrMalformed
+	}
+	return v, nil
+}
+
+// seal encrypts with AES-GCM, the random nonce in front of the ciphertext
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	aead, err := gcm(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(key, sealed, additional []byte) ([]byte, error) {
+	aead, err := gcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func gcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,175 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	key1     = bytes.Repeat([]byte{1}, keySize)
+	key2     = bytes.Repeat([]byte{2}, keySize)
+	indexKey = bytes.Repeat([]byte{3}, keySize)
+)
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	keyring, err := New(map[int][]byte{1: key1}, 0, indexKey)
+	require.NoError(t, err)
+
+	sealed, err := keyring.Encrypt("1103702071811")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "enc:v1:"))
+	assert.NotContains(t, sealed, "1103702071811")
+
+	again, err := keyring.Encrypt("1103702071811")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every value gets its own data key and nonce")
+
+	plaintext, err := keyring.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "1103702071811", plaintext)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	old, err := New(map[int][]byte{1: key1}, 0, indexKey)
+	require.NoError(t, err)
+	sealed, err := old.Encrypt("AA1234567")
+	require.NoError(t, err)
+
+	rotated, err := New(map[int][]byte{1: key1, 2: key2}, 0, indexKey)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rotated.Active())
+	assert.Equal(t, "enc:v2:", rotated.Prefix())
+
+	plaintext, err := rotated.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "AA1234567", plaintext)
+
+	retired, err := New(map[int][]byte{2: key2}, 0, indexKey)
+	require.NoError(t, err)
+	_, err = retired.Decrypt(sealed)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_Decrypt(t *testing.T) {
+	keyring, err := New(map[int][]byte{1: key1}, 0, indexKey)
+	require.NoError(t, err)
+
+	t.Run("Plaintext reads as it is", func(t *testing.T) {
+		plaintext, err := keyring.Decrypt("somchai@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "somchai@example.com", plaintext)
+	})
+
+	t.Run("Tampered value fails", func(t *testing.T) {
+		sealed, err := keyring.Encrypt("0812345678")
+		require.NoError(t, err)
+		tampered := sealed[:len(sealed)-2] + "AA"
+		if tampered == sealed {
+			tampered = sealed[:len(sealed)-2] + "BB"
+		}
+		_, err = keyring.Decrypt(tampered)
+		assert.Error(t, err)
+	})
+
+	t.Run("Malformed value fails", func(t *testing.T) {
+		_, err := keyring.Decrypt("enc:v1:abc")
+		assert.ErrorIs(t, err, ErrMalformed)
+	})
+}
+
+func TestKeyring_BlindIndex(t *testing.T) {
+	keyring, err := New(map[int][]byte{1: key1}, 0, indexKey)
+	require.NoError(t, err)
+
+	index := keyring.BlindIndex("national_id", "1103702071811")
+	assert.Len(t, index, 64)
+	assert.Equal(t, index, keyring.BlindIndex("national_id", "1103702071811"))
+	assert.NotEqual(t, index, keyring.BlindIndex("phone_number", "1103702071811"))
+	assert.Empty(t, keyring.BlindIndex("national_id", ""))
+
+	other, err := New(map[int][]byte{1: key1}, 0, bytes.Repeat([]byte{4}, keySize))
+	require.NoError(t, err)
+	assert.NotEqual(t, index, other.BlindIndex("national_id", "1103702071811"))
+}
+
+func TestParse(t *testing.T) {
+	encode := base64.StdEncoding.EncodeToString
+
+	keyring, err := Parse("1:"+encode(key1)+", v2:"+encode(key2), 1, encode(indexKey))
+	require.NoError(t, err)
+	assert.Equal(t, 1, keyring.Active())
+
+	cases := map[string]struct {
+		keys   string
+		active int
+		index  string
+	}{
+		"No keys":           {"", 0, encode(indexKey)},
+		"Short key":         {"1:" + encode(key1[:16]), 0, encode(indexKey)},
+		"Missing version":   {encode(key1), 0, encode(indexKey)},
+		"Unknown active":    {"1:" + encode(key1), 3, encode(indexKey)},
+		"Repeated version":  {"1:" + encode(key1) + ",1:" + encode(key2), 0, encode(indexKey)},
+		"Missing index key": {"1:" + encode(key1), 0, ""},
+	}
+	for name, c := range cases {
+		_, err := Parse(c.keys, c.active, c.index)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	encode := base64.StdEncoding.EncodeToString
+	t.Cleanup(func() {
+		viper.Set("FIELD_ENCRYPTION_KEYS", nil)
+		viper.Set("BLIND_INDEX_KEY", nil)
+	})
+
+	viper.Set("FIELD_ENCRYPTION_KEYS", "1:"+encode(key1))
+	viper.Set("BLIND_INDEX_KEY", "")
+	_, err := Load()
+	assert.EqualError(t, err, "fieldcrypt: BLIND_INDEX_KEY is not set")
+
+	viper.Set("FIELD_ENCRYPTION_KEYS", " ")
+	viper.Set("BLIND_INDEX_KEY", encode(indexKey))
+	_, err = Load()
+	assert.EqualError(t, err, "fieldcrypt: FIELD_ENCRYPTION_KEYS is not set")
+
+	viper.Set("FIELD_ENCRYPTION_KEYS", "1:"+encode(key1))
+	_, err = Load()
+	assert.NoError(t, err)
+}
+
+func TestEncryptedString(t *testing.T) {
+	keyring, err := New(map[int][]byte{1: key1}, 0, indexKey)
+	require.NoError(t, err)
+	Use(keyring)
+	t.Cleanup(func() { Use(nil) })
+
+	value, err := EncryptedString("AA1234567").Value()
+	require.NoError(t, err)
+	assert.True(t, Encrypted(value.(string)))
+
+	var scanned EncryptedString
+	require.NoError(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, EncryptedString("AA1234567"), scanned)
+
+	require.NoError(t, scanned.Scan("AA7654321"))
+	assert.Equal(t, EncryptedString("AA7654321"), scanned, "plaintext rows read before re-encryption")
+
+	require.NoError(t, scanned.Scan(nil))
+	assert.Empty(t, scanned)
+
+	empty, err := EncryptedString("").Value()
+	require.NoError(t, err)
+	assert.Nil(t, empty)
+
+	Use(nil)
+	_, err = EncryptedString("AA1234567").Value()
+	assert.ErrorIs(t, err, ErrNoKeyring)
+}
@@ -0,0 +1,65 @@
+package fieldcrypt
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// EncryptedString is a text column stored encrypted with the keyring of Use.
+// It holds the plaintext in memory, an empty string is stored as NULL.
+type EncryptedString string
+
+var (
+	_ driver.Valuer = EncryptedString("")
+	_ sql.Scanner   = (*EncryptedString)(nil)
+)
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s == "" {
+		return nil, nil
+	}
+	keyring := Current()
+	if keyring == nil {
+		return nil, ErrNoKeyring
+	}
+	return keyring.Encrypt(string(s))
+}
+
+func (s *EncryptedString) Scan(src any) error {
+	var value string
+	switch src := src.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		value = src
+	case []byte:
+		value = string(src)
+	default:
+		return fmt.Errorf("fieldcrypt: cannot scan %T into EncryptedString", src)
+	}
+	if !Encrypted(value) {
+		*s = EncryptedString(value)
+		return nil
+	}
+	keyring := Current()
+	if keyring == nil {
+		return ErrNoKeyring
+	}
+	plaintext, err := keyring.Decrypt(value)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
+
+// BlindIndex is the blind index of the keyring of Use, empty when none was loaded
+func BlindIndex(kind, value string) string {
+	keyring := Current()
+	if keyring == nil {
+		return ""
+	}
+	return keyring.BlindIndex(kind, value)
+}
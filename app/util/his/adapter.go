@@ -0,0 +1,285 @@
+package his
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AuthNone   = "none"
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+	AuthAPIKey = "api_key"
+
+	defaultTimeout          = 10 * time.Second
+	defaultDeadline         = 30 * time.Second
+	defaultMaxAttempts      = 3
+	defaultBackoff          = 200 * time.Millisecond
+	defaultMaxBackoff       = 2 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultSearchPath       = "/patient/search/{id}"
+	defaultAPIKeyName       = "X-API-Key"
+)
+
+// AuthConfig describes how requests to the upstream are authenticated
+type AuthConfig struct {
+	Type     string `json:"type"`
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Header   string `json:"header"`
+}
+
+// AdapterConfig is the integration setting of one hospital
+type AdapterConfig struct {
+	Hospital   string     `json:"hospital"`
+	BaseURL    string     `json:"base_url"`
+	SearchPath string     `json:"search_path"`
+	Auth       AuthConfig `json:"auth"`
+	// Timeout bounds a single attempt, Deadline the whole lookup including retries
+	Timeout  string        `json:"timeout"`
+	Deadline string        `json:"deadline"`
+	Retry    RetryConfig   `json:"retry"`
+	Breaker  BreakerConfig `json:"breaker"`
+	// Fields maps a Patient json key to a dot separated path in the upstream payload
+	Fields map[string]string `json:"fields"`
+}
+
+// Patient is a patient record as the upstream answers it, its keys found in the
+// upstream payload through AdapterConfig.Fields
+type Patient struct {
+	FirstNameTH  string `json:"first_name_th"`
+	MiddleNameTH string `json:"middle_name_th"`
+	LastNameTH   string `json:"last_name_th"`
+	FirstNameEN  string `json:"first_name_en"`
+	MiddleNameEN string `json:"middle_name_en"`
+	LastNameEN   string `json:"last_name_en"`
+	DateOfBirth  string `json:"date_of_birth"`
+	PatientHN    string `json:"patient_hn"`
+	NationalID   string `json:"national_id"`
+	PassportID   string `json:"passport_id"`
+	PhoneNumber  string `json:"phone_number"`
+	Email        string `json:"email"`
+	Gender       string `json:"gender"`
+	Hospital     string `json:"hospital"`
+}
+
+// UpstreamError is returned when the upstream answers with an unexpected status.
+// The upstream body is dropped so it never reaches our clients.
+type UpstreamError struct {
+	Hospital   string
+	StatusCode int
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("his %s responded with status %d", e.Hospital, e.StatusCode)
+}
+
+type Adapter struct {
+	config      AdapterConfig
+	fields      map[string]string
+	timeout     time.Duration
+	deadline    time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	breaker     *breaker
+	client      *http.Client
+}
+
+func NewAdapter(conf AdapterConfig) (*Adapter, error) {
+	if conf.Hospital == "" {
+		return nil, fmt.Errorf("his adapter: hospital is required")
+	}
+	if _, err := url.ParseRequestURI(conf.BaseURL); err != nil {
+		return nil, fmt.Errorf("his adapter %s: invalid base_url: %w", conf.Hospital, err)
+	}
+	if conf.SearchPath == "" {
+		conf.SearchPath = defaultSearchPath
+	}
+	if conf.Auth.Type == "" {
+		conf.Auth.Type = AuthNone
+	}
+	switch conf.Auth.Type {
+	case AuthNone, AuthBearer, AuthBasic, AuthAPIKey:
+	default:
+		return nil, fmt.Errorf("his adapter %s: unsupported auth type %q", conf.Hospital, conf.Auth.Type)
+	}
+
+	timeout, err := parseDuration(conf.Timeout, defaultTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("his adapter %s: invalid timeout: %w", conf.Hospital, err)
+	}
+	deadline, err := parseDuration(conf.Deadline, defaultDeadline)
+	if err != nil {
+		return nil, fmt.Errorf("his adapter %s: invalid deadline: %w", conf.Hospital, err)
+	}
+	backoff, err := parseDuration(conf.Retry.Backoff, defaultBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("his adapter %s: invalid retry backoff: %w", conf.Hospital, err)
+	}
+	maxBackoff, err := parseDuration(conf.Retry.MaxBackoff, defaultMaxBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("his adapter %s: invalid retry max_backoff: %w", conf.Hospital, err)
+	}
+	cooldown, err := parseDuration(conf.Breaker.Cooldown, defaultBreakerCooldown)
+	if err != nil {
+		return nil, fmt.Errorf("his adapter %s: invalid breaker cooldown: %w", conf.Hospital, err)
+	}
+
+	maxAttempts := conf.Retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	threshold := conf.Breaker.Threshold
+	if threshold == 0 {
+		threshold = defaultBreakerThreshold
+	}
+
+	fields := defaultFields()
+	for key, path := range conf.Fields {
+		if _, ok := fields[key]; !ok {
+			return nil, fmt.Errorf("his adapter %s: unknown field %q", conf.Hospital, key)
+		}
+		fields[key] = path
+	}
+
+	return &Adapter{
+		config:      conf,
+		fields:      fields,
+		timeout:     timeout,
+		deadline:    deadline,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+		breaker:     newBreaker(threshold, cooldown),
+		client:      &http.Client{},
+	}, nil
+}
+
+func (a *Adapter) Hospital() string {
+	return a.config.Hospital
+}
+
+func (a *Adapter) Timeout() time.Duration {
+	return a.timeout
+}
+
+// FetchPatient looks the patient up on the upstream and maps it into a Patient
+func (a *Adapter) FetchPatient(ctx context.Context, id string) (*Patient, error) {
+	if err := a.breaker.allow(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, a.Hospital())
+	}
+	body, err := a.get(ctx, id)
+	a.breaker.done(err)
+	if err != nil {
+		return nil, err
+	}
+	return a.mapPatient(body)
+}
+
+func (a *Adapter) newRequest(ctx context.Context, id string) (*http.Request, error) {
+	path := strings.ReplaceAll(a.config.SearchPath, "{id}", url.PathEscape(id))
+	endpoint := strings.TrimRight(a.config.BaseURL, "/") + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	auth := a.config.Auth
+	switch auth.Type {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case AuthBasic:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case AuthAPIKey:
+		header := auth.Header
+		if header == "" {
+			header = defaultAPIKeyName
+		}
+		req.Header.Set(header, auth.Token)
+	}
+	return req, nil
+}
+
+func (a *Adapter) mapPatient(body []byte) (*Patient, error) {
+	payload := map[string]any{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("his %s: decode response: %w", a.Hospital(), err)
+	}
+
+	mapped := make(map[string]string, len(a.fields))
+	for key, path := range a.fields {
+		if path == "" {
+			continue
+		}
+		mapped[key] = stringify(lookup(payload, path))
+	}
+
+	raw, err := json.Marshal(mapped)
+	if err != nil {
+		return nil, err
+	}
+	patient := new(Patient)
+	if err := json.Unmarshal(raw, patient); err != nil {
+		return nil, err
+	}
+	return patient, nil
+}
+
+// defaultFields maps every Patient key onto the same key upstream
+func defaultFields() map[string]string {
+	fields := map[string]string{}
+	t := reflect.TypeOf(Patient{})
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if key != "" && key != "-" {
+			fields[key] = key
+		}
+	}
+	return fields
+}
+
+func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func lookup(payload map[string]any, path string) any {
+	var current any = payload
+	for _, part := range strings.Split(path, ".") {
+		node, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = node[part]
+	}
+	return current
+}
+
+func stringify(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,129 @@
+package his
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrPatientNotFound     = errors.New("his-patient-not-found")
+	ErrUpstreamTimeout     = errors.New("his-upstream-timeout")
+	ErrUpstreamUnavailable = errors.New("his-upstream-unavailable")
+	ErrCircuitOpen         = errors.New("his-circuit-open")
+)
+
+// RetryConfig bounds the retries of a failed lookup
+type RetryConfig struct {
+	MaxAttempts int    `json:"max_attempts"`
+	Backoff     string `json:"backoff"`
+	MaxBackoff  string `json:"max_backoff"`
+}
+
+// BreakerConfig controls when the circuit of a hospital opens
+type BreakerConfig struct {
+	Threshold int    `json:"threshold"`
+	Cooldown  string `json:"cooldown"`
+}
+
+// get runs the lookup within the deadline, retrying transient failures
+// with capped exponential backoff and full jitter.
+func (a *Adapter) get(ctx context.Context, id string) ([]byte, error) {
+	if a.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.deadline)
+		defer cancel()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < a.maxAttempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(a.backoffFor(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, lastErr
+			case <-timer.C:
+			}
+		}
+
+		body, err := a.attempt(ctx, id)
+		if err == nil || !retryable(err) || ctx.Err() != nil {
+			return body, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (a *Adapter) attempt(ctx context.Context, id string) ([]byte, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	req, err := a.newRequest(attemptCtx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, a.normalize(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, a.normalize(ctx, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrPatientNotFound, a.Hospital())
+	default:
+		return nil, &UpstreamError{
+			Hospital:   a.Hospital(),
+			StatusCode: resp.StatusCode,
+		}
+	}
+}
+
+// normalize turns transport errors into the package errors, keeping a
+// cancellation by the caller as it is
+func (a *Adapter) normalize(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %s", ErrUpstreamTimeout, a.Hospital())
+	}
+	return fmt.Errorf("%w: %s: %v", ErrUpstreamUnavailable, a.Hospital(), err)
+}
+
+func (a *Adapter) backoffFor(attempt int) time.Duration {
+	backoff := a.backoff << (attempt - 1)
+	if backoff <= 0 || backoff > a.maxBackoff {
+		backoff = a.maxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(backoff) + 1))
+}
+
+func retryable(err error) bool {
+	if errors.Is(err, ErrUpstreamTimeout) || errors.Is(err, ErrUpstreamUnavailable) {
+		return true
+	}
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		switch upstream.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+// Package histest provides a local stand-in for a hospital HIS, for use in tests.
+package histest
+
+import (
+	"app/app/util/his"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is an httptest server answering GET /patient/search/{id}
+type Server struct {
+	*httptest.Server
+
+	Hospital string
+
+	mu         sync.Mutex
+	patients   map[string]any
+	requests   []*http.Request
+	failStatus int
+	failCount  int
+	delay      time.Duration
+}
+
+// NewServer starts a stand-in HIS for the hospital, closed when the test ends
+func NewServer(t testing.TB, hospital string, patients map[string]any) *Server {
+	t.Helper()
+	s := &Server{
+		Hospital: hospital,
+		patients: patients,
+	}
+	if s.patients == nil {
+		s.patients = map[string]any{}
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetPatient adds or replaces the upstream record returned for the id
+func (s *Server) SetPatient(id string, patient any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patients[id] = patient
+}
+
+// FailNext makes the next n requests answer with the status
+func (s *Server) FailNext(status int, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failStatus = status
+	s.failCount = n
+}
+
+// SetDelay holds every response for the duration
+func (s *Server) SetDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = delay
+}
+
+// Requests returns every request the stand-in has received
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+// Config returns an adapter config pointing at the stand-in
+func (s *Server) Config() his.AdapterConfig {
+	return his.AdapterConfig{
+		Hospital:   s.Hospital,
+		BaseURL:    s.URL,
+		SearchPath: "/patient/search/{id}",
+	}
+}
+
+// Adapter returns an adapter wired to the stand-in
+func (s *Server) Adapter(t testing.TB) *his.Adapter {
+	t.Helper()
+	adapter, err := his.NewAdapter(s.Config())
+	if err != nil {
+		t.Fatalf("histest: %v", err)
+	}
+	return adapter
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	id := strings.TrimPrefix(r.URL.Path, "/patient/search/")
+	patient, ok := s.patients[id]
+	failStatus := 0
+	if s.failCount > 0 {
+		s.failCount--
+		failStatus = s.failStatus
+	}
+	delay := s.delay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if failStatus != 0 {
+		w.WriteHeader(failStatus)
+		json.NewEncoder(w).Encode(map[string]string{"message": "upstream failure"})
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "patient not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(patient)
+}
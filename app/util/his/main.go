@@ -0,0 +1,94 @@
+package his
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Registry keeps one upstream HIS adapter per hospital
+type Registry struct {
+	mu       sync.RWMutex
+	adapters map[string]*Adapter
+}
+
+var ErrAdapterNotFound = errors.New("his-adapter-not-found")
+
+func NewRegistry(adapters ...*Adapter) *Registry {
+	r := &Registry{
+		adapters: make(map[string]*Adapter),
+	}
+	for _, adapter := range adapters {
+		r.Register(adapter)
+	}
+	return r
+}
+
+// Register adds or replaces the adapter of the adapter's hospital
+func (r *Registry) Register(adapter *Adapter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.adapters[adapter.Hospital()] = adapter
+}
+
+// Get returns the adapter registered for the hospital
+func (r *Registry) Get(hospital string) (*Adapter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	adapter, ok := r.adapters[hospital]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAdapterNotFound, hospital)
+	}
+	return adapter, nil
+}
+
+// Remove drops the adapter of the hospital, lookups then fail with ErrAdapterNotFound
+func (r *Registry) Remove(hospital string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.adapters, hospital)
+}
+
+// Hospitals returns the hospitals that have an adapter
+func (r *Registry) Hospitals() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hospitals := make([]string, 0, len(r.adapters))
+	for hospital := range r.adapters {
+		hospitals = append(hospitals, hospital)
+	}
+	return hospitals
+}
+
+// Load builds the registry from HIS_ADAPTERS, a JSON array of AdapterConfig.
+// When it is empty the legacy hospital-a integration is registered.
+func Load() (*Registry, error) {
+	configs := []AdapterConfig{}
+	raw := strings.TrimSpace(viper.GetString("HIS_ADAPTERS"))
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+			return nil, fmt.Errorf("invalid HIS_ADAPTERS: %w", err)
+		}
+	}
+	if len(configs) == 0 {
+		configs = append(configs, AdapterConfig{
+			Hospital:   "hospital-a",
+			BaseURL:    "https://hospital-a.api.co.th",
+			SearchPath: "/patient/search/{id}",
+		})
+	}
+
+	registry := NewRegistry()
+	for _, conf := range configs {
+		adapter, err := NewAdapter(conf)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(adapter)
+	}
+	return registry, nil
+}
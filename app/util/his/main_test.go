@@ -0,0 +1,154 @@
+package his_test
+
+import (
+	"app/app/util/his"
+	"app/app/util/his/histest"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_SelectsAdapterPerHospital(t *testing.T) {
+	hospitalA := histest.NewServer(t, "hospital-a", map[string]any{
+		"1100700000001": map[string]any{"first_name_en": "Somchai", "national_id": "1100700000001"},
+	})
+	hospitalB := histest.NewServer(t, "hospital-b", map[string]any{
+		"1100700000001": map[string]any{"first_name_en": "Somsri", "national_id": "1100700000001"},
+	})
+	registry := his.NewRegistry(hospitalA.Adapter(t), hospitalB.Adapter(t))
+
+	adapter, err := registry.Get("hospital-b")
+	require.NoError(t, err)
+	patient, err := adapter.FetchPatient(context.Background(), "1100700000001")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Somsri", patient.FirstNameEN)
+	assert.Len(t, hospitalA.Requests(), 0)
+	assert.Len(t, hospitalB.Requests(), 1)
+
+	_, err = registry.Get("hospital-c")
+	assert.True(t, errors.Is(err, his.ErrAdapterNotFound))
+}
+
+func TestAdapter_AuthAndFieldMapping(t *testing.T) {
+	server := histest.NewServer(t, "hospital-a", map[string]any{
+		"A1234567": map[string]any{
+			"name":      map[string]any{"first": "John", "last": "Doe"},
+			"passport":  "A1234567",
+			"birthDate": "1990-01-01",
+			"sex":       1,
+		},
+	})
+	conf := server.Config()
+	conf.Auth = his.AuthConfig{Type: his.AuthAPIKey, Header: "X-Hospital-Key", Token: "key-a"}
+	conf.Fields = map[string]string{
+		"first_name_en": "name.first",
+		"last_name_en":  "name.last",
+		"passport_id":   "passport",
+		"date_of_birth": "birthDate",
+		"gender":        "sex",
+	}
+	adapter, err := his.NewAdapter(conf)
+	require.NoError(t, err)
+
+	patient, err := adapter.FetchPatient(context.Background(), "A1234567")
+	require.NoError(t, err)
+
+	assert.Equal(t, "John", patient.FirstNameEN)
+	assert.Equal(t, "Doe", patient.LastNameEN)
+	assert.Equal(t, "A1234567", patient.PassportID)
+	assert.Equal(t, "1990-01-01", patient.DateOfBirth)
+	assert.Equal(t, "1", patient.Gender)
+	assert.Equal(t, "key-a", server.Requests()[0].Header.Get("X-Hospital-Key"))
+}
+
+func TestAdapter_NotFound(t *testing.T) {
+	server := histest.NewServer(t, "hospital-a", nil)
+
+	_, err := server.Adapter(t).FetchPatient(context.Background(), "missing")
+
+	assert.True(t, errors.Is(err, his.ErrPatientNotFound))
+	assert.Len(t, server.Requests(), 1, "a 404 is not retried")
+}
+
+func TestAdapter_RetriesTransientFailures(t *testing.T) {
+	server := histest.NewServer(t, "hospital-a", map[string]any{
+		"p1": map[string]any{"first_name_en": "John"},
+	})
+	server.FailNext(http.StatusServiceUnavailable, 2)
+	conf := server.Config()
+	conf.Retry = his.RetryConfig{MaxAttempts: 3, Backoff: "1ms", MaxBackoff: "5ms"}
+	adapter, err := his.NewAdapter(conf)
+	require.NoError(t, err)
+
+	patient, err := adapter.FetchPatient(context.Background(), "p1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "John", patient.FirstNameEN)
+	assert.Len(t, server.Requests(), 3)
+}
+
+func TestAdapter_DoesNotRetryClientErrors(t *testing.T) {
+	server := histest.NewServer(t, "hospital-a", nil)
+	server.FailNext(http.StatusBadRequest, 1)
+
+	_, err := server.Adapter(t).FetchPatient(context.Background(), "p1")
+
+	var upstream *his.UpstreamError
+	require.True(t, errors.As(err, &upstream))
+	assert.Equal(t, http.StatusBadRequest, upstream.StatusCode)
+	assert.Len(t, server.Requests(), 1)
+}
+
+func TestAdapter_Timeout(t *testing.T) {
+	server := histest.NewServer(t, "hospital-a", map[string]any{"p1": map[string]any{}})
+	server.SetDelay(200 * time.Millisecond)
+	conf := server.Config()
+	conf.Timeout = "20ms"
+	conf.Retry = his.RetryConfig{MaxAttempts: 1}
+	adapter, err := his.NewAdapter(conf)
+	require.NoError(t, err)
+
+	_, err = adapter.FetchPatient(context.Background(), "p1")
+
+	assert.True(t, errors.Is(err, his.ErrUpstreamTimeout))
+}
+
+func TestAdapter_CircuitBreaker(t *testing.T) {
+	server := histest.NewServer(t, "hospital-a", map[string]any{"p1": map[string]any{}})
+	server.FailNext(http.StatusInternalServerError, 2)
+	conf := server.Config()
+	conf.Retry = his.RetryConfig{MaxAttempts: 1}
+	conf.Breaker = his.BreakerConfig{Threshold: 2, Cooldown: "50ms"}
+	adapter, err := his.NewAdapter(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = adapter.FetchPatient(context.Background(), "p1")
+		require.Error(t, err)
+	}
+	_, err = adapter.FetchPatient(context.Background(), "p1")
+	assert.True(t, errors.Is(err, his.ErrCircuitOpen))
+	assert.Len(t, server.Requests(), 2, "an open circuit does not reach the upstream")
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = adapter.FetchPatient(context.Background(), "p1")
+	assert.NoError(t, err, "the probe after the cooldown closes the circuit")
+}
+
+func TestNewAdapter_InvalidConfig(t *testing.T) {
+	_, err := his.NewAdapter(his.AdapterConfig{Hospital: "hospital-a", BaseURL: "://"})
+	assert.Error(t, err)
+
+	_, err = his.NewAdapter(his.AdapterConfig{
+		Hospital: "hospital-a",
+		BaseURL:  "http://localhost",
+		Fields:   map[string]string{"unknown": "x"},
+	})
+	assert.Error(t, err)
+}
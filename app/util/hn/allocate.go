@@ -0,0 +1,75 @@
+package hn
+
+import (
+	"app/app/model"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Next allocates the next hospital number of the hospital. The counter row
+// stays locked until tx ends, so a rolled back insert gives its number back.
+func (f *Format) Next(ctx context.Context, tx bun.IDB, hospital string) (string, error) {
+	hns, err := f.Allocate(ctx, tx, hospital, 1)
+	if err != nil {
+		return "", err
+	}
+	return hns[0], nil
+}
+
+// Allocate allocates n hospital numbers. Numbers a patient already holds, such
+// as HNs synced from the HIS in the same format, are skipped and more are taken
+// from the counter in their place.
+func (f *Format) Allocate(ctx context.Context, tx bun.IDB, hospital string, n int) ([]string, error) {
+	hns := make([]string, 0, n)
+	for len(hns) < n {
+		allocated, err := f.allocate(ctx, tx, hospital, n-len(hns))
+		if err != nil {
+			return nil, err
+		}
+		var taken []string
+		err = tx.NewSelect().
+			Model((*model.Patient)(nil)).
+			Column("patient_hn").
+			WhereAllWithDeleted().
+			Where("hospital = ?", hospital).
+			Where("patient_hn IN (?)", bun.In(allocated)).
+			Scan(ctx, &taken)
+		if err != nil {
+			return nil, err
+		}
+		for _, hn := range allocated {
+			if !slices.Contains(taken, hn) {
+				hns = append(hns, hn)
+			}
+		}
+	}
+	return hns, nil
+}
+
+// allocate renders n consecutive hospital numbers with a single counter update
+func (f *Format) allocate(ctx context.Context, tx bun.IDB, hospital string, n int) ([]string, error) {
+	now := time.Now()
+	seq := &model.HNSequence{
+		Hospital: hospital,
+		Period:   f.Period(now),
+		Value:    int64(n),
+	}
+	_, err := tx.NewInsert().
+		Model(seq).
+		On("CONFLICT (hospital, period) DO UPDATE").
+		Set("value = ?TableAlias.value + ?", n).
+		Set("updated_at = EXTRACT(EPOCH FROM NOW())").
+		Returning("value").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hns := make([]string, 0, n)
+	for value := seq.Value - int64(n) + 1; value <= seq.Value; value++ {
+		hns = append(hns, f.Render(hospital, now, value))
+	}
+	return hns, nil
+}
@@ -0,0 +1,49 @@
+package hn
+
+import (
+	"app/app/helper/testhelper"
+	"app/app/model"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat_Allocate(t *testing.T) {
+	ctx := context.Background()
+	format, err := Parse("HN{seq:6}")
+	require.NoError(t, err)
+
+	t.Run("Success - Consecutive numbers per hospital", func(t *testing.T) {
+		db := testhelper.DB(t)
+		hospital := testhelper.Hospital(t, db)
+
+		first, err := format.Next(ctx, db, hospital)
+		require.NoError(t, err)
+		next, err := format.Allocate(ctx, db, hospital, 2)
+		require.NoError(t, err)
+
+		assert.Equal(t, "HN000001", first)
+		assert.Equal(t, []string{"HN000002", "HN000003"}, next)
+	})
+
+	t.Run("Success - Skips numbers a patient already holds", func(t *testing.T) {
+		db := testhelper.DB(t)
+		hospital := testhelper.Hospital(t, db)
+		synced := &model.Patient{
+			FirstNameTH: "สมชาย",
+			LastNameTH:  "ใจดี",
+			PatientHN:   "HN000002",
+			NationalID:  "1103702071811",
+			Hospital:    hospital,
+		}
+		_, err := db.NewInsert().Model(synced).Exec(ctx)
+		require.NoError(t, err)
+
+		hns, err := format.Allocate(ctx, db, hospital, 3)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"HN000001", "HN000003", "HN000004"}, hns)
+	})
+}
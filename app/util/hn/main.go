@@ -0,0 +1,80 @@
+package hn
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Format renders hospital numbers from a template such as "HN{yyyy}{seq:6}".
+//
+//	{hospital}   hospital code in upper case
+//	{yyyy} {yy}  Gregorian year
+//	{bbbb} {bb}  Buddhist Era year
+//	{seq:N}      counter zero padded to N digits
+//
+// The counter restarts every year when the template contains a year token.
+type Format struct {
+	template string
+	yearly   bool
+}
+
+var (
+	tokenRegex = regexp.MustCompile(`\{(hospital|yyyy|yy|bbbb|bb|seq(?::(\d+))?)\}`)
+	yearTokens = []string{"{yyyy}", "{yy}", "{bbbb}", "{bb}"}
+)
+
+func Parse(template string) (*Format, error) {
+	if !strings.Contains(template, "{seq") {
+		return nil, fmt.Errorf("hn format %q: missing {seq} token", template)
+	}
+	if rest := tokenRegex.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		return nil, fmt.Errorf("hn format %q: unknown token", template)
+	}
+	f := &Format{template: template}
+	for _, token := range yearTokens {
+		if strings.Contains(template, token) {
+			f.yearly = true
+		}
+	}
+	return f, nil
+}
+
+// Load parses HN_FORMAT
+func Load() (*Format, error) {
+	return Parse(viper.GetString("HN_FORMAT"))
+}
+
+// Period is the key the counter is kept under at the time
+func (f *Format) Period(t time.Time) string {
+	if !f.yearly {
+		return ""
+	}
+	return strconv.Itoa(t.Year())
+}
+
+func (f *Format) Render(hospital string, t time.Time, seq int64) string {
+	year := t.Year()
+	return tokenRegex.ReplaceAllStringFunc(f.template, func(token string) string {
+		match := tokenRegex.FindStringSubmatch(token)
+		switch {
+		case match[1] == "hospital":
+			return strings.ToUpper(hospital)
+		case match[1] == "yyyy":
+			return fmt.Sprintf("%04d", year)
+		case match[1] == "yy":
+			return fmt.Sprintf("%02d", year%100)
+		case match[1] == "bbbb":
+			return fmt.Sprintf("%04d", year+543)
+		case match[1] == "bb":
+			return fmt.Sprintf("%02d", (year+543)%100)
+		default:
+			width, _ := strconv.Atoi(match[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		}
+	})
+}
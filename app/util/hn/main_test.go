@@ -0,0 +1,42 @@
+package hn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat_Render(t *testing.T) {
+	at := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"HN{yyyy}{seq:6}":      "HN2025000042",
+		"{bb}-{seq:5}":         "68-00042",
+		"{hospital}/{yy}{seq}": "HOSPITAL-A/2542",
+		"{seq:8}":              "00000042",
+	}
+	for template, expected := range cases {
+		f, err := Parse(template)
+		require.NoError(t, err, template)
+		assert.Equal(t, expected, f.Render("hospital-a", at, 42), template)
+	}
+}
+
+func TestFormat_Period(t *testing.T) {
+	at := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	yearly, _ := Parse("HN{yyyy}{seq:6}")
+	running, _ := Parse("HN{seq:8}")
+
+	assert.Equal(t, "2025", yearly.Period(at))
+	assert.Equal(t, "", running.Period(at))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse("HN{yyyy}")
+	assert.Error(t, err)
+
+	_, err = Parse("HN{month}{seq:4}")
+	assert.Error(t, err)
+}
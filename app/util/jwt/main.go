@@ -18,9 +18,21 @@ const (
 )
 
 type ClaimData struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Hospital string `json:"hospital"`
+	ID          string   `json:"id"`
+	Username    string   `json:"username"`
+	Hospital    string   `json:"hospital"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// HasPermission reports whether the token grants the permission
+func (c ClaimData) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
 }
 
 type Claims struct {
@@ -33,12 +45,11 @@ func CreateToken(claims ClaimData) (string, *Claims, error) {
 
 	now := time.Now()
 	id := uuid.New().String()
-	duration := viper.GetInt64("JWT_DURATION")
 	claimsData := Claims{
 		Data: claims,
 		Uuid: id,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(duration) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(GenerateExpires()),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
@@ -79,8 +90,9 @@ func getSecret(token *jwt.Token) (interface{}, error) {
 	return []byte(viper.GetString("JWT_SECRET")), nil
 }
 
+// GenerateExpires returns the expiry of an access token issued now
 func GenerateExpires() time.Time {
 	now := time.Now()
-	duration := viper.GetInt64("JWT_DURATION")
-	return now.Add(time.Duration(duration) * time.Hour)
+	duration := viper.GetInt64("JWT_ACCESS_DURATION")
+	return now.Add(time.Duration(duration) * time.Minute)
 }
@@ -0,0 +1,31 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// NewRefreshToken returns a random opaque token and the hash to store in its place
+func NewRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func GenerateRefreshExpires() time.Time {
+	duration := viper.GetInt64("JWT_REFRESH_DURATION")
+	return time.Now().Add(time.Duration(duration) * time.Hour)
+}
@@ -0,0 +1,40 @@
+package mask
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Tail keeps the last n characters of value and replaces the others with '*'
+func Tail(value string, n int) string {
+	count := utf8.RuneCountInString(value)
+	if value == "" || count <= n {
+		return strings.Repeat("*", count)
+	}
+	runes := []rune(value)
+	return strings.Repeat("*", count-n) + string(runes[count-n:])
+}
+
+// Email keeps the first character of the local part and the domain
+func Email(value string) string {
+	at := strings.LastIndex(value, "@")
+	if at < 1 {
+		return Tail(value, 0)
+	}
+	local := []rune(value[:at])
+	return string(local[0]) + strings.Repeat("*", len(local)-1) + value[at:]
+}
+
+// Year keeps the year of a YYYY-MM-DD date
+func Year(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+	return date[:4]
+}
+
+// Masked tells whether value went through Tail or Email, such a value sent
+// back by a client would overwrite the real one
+func Masked(value string) bool {
+	return strings.Contains(value, "*")
+}
@@ -0,0 +1,30 @@
+package mask
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTail(t *testing.T) {
+	assert.Equal(t, "*********1811", Tail("1103702071811", 4))
+	assert.Equal(t, "***", Tail("abc", 4))
+	assert.Equal(t, "", Tail("", 4))
+}
+
+func TestEmail(t *testing.T) {
+	assert.Equal(t, "s******@example.com", Email("somchai@example.com"))
+	assert.Equal(t, "*****", Email("@host"))
+}
+
+func TestYear(t *testing.T) {
+	assert.Equal(t, "1990", Year("1990-01-01"))
+	assert.Equal(t, "", Year(""))
+}
+
+func TestMasked(t *testing.T) {
+	assert.True(t, Masked(Tail("0812345678", 4)))
+	assert.True(t, Masked(Email("somchai@example.com")))
+	assert.False(t, Masked("somchai@example.com"))
+	assert.False(t, Masked(""))
+}
@@ -0,0 +1,134 @@
+package pii
+
+import (
+	"app/app/enum"
+	"app/app/util/jwt"
+	"app/app/util/mask"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/viper"
+)
+
+// PurposeHeader carries the purpose of use of a request
+const PurposeHeader = "X-Purpose-Of-Use"
+
+// Action is what a viewer gets of a field
+type Action string
+
+const (
+	ActionShow Action = "show"
+	ActionMask Action = "mask"
+	ActionOmit Action = "omit"
+)
+
+// Fields are the sensitive patient fields and how they are masked: the
+// identifiers and phone number keep their last 4 digits, the email its first
+// letter and domain, and the birth date its year
+var Fields = map[string]func(string) string{
+	"national_id":   func(v string) string { return mask.Tail(v, 4) },
+	"passport_id":   func(v string) string { return mask.Tail(v, 4) },
+	"phone_number":  func(v string) string { return mask.Tail(v, 4) },
+	"email":         mask.Email,
+	"date_of_birth": mask.Year,
+}
+
+// Policy holds the rules of each field, keyed by "role:purpose", "*:purpose",
+// "role" or "default", most specific first. A field without a matching rule is
+// shown to viewers with patient:read_sensitive and masked for the others.
+//
+//	{"national_id": {"default": "mask", "doctor:treatment": "show"}, "email": {"*:research": "omit"}}
+type Policy map[string]map[string]Action
+
+// Parse reads a policy from JSON, an empty string is the empty policy
+func Parse(raw string) (Policy, error) {
+	policy := Policy{}
+	if strings.TrimSpace(raw) == "" {
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return nil, fmt.Errorf("masking policy: %w", err)
+	}
+	for field, rules := range policy {
+		if _, ok := Fields[field]; !ok {
+			return nil, fmt.Errorf("masking policy: unknown field %q", field)
+		}
+		for key, action := range rules {
+			if !slices.Contains([]Action{ActionShow, ActionMask, ActionOmit}, action) {
+				return nil, fmt.Errorf("masking policy: %s %s: unknown action %q", field, key, action)
+			}
+			if _, purpose, ok := strings.Cut(key, ":"); ok && !enum.IsPurpose(enum.Purpose(purpose)) {
+				return nil, fmt.Errorf("masking policy: %s %s: unknown purpose %q", field, key, purpose)
+			}
+		}
+	}
+	return policy, nil
+}
+
+// Load parses PII_MASKING_POLICY
+func Load() (Policy, error) {
+	return Parse(viper.GetString("PII_MASKING_POLICY"))
+}
+
+var current atomic.Pointer[Policy]
+
+// Use makes the policy the one viewers follow
+func Use(policy Policy) {
+	current.Store(&policy)
+}
+
+// Viewer is a caller looking at patient data, and why
+type Viewer struct {
+	Role    string
+	Purpose enum.Purpose
+	// Sensitive shows the fields no rule covers
+	Sensitive bool
+}
+
+// ViewerOf is the viewer of the token's staff member for the purpose
+func ViewerOf(data jwt.ClaimData, purpose enum.Purpose) Viewer {
+	return Viewer{
+		Role:      data.Role,
+		Purpose:   purpose,
+		Sensitive: data.HasPermission(string(enum.PERMISSION_PATIENT_READ_SENSITIVE)),
+	}
+}
+
+// Action is what the policy of Use gives the viewer of the field
+func (v Viewer) Action(field string) Action {
+	if _, ok := Fields[field]; !ok {
+		return ActionShow
+	}
+	var rules map[string]Action
+	if policy := current.Load(); policy != nil {
+		rules = (*policy)[field]
+	}
+	keys := []string{v.Role, "default"}
+	if v.Purpose != "" {
+		keys = append([]string{v.Role + ":" + string(v.Purpose), "*:" + string(v.Purpose)}, keys...)
+	}
+	for _, key := range keys {
+		if action, ok := rules[key]; ok {
+			return action
+		}
+	}
+	if v.Sensitive {
+		return ActionShow
+	}
+	return ActionMask
+}
+
+// Apply returns the value of the field as the viewer sees it, "" when omitted
+func (v Viewer) Apply(field, value string) string {
+	switch v.Action(field) {
+	case ActionMask:
+		return Fields[field](value)
+	case ActionOmit:
+		return ""
+	default:
+		return value
+	}
+}
@@ -0,0 +1,73 @@
+package pii
+
+import (
+	"app/app/enum"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func usePolicy(t *testing.T, raw string) {
+	policy, err := Parse(raw)
+	require.NoError(t, err)
+	Use(policy)
+	t.Cleanup(func() { Use(nil) })
+}
+
+func TestParse(t *testing.T) {
+	policy, err := Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, policy)
+
+	_, err = Parse(`{"national_id": {"default": "mask", "doctor:treatment": "show"}}`)
+	assert.NoError(t, err)
+
+	cases := map[string]string{
+		"Not JSON":        `national_id=mask`,
+		"Unknown field":   `{"first_name": {"default": "mask"}}`,
+		"Unknown action":  `{"email": {"default": "hide"}}`,
+		"Unknown purpose": `{"email": {"doctor:marketing": "show"}}`,
+	}
+	for name, raw := range cases {
+		_, err := Parse(raw)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestViewer_Action(t *testing.T) {
+	t.Run("Without a rule read_sensitive decides", func(t *testing.T) {
+		Use(nil)
+		assert.Equal(t, ActionShow, Viewer{Role: "doctor", Sensitive: true}.Action("national_id"))
+		assert.Equal(t, ActionMask, Viewer{Role: "read_only"}.Action("national_id"))
+		assert.Equal(t, ActionShow, Viewer{Role: "read_only"}.Action("first_name"), "fields that are not sensitive are shown")
+	})
+
+	t.Run("Most specific rule wins", func(t *testing.T) {
+		usePolicy(t, `{"national_id": {
+			"default": "omit",
+			"nurse": "mask",
+			"*:research": "omit",
+			"doctor:treatment": "show"
+		}}`)
+
+		assert.Equal(t, ActionShow, Viewer{Role: "doctor", Purpose: enum.PURPOSE_TREATMENT}.Action("national_id"))
+		assert.Equal(t, ActionOmit, Viewer{Role: "doctor", Purpose: enum.PURPOSE_RESEARCH, Sensitive: true}.Action("national_id"))
+		assert.Equal(t, ActionMask, Viewer{Role: "nurse", Purpose: enum.PURPOSE_TREATMENT}.Action("national_id"))
+		assert.Equal(t, ActionOmit, Viewer{Role: "admin", Sensitive: true}.Action("national_id"))
+		assert.Equal(t, ActionShow, Viewer{Role: "admin", Sensitive: true}.Action("email"), "fields without rules keep the default")
+	})
+}
+
+func TestViewer_Apply(t *testing.T) {
+	usePolicy(t, `{"email": {"*:research": "omit"}}`)
+
+	viewer := Viewer{Role: "read_only"}
+	assert.Equal(t, "*********1811", viewer.Apply("national_id", "1103702071811"))
+	assert.Equal(t, "1990", viewer.Apply("date_of_birth", "1990-05-17"))
+	assert.Equal(t, "Somchai", viewer.Apply("first_name", "Somchai"))
+
+	researcher := Viewer{Role: "doctor", Purpose: enum.PURPOSE_RESEARCH, Sensitive: true}
+	assert.Empty(t, researcher.Apply("email", "somchai@example.com"))
+	assert.Equal(t, "1103702071811", researcher.Apply("national_id", "1103702071811"))
+}
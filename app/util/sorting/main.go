@@ -0,0 +1,99 @@
+package sorting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidSort = errors.New("invalid-sort")
+
+// MaxFields bounds the number of keys of one sort
+const MaxFields = 5
+
+// Field is one key of a parsed sort
+type Field struct {
+	Name   string
+	Column string
+	Desc   bool
+}
+
+// String renders the field the way Parse reads it
+func (f Field) String() string {
+	if f.Desc {
+		return "-" + f.Name
+	}
+	return f.Name
+}
+
+// Format renders fields the way Parse reads them
+func Format(fields []Field) string {
+	keys := make([]string, len(fields))
+	for i, field := range fields {
+		keys[i] = field.String()
+	}
+	return strings.Join(keys, ",")
+}
+
+// Spec maps the public field names a list can be sorted by onto their columns
+type Spec map[string]string
+
+// Parse reads a sort such as "last_name_en,-date_of_birth", a leading "-" sorts
+// descending and an optional "+" ascending
+func (s Spec) Parse(raw string) ([]Field, error) {
+	fields := []Field{}
+	seen := map[string]bool{}
+	for _, key := range strings.Split(raw, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		field := Field{Name: key}
+		switch key[0] {
+		case '-':
+			field.Name, field.Desc = key[1:], true
+		case '+':
+			field.Name = key[1:]
+		}
+		column, ok := s[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidSort, field.Name)
+		}
+		if seen[field.Name] {
+			return nil, fmt.Errorf("%w: field %q given twice", ErrInvalidSort, field.Name)
+		}
+		seen[field.Name] = true
+		field.Column = column
+		fields = append(fields, field)
+	}
+	if len(fields) > MaxFields {
+		return nil, fmt.Errorf("%w: at most %d fields", ErrInvalidSort, MaxFields)
+	}
+	return fields, nil
+}
+
+// Legacy turns a sort_by/order_by pair into a sort, order is asc or desc
+func Legacy(sortBy, order string) (string, error) {
+	if sortBy == "" {
+		return "", nil
+	}
+	switch strings.ToLower(order) {
+	case "", "asc":
+		return sortBy, nil
+	case "desc":
+		return "-" + sortBy, nil
+	default:
+		return "", fmt.Errorf("%w: unknown direction %q", ErrInvalidSort, order)
+	}
+}
+
+// Names returns the field names of the spec in alphabetical order
+func (s Spec) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
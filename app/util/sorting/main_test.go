@@ -0,0 +1,65 @@
+package sorting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var spec = Spec{
+	"last_name_en":  "last_name_en",
+	"date_of_birth": "date_of_birth",
+	"hn":            "patient_hn",
+}
+
+func TestSpec_Parse(t *testing.T) {
+	t.Run("Multiple keys", func(t *testing.T) {
+		fields, err := spec.Parse("last_name_en, -date_of_birth,+hn")
+		require.NoError(t, err)
+		assert.Equal(t, []Field{
+			{Name: "last_name_en", Column: "last_name_en"},
+			{Name: "date_of_birth", Column: "date_of_birth", Desc: true},
+			{Name: "hn", Column: "patient_hn"},
+		}, fields)
+		assert.Equal(t, "last_name_en,-date_of_birth,hn", Format(fields))
+	})
+
+	t.Run("Empty sort", func(t *testing.T) {
+		fields, err := spec.Parse("")
+		require.NoError(t, err)
+		assert.Empty(t, fields)
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		_, err := spec.Parse("last_name_en;DROP TABLE patients")
+		assert.ErrorIs(t, err, ErrInvalidSort)
+	})
+
+	t.Run("Column names are not accepted", func(t *testing.T) {
+		_, err := spec.Parse("patient_hn")
+		assert.ErrorIs(t, err, ErrInvalidSort)
+	})
+
+	t.Run("Duplicate field", func(t *testing.T) {
+		_, err := spec.Parse("hn,-hn")
+		assert.ErrorIs(t, err, ErrInvalidSort)
+	})
+}
+
+func TestLegacy(t *testing.T) {
+	sort, err := Legacy("created_at", "DESC")
+	require.NoError(t, err)
+	assert.Equal(t, "-created_at", sort)
+
+	sort, err = Legacy("created_at", "asc")
+	require.NoError(t, err)
+	assert.Equal(t, "created_at", sort)
+
+	_, err = Legacy("created_at", "sideways")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func TestSpec_Names(t *testing.T) {
+	assert.Equal(t, []string{"date_of_birth", "hn", "last_name_en"}, spec.Names())
+}
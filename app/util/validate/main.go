@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"app/app/enum"
+	"regexp"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+var (
+	passportRegex = regexp.MustCompile(`^[A-Z0-9]{6,9}$`)
+	minBirthDate  = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// NationalID checks a 13 digit Thai citizen ID and its mod 11 check digit
+func NationalID(id string) bool {
+	if len(id) != 13 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 13; i++ {
+		if id[i] < '0' || id[i] > '9' {
+			return false
+		}
+		if i < 12 {
+			sum += int(id[i]-'0') * (13 - i)
+		}
+	}
+	return (11-sum%11)%10 == int(id[12]-'0')
+}
+
+// Passport checks an upper-case passport number of 6 to 9 letters and digits
+func Passport(id string) bool {
+	return passportRegex.MatchString(id)
+}
+
+func Gender(code string) bool {
+	return enum.IsGender(enum.Gender(code))
+}
+
+// DateOfBirth parses a YYYY-MM-DD birth date that is neither in the future nor before 1900
+func DateOfBirth(value string) (time.Time, bool) {
+	dob, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if dob.Before(minBirthDate) || dob.After(time.Now()) {
+		return time.Time{}, false
+	}
+	return dob, true
+}
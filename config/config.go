@@ -27,7 +27,22 @@ func app() {
 	conf("DB_PASSWORD", "secret")
 
 	conf("JWT_SECRET", "secret")
-	conf("JWT_DURATION", 720)
+	conf("JWT_ACCESS_DURATION", 15)
+	conf("JWT_REFRESH_DURATION", 720)
 
 	conf("HTTP_JSON_NAMING", "camel_case")
+	conf("PAGINATION_MAX_SIZE", 100)
+
+	conf("HIS_ADAPTERS", "")
+	conf("HIS_CACHE_TTL", "24h")
+
+	conf("HN_FORMAT", "HN{yyyy}{seq:6}")
+
+	conf("AUDIT_RETENTION_DAYS", 1825)
+
+	conf("FIELD_ENCRYPTION_KEYS", "")
+	conf("FIELD_ENCRYPTION_KEY_VERSION", 0)
+	conf("BLIND_INDEX_KEY", "")
+
+	conf("PII_MASKING_POLICY", "")
 }
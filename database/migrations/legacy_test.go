@@ -0,0 +1,115 @@
+package migrations_test
+
+import (
+	"app/app/helper/testhelper"
+	"app/app/modules/patient"
+	"app/database/migrations"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/migrate"
+)
+
+// legacySchema is what the model sync created before the migrations existed
+var legacySchema = []string{
+	`CREATE TABLE "staffs" (
+		"id" uuid NOT NULL DEFAULT gen_random_uuid(),
+		"username" VARCHAR NOT NULL UNIQUE,
+		"password" VARCHAR NOT NULL,
+		"hospital" VARCHAR NOT NULL,
+		"created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+		"updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+		"deleted_at" TIMESTAMPTZ,
+		PRIMARY KEY ("id")
+	)`,
+	`CREATE TABLE "patients" (
+		"id" uuid NOT NULL DEFAULT gen_random_uuid(),
+		"first_name_th" VARCHAR, "middle_name_th" VARCHAR, "last_name_th" VARCHAR,
+		"first_name_en" VARCHAR, "middle_name_en" VARCHAR, "last_name_en" VARCHAR,
+		"date_of_birth" date,
+		"patient_hn" VARCHAR,
+		"national_id" VARCHAR UNIQUE,
+		"passport_id" VARCHAR UNIQUE,
+		"phone_number" VARCHAR,
+		"email" VARCHAR,
+		"gender" char(1),
+		"hospital" VARCHAR NOT NULL,
+		"created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+		"updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+		"deleted_at" TIMESTAMPTZ,
+		PRIMARY KEY ("id")
+	)`,
+	`INSERT INTO "staffs" ("username", "password", "hospital") VALUES ('legacy', 'x', 'hospital-a')`,
+	`INSERT INTO "patients" ("national_id", "patient_hn", "hospital") VALUES ('1103702071811', 'HN1', 'hospital-a')`,
+}
+
+// legacyDB is a schema of its own on the test database, dropped when the test ends
+func legacyDB(t *testing.T) *bun.DB {
+	t.Helper()
+	dsn := os.Getenv(testhelper.DatabaseURL)
+	if dsn == "" {
+		t.Skipf("%s is not set", testhelper.DatabaseURL)
+	}
+	ctx := context.Background()
+	schema := "legacy_" + uuid.NewString()[:8]
+
+	config, err := pgx.ParseConfig(dsn)
+	require.NoError(t, err)
+	admin := bun.NewDB(stdlib.OpenDB(*config), pgdialect.New())
+	t.Cleanup(func() { admin.Close() })
+	_, err = admin.ExecContext(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE") })
+
+	// public stays on the path for the extensions
+	config.RuntimeParams["search_path"] = schema + ", public"
+	db := bun.NewDB(stdlib.OpenDB(*config), pgdialect.New())
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrations_LegacySchema(t *testing.T) {
+	db := legacyDB(t)
+	ctx := context.Background()
+	for _, statement := range legacySchema {
+		_, err := db.ExecContext(ctx, statement)
+		require.NoError(t, err)
+	}
+
+	migrator := migrate.NewMigrator(db, migrations.Migrations, migrate.WithMarkAppliedOnSuccess(true))
+	require.NoError(t, migrator.Init(ctx))
+	// the plaintext patient has no blind indexes until it is re-encrypted
+	_, err := migrator.Migrate(ctx)
+	require.ErrorContains(t, err, "reencrypt-patients")
+	testhelper.UseKeyring(t)
+	patients := patient.NewService(db, nil, nil)
+	unindexed, err := patients.Unindexed(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, unindexed)
+	_, err = patients.Reencrypt(ctx, false)
+	require.NoError(t, err)
+	_, err = migrator.Migrate(ctx)
+	require.NoError(t, err)
+
+	var role string
+	require.NoError(t, db.NewRaw(`SELECT "role" FROM "staffs" WHERE "username" = 'legacy'`).Scan(ctx, &role))
+	assert.Equal(t, "read_only", role)
+
+	var synced int
+	require.NoError(t, db.NewRaw(`SELECT count(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'patients' AND column_name = 'synced_at'`).Scan(ctx, &synced))
+	assert.Equal(t, 1, synced)
+
+	// the same national ID at a second hospital no longer breaks a global constraint
+	_, err = db.ExecContext(ctx, `INSERT INTO "hospitals" ("code", "name_th", "name_en") VALUES ('hospital-b', 'b', 'b')`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO "patients" ("national_id", "patient_hn", "hospital") VALUES ('1103702071811', 'HN1', 'hospital-b')`)
+	assert.NoError(t, err)
+}
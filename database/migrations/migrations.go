@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"embed"
+
+	"github.com/uptrace/bun/migrate"
+)
+
+// Directory is where `migrate create` writes new migration files
+const Directory = "database/migrations/sql"
+
+//go:embed sql/*.sql
+var sqlMigrations embed.FS
+
+// Migrations are the versioned schema changes in sql/, applied in the order of
+// their timestamp prefix. A migration is a NAME.up.sql and NAME.down.sql pair,
+// the .tx. variants run in a transaction.
+var Migrations = migrate.NewMigrations(migrate.WithMigrationsDirectory(Directory))
+
+func init() {
+	if err := Migrations.Discover(sqlMigrations); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationsArePaired(t *testing.T) {
+	sorted := Migrations.Sorted()
+	assert.NotEmpty(t, sorted)
+	for _, migration := range sorted {
+		assert.NotNil(t, migration.Up, "%s has no up migration", migration)
+		assert.NotNil(t, migration.Down, "%s has no down migration", migration)
+	}
+}
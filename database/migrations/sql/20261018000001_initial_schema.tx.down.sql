@@ -0,0 +1,33 @@
+DROP TABLE IF EXISTS "hn_sequences";
+
+--bun:split
+
+DROP TABLE IF EXISTS "patients";
+
+--bun:split
+
+DROP TABLE IF EXISTS "revoked_tokens";
+
+--bun:split
+
+DROP TABLE IF EXISTS "refresh_tokens";
+
+--bun:split
+
+DROP TABLE IF EXISTS "staffs";
+
+--bun:split
+
+DROP TABLE IF EXISTS "role_permissions";
+
+--bun:split
+
+DROP TABLE IF EXISTS "permissions";
+
+--bun:split
+
+DROP TABLE IF EXISTS "roles";
+
+--bun:split
+
+DROP TABLE IF EXISTS "hospitals";
@@ -0,0 +1,235 @@
+-- Schema previously created by the model sync. Every statement is idempotent so
+-- databases created that way adopt this migration without losing data.
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "hospitals" (
+    "code" VARCHAR NOT NULL,
+    "name_th" VARCHAR NOT NULL,
+    "name_en" VARCHAR NOT NULL,
+    "status" VARCHAR NOT NULL DEFAULT 'active',
+    "api" jsonb,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("code")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "roles" (
+    "code" VARCHAR NOT NULL,
+    "name" VARCHAR NOT NULL,
+    "description" VARCHAR,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("code")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "permissions" (
+    "code" VARCHAR NOT NULL,
+    "description" VARCHAR,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("code")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "role_permissions" (
+    "role" VARCHAR NOT NULL,
+    "permission" VARCHAR NOT NULL,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("role", "permission")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "staffs" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "username" VARCHAR NOT NULL,
+    "password" VARCHAR NOT NULL,
+    "hospital" VARCHAR NOT NULL,
+    "role" VARCHAR NOT NULL DEFAULT 'read_only',
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "deleted_at" TIMESTAMPTZ,
+    PRIMARY KEY ("id"),
+    UNIQUE ("username")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "staff_id" uuid NOT NULL,
+    "family_id" uuid NOT NULL,
+    "token_hash" VARCHAR NOT NULL,
+    "access_uuid" VARCHAR NOT NULL,
+    "expires_at" BIGINT NOT NULL,
+    "revoked_at" BIGINT,
+    "replaced_by" uuid,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("id"),
+    UNIQUE ("token_hash")
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "refresh_tokens_staff_id_idx" ON "refresh_tokens" ("staff_id");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "refresh_tokens_family_id_idx" ON "refresh_tokens" ("family_id");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "refresh_tokens_access_uuid_idx" ON "refresh_tokens" ("access_uuid");
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "revoked_tokens" (
+    "uuid" VARCHAR NOT NULL,
+    "staff_id" uuid NOT NULL,
+    "expires_at" BIGINT NOT NULL,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("uuid")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "patients" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "first_name_th" VARCHAR,
+    "middle_name_th" VARCHAR,
+    "last_name_th" VARCHAR,
+    "first_name_en" VARCHAR,
+    "middle_name_en" VARCHAR,
+    "last_name_en" VARCHAR,
+    "date_of_birth" date,
+    "patient_hn" VARCHAR,
+    "national_id" VARCHAR,
+    "passport_id" VARCHAR,
+    "phone_number" VARCHAR,
+    "email" VARCHAR,
+    "gender" char(1),
+    "hospital" VARCHAR NOT NULL,
+    "synced_at" BIGINT,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "deleted_at" TIMESTAMPTZ,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "patients_hospital_patient_hn_key" UNIQUE ("patient_hn", "hospital"),
+    CONSTRAINT "patients_national_id_hospital_key" UNIQUE ("national_id", "hospital"),
+    CONSTRAINT "patients_passport_id_hospital_key" UNIQUE ("passport_id", "hospital")
+);
+
+--bun:split
+
+-- columns added to the models after the model sync created the tables, which it
+-- never altered
+ALTER TABLE "staffs" ADD COLUMN IF NOT EXISTS "role" VARCHAR NOT NULL DEFAULT 'read_only';
+
+--bun:split
+
+ALTER TABLE "patients" ADD COLUMN IF NOT EXISTS "synced_at" BIGINT;
+
+--bun:split
+
+-- the model sync made national ID and passport ID unique across hospitals, they
+-- are unique per hospital, and so is the HN
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_national_id_key";
+
+--bun:split
+
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_passport_id_key";
+
+--bun:split
+
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_hospital_patient_hn_key";
+
+--bun:split
+
+ALTER TABLE "patients" ADD CONSTRAINT "patients_hospital_patient_hn_key" UNIQUE ("patient_hn", "hospital");
+
+--bun:split
+
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_national_id_hospital_key";
+
+--bun:split
+
+ALTER TABLE "patients" ADD CONSTRAINT "patients_national_id_hospital_key" UNIQUE ("national_id", "hospital");
+
+--bun:split
+
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_passport_id_hospital_key";
+
+--bun:split
+
+ALTER TABLE "patients" ADD CONSTRAINT "patients_passport_id_hospital_key" UNIQUE ("passport_id", "hospital");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_first_name_idx" ON "patients" ("first_name_th", "first_name_en");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_middle_name_idx" ON "patients" ("middle_name_th", "middle_name_en");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_last_name_idx" ON "patients" ("last_name_th", "last_name_en");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_date_of_birth_idx" ON "patients" ("date_of_birth");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_email_idx" ON "patients" ("email");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_phone_number_idx" ON "patients" ("phone_number");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_hospital_idx" ON "patients" ("hospital");
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "hn_sequences" (
+    "hospital" VARCHAR NOT NULL,
+    "period" VARCHAR NOT NULL,
+    "value" BIGINT NOT NULL,
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("hospital", "period")
+);
+
+--bun:split
+
+-- hospitals already referenced by free text are kept, named after their code
+INSERT INTO "hospitals" ("code", "name_th", "name_en")
+SELECT "hospital", "hospital", "hospital" FROM "staffs"
+UNION SELECT "hospital", "hospital", "hospital" FROM "patients"
+ON CONFLICT DO NOTHING;
+
+--bun:split
+
+ALTER TABLE "staffs" DROP CONSTRAINT IF EXISTS "staffs_hospital_fkey";
+
+--bun:split
+
+ALTER TABLE "staffs" ADD CONSTRAINT "staffs_hospital_fkey"
+    FOREIGN KEY ("hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE;
+
+--bun:split
+
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_hospital_fkey";
+
+--bun:split
+
+ALTER TABLE "patients" ADD CONSTRAINT "patients_hospital_fkey"
+    FOREIGN KEY ("hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE;
@@ -0,0 +1,22 @@
+-- pg_trgm is left installed, other objects may use it
+DROP INDEX IF EXISTS "patients_phone_number_pattern_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_passport_id_pattern_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_national_id_pattern_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_patient_hn_pattern_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_email_trgm_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_search_name_trgm_idx";
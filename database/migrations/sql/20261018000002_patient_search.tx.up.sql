@@ -0,0 +1,27 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+--bun:split
+
+-- the expression has to match patient.searchName verbatim
+CREATE INDEX IF NOT EXISTS "patients_search_name_trgm_idx" ON "patients"
+    USING gin ((lower(coalesce(first_name_th, '') || coalesce(' ' || nullif(middle_name_th, ''), '') || ' ' || coalesce(last_name_th, '') || ' ' || coalesce(first_name_en, '') || coalesce(' ' || nullif(middle_name_en, ''), '') || ' ' || coalesce(last_name_en, ''))) gin_trgm_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_email_trgm_idx" ON "patients" USING gin (lower(email) gin_trgm_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_patient_hn_pattern_idx" ON "patients" ("patient_hn" varchar_pattern_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_national_id_pattern_idx" ON "patients" ("national_id" varchar_pattern_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_passport_id_pattern_idx" ON "patients" ("passport_id" varchar_pattern_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_phone_number_pattern_idx" ON "patients" ("phone_number" varchar_pattern_ops);
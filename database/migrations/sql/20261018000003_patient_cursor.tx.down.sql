@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "patients_hospital_created_at_idx";
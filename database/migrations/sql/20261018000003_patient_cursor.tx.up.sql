@@ -0,0 +1,3 @@
+-- the default patient list order, also walked backward for -created_at
+CREATE INDEX IF NOT EXISTS "patients_hospital_created_at_idx" ON "patients" ("hospital", "created_at", "id")
+    WHERE "deleted_at" IS NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "import_job_errors";
+
+--bun:split
+
+DROP TABLE IF EXISTS "import_jobs";
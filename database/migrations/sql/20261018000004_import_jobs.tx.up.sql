@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS "import_jobs" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "hospital" VARCHAR NOT NULL,
+    "format" VARCHAR NOT NULL,
+    "file_name" VARCHAR,
+    "status" VARCHAR NOT NULL DEFAULT 'pending',
+    "total" BIGINT NOT NULL DEFAULT 0,
+    "processed" BIGINT NOT NULL DEFAULT 0,
+    "inserted" BIGINT NOT NULL DEFAULT 0,
+    "updated" BIGINT NOT NULL DEFAULT 0,
+    "failed" BIGINT NOT NULL DEFAULT 0,
+    "error" VARCHAR,
+    "created_by" uuid,
+    "started_at" BIGINT,
+    "finished_at" BIGINT,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "import_jobs_hospital_fkey" FOREIGN KEY ("hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "import_jobs_hospital_created_at_idx" ON "import_jobs" ("hospital", "created_at");
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "import_job_errors" (
+    "id" BIGSERIAL NOT NULL,
+    "job_id" uuid NOT NULL,
+    "row" BIGINT NOT NULL,
+    "identifier" VARCHAR,
+    "message" VARCHAR NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "import_job_errors_job_id_fkey" FOREIGN KEY ("job_id") REFERENCES "import_jobs" ("id") ON DELETE CASCADE
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "import_job_errors_job_id_row_idx" ON "import_job_errors" ("job_id", "row");
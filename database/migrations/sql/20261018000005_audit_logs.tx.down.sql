@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "audit_logs";
+
+--bun:split
+
+DROP FUNCTION IF EXISTS "audit_logs_append_only"();
@@ -0,0 +1,56 @@
+-- no foreign keys: the log keeps the hospital and staff as they were, and a
+-- cascading update would be refused by the append-only trigger anyway
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" BIGSERIAL NOT NULL,
+    "request_id" VARCHAR NOT NULL,
+    "staff_id" uuid,
+    "hospital" VARCHAR NOT NULL,
+    "action" VARCHAR NOT NULL,
+    "patient_ids" uuid[],
+    "query" JSONB,
+    "method" VARCHAR NOT NULL,
+    "path" VARCHAR NOT NULL,
+    "status" INT NOT NULL,
+    "client_ip" VARCHAR,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("id")
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "audit_logs_hospital_created_at_idx" ON "audit_logs" ("hospital", "created_at");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "audit_logs_staff_id_created_at_idx" ON "audit_logs" ("staff_id", "created_at");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "audit_logs_patient_ids_idx" ON "audit_logs" USING gin ("patient_ids");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "audit_logs_created_at_idx" ON "audit_logs" ("created_at");
+
+--bun:split
+
+-- rows are never updated, and only deleted by the retention purge, which sets
+-- app.audit_purge for its own transaction
+CREATE OR REPLACE FUNCTION "audit_logs_append_only"() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('app.audit_purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+--bun:split
+
+CREATE TRIGGER "audit_logs_append_only" BEFORE UPDATE OR DELETE ON "audit_logs"
+    FOR EACH ROW EXECUTE FUNCTION "audit_logs_append_only"();
+
+--bun:split
+
+CREATE TRIGGER "audit_logs_no_truncate" BEFORE TRUNCATE ON "audit_logs"
+    FOR EACH STATEMENT EXECUTE FUNCTION "audit_logs_append_only"();
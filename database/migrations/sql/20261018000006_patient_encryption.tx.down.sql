@@ -0,0 +1,38 @@
+DROP INDEX IF EXISTS "patients_hospital_phone_number_bidx_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_hospital_email_bidx_idx";
+
+--bun:split
+
+ALTER TABLE "patients"
+    DROP COLUMN IF EXISTS "email_bidx",
+    DROP COLUMN IF EXISTS "phone_number_bidx",
+    DROP COLUMN IF EXISTS "passport_id_bidx",
+    DROP COLUMN IF EXISTS "national_id_bidx";
+
+--bun:split
+
+-- the columns still hold ciphertext, which the restored indexes cover as text
+CREATE INDEX IF NOT EXISTS "patients_phone_number_pattern_idx" ON "patients" ("phone_number" varchar_pattern_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_passport_id_pattern_idx" ON "patients" ("passport_id" varchar_pattern_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_national_id_pattern_idx" ON "patients" ("national_id" varchar_pattern_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_email_trgm_idx" ON "patients" USING gin (lower(email) gin_trgm_ops);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_phone_number_idx" ON "patients" ("phone_number");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_email_idx" ON "patients" ("email");
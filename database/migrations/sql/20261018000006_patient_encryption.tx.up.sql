@@ -0,0 +1,41 @@
+-- national_id, passport_id, phone_number and email hold ciphertext from here on,
+-- they are looked up by the blind indexes. Rows written before are filled in by
+-- the reencrypt-patients command, the uniqueness per hospital moves to the blind
+-- indexes once they are (patient_blind_index_keys).
+ALTER TABLE "patients"
+    ADD COLUMN IF NOT EXISTS "national_id_bidx" VARCHAR,
+    ADD COLUMN IF NOT EXISTS "passport_id_bidx" VARCHAR,
+    ADD COLUMN IF NOT EXISTS "phone_number_bidx" VARCHAR,
+    ADD COLUMN IF NOT EXISTS "email_bidx" VARCHAR;
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_email_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_phone_number_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_email_trgm_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_national_id_pattern_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_passport_id_pattern_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "patients_phone_number_pattern_idx";
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_hospital_email_bidx_idx" ON "patients" ("hospital", "email_bidx");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patients_hospital_phone_number_bidx_idx" ON "patients" ("hospital", "phone_number_bidx");
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "audit_logs_unmask_created_at_idx";
+
+--bun:split
+
+ALTER TABLE "audit_logs"
+    DROP COLUMN IF EXISTS "reason",
+    DROP COLUMN IF EXISTS "purpose";
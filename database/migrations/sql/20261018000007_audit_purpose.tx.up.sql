@@ -0,0 +1,9 @@
+ALTER TABLE "audit_logs"
+    ADD COLUMN IF NOT EXISTS "purpose" VARCHAR,
+    ADD COLUMN IF NOT EXISTS "reason" TEXT;
+
+--bun:split
+
+-- break-glass access is reviewed on its own
+CREATE INDEX IF NOT EXISTS "audit_logs_unmask_created_at_idx" ON "audit_logs" ("hospital", "created_at")
+    WHERE "action" = 'patient.unmask';
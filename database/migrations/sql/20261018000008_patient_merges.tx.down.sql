@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "patients_hospital_date_of_birth_idx";
+
+--bun:split
+
+DROP TABLE IF EXISTS "patient_merges";
+
+--bun:split
+
+ALTER TABLE "patients" DROP COLUMN IF EXISTS "merged_into";
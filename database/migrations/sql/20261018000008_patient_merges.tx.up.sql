@@ -0,0 +1,36 @@
+ALTER TABLE "patients" ADD COLUMN IF NOT EXISTS "merged_into" uuid;
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "patient_merges" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "hospital" VARCHAR NOT NULL,
+    "survivor_id" uuid NOT NULL,
+    "merged_id" uuid NOT NULL,
+    "fields" VARCHAR[],
+    "reason" TEXT,
+    "merged_by" uuid,
+    "snapshot" TEXT,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "patient_merges_hospital_fkey" FOREIGN KEY ("hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE,
+    CONSTRAINT "patient_merges_survivor_id_fkey" FOREIGN KEY ("survivor_id") REFERENCES "patients" ("id"),
+    CONSTRAINT "patient_merges_merged_id_fkey" FOREIGN KEY ("merged_id") REFERENCES "patients" ("id")
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patient_merges_hospital_created_at_idx" ON "patient_merges" ("hospital", "created_at");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patient_merges_survivor_id_idx" ON "patient_merges" ("survivor_id");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patient_merges_merged_id_idx" ON "patient_merges" ("merged_id");
+
+--bun:split
+
+-- the duplicate finder pairs the patients of a hospital born on the same day
+CREATE INDEX IF NOT EXISTS "patients_hospital_date_of_birth_idx" ON "patients" ("hospital", "date_of_birth");
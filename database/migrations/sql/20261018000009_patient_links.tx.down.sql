@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "record_requests";
+
+--bun:split
+
+DROP TABLE IF EXISTS "patient_links";
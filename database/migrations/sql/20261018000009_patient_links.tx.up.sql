@@ -0,0 +1,48 @@
+-- the master patient index: records of one person across hospitals share a person_id
+CREATE TABLE IF NOT EXISTS "patient_links" (
+    "patient_id" uuid NOT NULL,
+    "person_id" uuid NOT NULL,
+    "hospital" VARCHAR NOT NULL,
+    "matched_on" VARCHAR NOT NULL,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("patient_id"),
+    CONSTRAINT "patient_links_patient_id_fkey" FOREIGN KEY ("patient_id") REFERENCES "patients" ("id") ON DELETE CASCADE,
+    CONSTRAINT "patient_links_hospital_fkey" FOREIGN KEY ("hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patient_links_person_id_idx" ON "patient_links" ("person_id");
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "record_requests" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "hospital" VARCHAR NOT NULL,
+    "patient_id" uuid NOT NULL,
+    "source_hospital" VARCHAR NOT NULL,
+    "source_patient_id" uuid NOT NULL,
+    "purpose" VARCHAR,
+    "reason" TEXT NOT NULL,
+    "status" VARCHAR NOT NULL DEFAULT 'pending',
+    "requested_by" uuid,
+    "decided_by" uuid,
+    "decided_at" BIGINT,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "record_requests_hospital_fkey" FOREIGN KEY ("hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE,
+    CONSTRAINT "record_requests_source_hospital_fkey" FOREIGN KEY ("source_hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE,
+    CONSTRAINT "record_requests_patient_id_fkey" FOREIGN KEY ("patient_id") REFERENCES "patients" ("id"),
+    CONSTRAINT "record_requests_source_patient_id_fkey" FOREIGN KEY ("source_patient_id") REFERENCES "patients" ("id")
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "record_requests_hospital_created_at_idx" ON "record_requests" ("hospital", "created_at");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "record_requests_source_hospital_created_at_idx" ON "record_requests" ("source_hospital", "created_at");
+
@@ -0,0 +1,25 @@
+-- what patients agreed to; withdrawn consents are kept with revoked_at set
+CREATE TABLE IF NOT EXISTS "patient_consents" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "hospital" VARCHAR NOT NULL,
+    "patient_id" uuid NOT NULL,
+    "type" VARCHAR NOT NULL,
+    "scope" VARCHAR NOT NULL DEFAULT '*',
+    "purposes" VARCHAR[],
+    "valid_from" BIGINT NOT NULL,
+    "valid_until" BIGINT,
+    "evidence" VARCHAR,
+    "recorded_by" uuid,
+    "revoked_at" BIGINT,
+    "revoked_by" uuid,
+    "revoke_reason" TEXT,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    "updated_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "patient_consents_hospital_fkey" FOREIGN KEY ("hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE,
+    CONSTRAINT "patient_consents_patient_id_fkey" FOREIGN KEY ("patient_id") REFERENCES "patients" ("id")
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "patient_consents_patient_id_type_idx" ON "patient_consents" ("patient_id", "type");
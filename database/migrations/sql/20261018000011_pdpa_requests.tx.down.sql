@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "pdpa_requests";
+
+--bun:split
+
+ALTER TABLE "patients" DROP COLUMN IF EXISTS "anonymized_at";
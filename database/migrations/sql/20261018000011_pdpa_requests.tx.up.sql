@@ -0,0 +1,21 @@
+ALTER TABLE "patients" ADD COLUMN IF NOT EXISTS "anonymized_at" BIGINT;
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "pdpa_requests" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "hospital" VARCHAR NOT NULL,
+    "patient_id" uuid NOT NULL,
+    "type" VARCHAR NOT NULL,
+    "patient_ids" uuid[],
+    "reason" TEXT,
+    "requested_by" uuid,
+    "created_at" BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "pdpa_requests_hospital_fkey" FOREIGN KEY ("hospital") REFERENCES "hospitals" ("code") ON UPDATE CASCADE,
+    CONSTRAINT "pdpa_requests_patient_id_fkey" FOREIGN KEY ("patient_id") REFERENCES "patients" ("id")
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "pdpa_requests_patient_id_idx" ON "pdpa_requests" ("patient_id");
@@ -0,0 +1,3 @@
+-- encrypted integrations cannot be read back as JSON, they have to be saved again
+ALTER TABLE "hospitals" ALTER COLUMN "api" TYPE jsonb
+    USING CASE WHEN "api" LIKE 'enc:%' THEN NULL ELSE "api"::jsonb END;
@@ -0,0 +1,4 @@
+-- the integration holds the HIS credentials, it is encrypted by the application
+-- from here on. Integrations saved before stay readable as plain JSON text until
+-- the reencrypt-patients command rewrites them.
+ALTER TABLE "hospitals" ALTER COLUMN "api" TYPE VARCHAR USING "api"::text;
@@ -0,0 +1,13 @@
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_passport_id_bidx_hospital_key";
+
+--bun:split
+
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_national_id_bidx_hospital_key";
+
+--bun:split
+
+ALTER TABLE "patients" ADD CONSTRAINT "patients_passport_id_hospital_key" UNIQUE ("passport_id", "hospital");
+
+--bun:split
+
+ALTER TABLE "patients" ADD CONSTRAINT "patients_national_id_hospital_key" UNIQUE ("national_id", "hospital");
@@ -0,0 +1,31 @@
+-- the blind indexes of rows stored before patient_encryption are filled in by
+-- the reencrypt-patients command, a unique constraint on them would let those
+-- rows be duplicated meanwhile
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM "patients"
+        WHERE ("national_id" IS NOT NULL AND "national_id_bidx" IS NULL)
+            OR ("passport_id" IS NOT NULL AND "passport_id_bidx" IS NULL)
+            OR ("phone_number" IS NOT NULL AND "phone_number_bidx" IS NULL)
+            OR ("email" IS NOT NULL AND "email_bidx" IS NULL)
+    ) THEN
+        RAISE EXCEPTION 'patients without blind indexes, run "go run . cmd reencrypt-patients" first';
+    END IF;
+END $$;
+
+--bun:split
+
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_national_id_hospital_key";
+
+--bun:split
+
+ALTER TABLE "patients" DROP CONSTRAINT IF EXISTS "patients_passport_id_hospital_key";
+
+--bun:split
+
+ALTER TABLE "patients" ADD CONSTRAINT "patients_national_id_bidx_hospital_key" UNIQUE ("national_id_bidx", "hospital");
+
+--bun:split
+
+ALTER TABLE "patients" ADD CONSTRAINT "patients_passport_id_bidx_hospital_key" UNIQUE ("passport_id_bidx", "hospital");
@@ -0,0 +1,15 @@
+-- rows are only updated by an erasure, which redacts the identifiers of the
+-- erased patient from the path and query and sets app.audit_redact for its own
+-- transaction; nothing else of a row may change
+CREATE OR REPLACE FUNCTION "audit_logs_append_only"() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('app.audit_purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    IF TG_OP = 'UPDATE' AND current_setting('app.audit_redact', true) = 'on'
+        AND to_jsonb(NEW) - 'path' - 'query' = to_jsonb(OLD) - 'path' - 'query' THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
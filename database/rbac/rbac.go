@@ -0,0 +1,50 @@
+// Package rbac keeps the roles, permissions and role_permissions tables in step
+// with enum.DefaultRolePermissions
+package rbac
+
+import (
+	"app/app/enum"
+	"app/app/model"
+	"context"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// Sync inserts the default roles, permissions and grants that are missing, so a
+// permission added to the matrix reaches existing databases. Rows beyond the
+// defaults are kept.
+func Sync(ctx context.Context, db bun.IDB) error {
+	matrix := enum.DefaultRolePermissions()
+
+	permissions := map[enum.Permission]bool{}
+	for role, perms := range matrix {
+		data := &model.Role{
+			Code: string(role),
+			Name: strings.ReplaceAll(string(role), "_", " "),
+		}
+		if _, err := db.NewInsert().Model(data).On("CONFLICT DO NOTHING").Exec(ctx); err != nil {
+			return err
+		}
+		for _, perm := range perms {
+			permissions[perm] = true
+		}
+	}
+
+	for perm := range permissions {
+		data := &model.Permission{Code: string(perm)}
+		if _, err := db.NewInsert().Model(data).On("CONFLICT DO NOTHING").Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	for role, perms := range matrix {
+		for _, perm := range perms {
+			data := &model.RolePermission{Role: string(role), Permission: string(perm)}
+			if _, err := db.NewInsert().Model(data).On("CONFLICT DO NOTHING").Exec(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
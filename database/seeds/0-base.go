@@ -6,13 +6,36 @@ import (
 	"github.com/uptrace/bun"
 )
 
-// "app/app/model"
+// Options controls the mock data generated by `migrate seed`
+type Options struct {
+	// Seed makes the generated data reproducible
+	Seed int64
+	// Hospitals is the number of generated hospitals, hospital-a onwards
+	Hospitals int
+	// Staff and Patients are generated per hospital
+	Staff    int
+	Patients int
+	// Password is shared by every generated staff account
+	Password string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Seed:      1,
+		Hospitals: 3,
+		Staff:     5,
+		Patients:  100,
+		Password:  "password123",
+	}
+}
 
 // Seeds Database seeds
-func Seeds(db *bun.DB) error {
+func Seeds(db *bun.DB, opts Options) error {
 
 	seeder := []func(*bun.DB) error{
-		mockUpSeed,
+		rbacSeed,
+		hospitalSeed,
+		mockUpSeed(opts),
 		// userSeed,
 		// teamSeed,
 	}
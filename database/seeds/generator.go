@@ -0,0 +1,147 @@
+package seeds
+
+import (
+	"app/app/enum"
+	"app/app/model"
+	"app/app/util/fieldcrypt"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type name struct {
+	TH string
+	EN string
+}
+
+var (
+	maleFirstNames = []name{
+		{"สมชาย", "Somchai"}, {"ประเสริฐ", "Prasert"}, {"วิชัย", "Wichai"}, {"สุรชัย", "Surachai"},
+		{"ธนากร", "Thanakorn"}, {"อนุชา", "Anucha"}, {"กิตติพงษ์", "Kittipong"}, {"ณัฐพล", "Nattapon"},
+		{"พงศกร", "Pongsakorn"}, {"ศุภชัย", "Supachai"}, {"ชยพล", "Chayapon"}, {"ธีรวัฒน์", "Teerawat"},
+	}
+	femaleFirstNames = []name{
+		{"สมหญิง", "Somying"}, {"สุภาพร", "Supaporn"}, {"วิไลวรรณ", "Wilaiwan"}, {"กาญจนา", "Kanchana"},
+		{"ปวีณา", "Paweena"}, {"นภัสสร", "Napatsorn"}, {"ศิริพร", "Siriporn"}, {"อรอุมา", "Onuma"},
+		{"พิมพ์ชนก", "Pimchanok"}, {"ณัฐธิดา", "Nattida"}, {"จิราพร", "Jiraporn"}, {"มณีรัตน์", "Maneerat"},
+	}
+	lastNames = []name{
+		{"ใจดี", "Jaidee"}, {"สุขสวัสดิ์", "Suksawat"}, {"ศรีสุข", "Srisuk"}, {"แสงทอง", "Saengthong"},
+		{"วงศ์ใหญ่", "Wongyai"}, {"บุญมา", "Boonma"}, {"ทองดี", "Thongdee"}, {"รัตนพันธ์", "Rattanaphan"},
+		{"พัฒนกุล", "Phatthanakun"}, {"เจริญผล", "Charoenphon"}, {"สมบูรณ์", "Somboon"}, {"ชัยมงคล", "Chaimongkol"},
+	}
+	foreignMaleFirstNames   = []string{"John", "Hiroshi", "Lukas", "Arjun", "Minh", "Daniel"}
+	foreignFemaleFirstNames = []string{"Emily", "Mei", "Sofia", "Priya", "Chloe", "Anna"}
+	foreignLastNames        = []string{"Smith", "Tanaka", "Mueller", "Kumar", "Nguyen", "Garcia", "Wang", "Brown"}
+
+	// hospitalLetters spell the letter of a generated hospital code in Thai
+	hospitalLetters = []string{
+		"เอ", "บี", "ซี", "ดี", "อี", "เอฟ", "จี", "เอช", "ไอ", "เจ", "เค", "แอล", "เอ็ม",
+		"เอ็น", "โอ", "พี", "คิว", "อาร์", "เอส", "ที", "ยู", "วี", "ดับเบิลยู", "เอ็กซ์", "วาย", "แซด",
+	}
+
+	minDateOfBirth = time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxDateOfBirth = time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// generator produces reproducible mock data. Each scope (a hospital's patients,
+// its staff) has its own source, so changing one volume leaves the others alone.
+type generator struct {
+	rnd *rand.Rand
+}
+
+func newGenerator(seed int64, scope string) *generator {
+	h := fnv.New64a()
+	h.Write([]byte(scope))
+	return &generator{rnd: rand.New(rand.NewSource(seed ^ int64(h.Sum64())))}
+}
+
+// seedID derives a stable uuid so a repeated run finds the rows it already inserted
+func seedID(seed int64, kind, hospital string, i int) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("seed:%d:%s:%s:%d", seed, kind, hospital, i))).String()
+}
+
+// mockHospital returns the i-th generated hospital, hospital-a being the first
+func mockHospital(i int) *model.Hospital {
+	letter := string(rune('a' + i))
+	return &model.Hospital{
+		Code:   "hospital-" + letter,
+		NameTH: "โรงพยาบาล " + hospitalLetters[i],
+		NameEN: "Hospital " + strings.ToUpper(letter),
+		Status: enum.STATUS_ACTIVE,
+	}
+}
+
+// nationalID returns a 13 digit Thai national ID with a valid mod 11 check digit
+func (g *generator) nationalID() string {
+	digits := make([]byte, 13)
+	digits[0] = byte('1' + g.rnd.Intn(8))
+	sum := int(digits[0]-'0') * 13
+	for i := 1; i < 12; i++ {
+		d := g.rnd.Intn(10)
+		digits[i] = byte('0' + d)
+		sum += d * (13 - i)
+	}
+	digits[12] = byte('0' + (11-sum%11)%10)
+	return string(digits)
+}
+
+// passportID returns two letters followed by seven digits
+func (g *generator) passportID() string {
+	return fmt.Sprintf("%c%c%07d", 'A'+g.rnd.Intn(26), 'A'+g.rnd.Intn(26), g.rnd.Intn(10000000))
+}
+
+// phoneNumber returns a Thai mobile number
+func (g *generator) phoneNumber() string {
+	prefixes := []string{"06", "08", "09"}
+	return fmt.Sprintf("%s%08d", prefixes[g.rnd.Intn(len(prefixes))], g.rnd.Intn(100000000))
+}
+
+func (g *generator) dateOfBirth() time.Time {
+	days := int(maxDateOfBirth.Sub(minDateOfBirth).Hours() / 24)
+	return minDateOfBirth.AddDate(0, 0, g.rnd.Intn(days+1))
+}
+
+// patient returns a Thai patient, or one in ten a foreigner identified by passport
+func (g *generator) patient(hospital string, i int) *model.Patient {
+	gender := enum.GENDER_MALE
+	if g.rnd.Intn(2) == 1 {
+		gender = enum.GENDER_FEMALE
+	}
+	patient := &model.Patient{
+		DateOfBirth: g.dateOfBirth(),
+		PhoneNumber: fieldcrypt.EncryptedString(g.phoneNumber()),
+		Gender:      string(gender),
+		Hospital:    hospital,
+	}
+
+	if g.rnd.Intn(10) == 0 {
+		firstNames := foreignMaleFirstNames
+		if gender == enum.GENDER_FEMALE {
+			firstNames = foreignFemaleFirstNames
+		}
+		patient.FirstNameEN = firstNames[g.rnd.Intn(len(firstNames))]
+		patient.LastNameEN = foreignLastNames[g.rnd.Intn(len(foreignLastNames))]
+		patient.PassportID = fieldcrypt.EncryptedString(g.passportID())
+	} else {
+		firstNames := maleFirstNames
+		if gender == enum.GENDER_FEMALE {
+			firstNames = femaleFirstNames
+		}
+		first := firstNames[g.rnd.Intn(len(firstNames))]
+		last := lastNames[g.rnd.Intn(len(lastNames))]
+		patient.FirstNameTH, patient.FirstNameEN = first.TH, first.EN
+		patient.LastNameTH, patient.LastNameEN = last.TH, last.EN
+		patient.NationalID = fieldcrypt.EncryptedString(g.nationalID())
+	}
+
+	if g.rnd.Intn(10) < 7 {
+		patient.Email = fieldcrypt.EncryptedString(fmt.Sprintf("%s.%s%d@example.com",
+			strings.ToLower(patient.FirstNameEN), strings.ToLower(patient.LastNameEN), i))
+	}
+	return patient
+}
@@ -0,0 +1,49 @@
+package seeds
+
+import (
+	"app/app/util/validate"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_IsDeterministic(t *testing.T) {
+	a := newGenerator(42, "patients:hospital-a")
+	b := newGenerator(42, "patients:hospital-a")
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, a.patient("hospital-a", i), b.patient("hospital-a", i))
+	}
+	assert.Equal(t, seedID(42, "patient", "hospital-a", 7), seedID(42, "patient", "hospital-a", 7))
+	assert.NotEqual(t, seedID(42, "patient", "hospital-a", 7), seedID(43, "patient", "hospital-a", 7))
+
+	other := newGenerator(42, "patients:hospital-b")
+	assert.NotEqual(t, newGenerator(42, "patients:hospital-a").patient("hospital-a", 0), other.patient("hospital-a", 0))
+}
+
+func TestGenerator_PatientsAreValid(t *testing.T) {
+	phone := regexp.MustCompile(`^0[689]\d{8}$`)
+	gen := newGenerator(1, "patients:hospital-a")
+	for i := 0; i < 1000; i++ {
+		patient := gen.patient("hospital-a", i)
+		if patient.NationalID != "" {
+			assert.True(t, validate.NationalID(string(patient.NationalID)), patient.NationalID)
+			assert.NotEmpty(t, patient.FirstNameTH)
+		} else {
+			assert.True(t, validate.Passport(string(patient.PassportID)), patient.PassportID)
+		}
+		assert.True(t, validate.Gender(patient.Gender))
+		_, ok := validate.DateOfBirth(patient.DateOfBirth.Format(validate.DateLayout))
+		assert.True(t, ok, patient.DateOfBirth)
+		assert.Regexp(t, phone, string(patient.PhoneNumber))
+		assert.NotEmpty(t, patient.FirstNameEN)
+		assert.NotEmpty(t, patient.LastNameEN)
+	}
+}
+
+func TestMockHospital(t *testing.T) {
+	first := mockHospital(0)
+	assert.Equal(t, "hospital-a", first.Code)
+	assert.Equal(t, "Hospital A", first.NameEN)
+	assert.Equal(t, "hospital-z", mockHospital(len(hospitalLetters)-1).Code)
+}
@@ -0,0 +1,21 @@
+package seeds
+
+import (
+	"app/app/enum"
+	"app/app/model"
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// hospitalSeed inserts the hospital of the legacy hospital-a integration
+func hospitalSeed(db *bun.DB) error {
+	data := &model.Hospital{
+		Code:   "hospital-a",
+		NameTH: "โรงพยาบาล เอ",
+		NameEN: "Hospital A",
+		Status: enum.STATUS_ACTIVE,
+	}
+	_, err := db.NewInsert().Model(data).On("CONFLICT DO NOTHING").Exec(context.Background())
+	return err
+}
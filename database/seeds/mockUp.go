@@ -1,10 +1,214 @@
 package seeds
 
 import (
+	"app/app/enum"
+	"app/app/model"
+	"app/app/util/fieldcrypt"
+	"app/app/util/hashing"
+	"app/app/util/hn"
+	"app/internal/logger"
+	"context"
+	"fmt"
+
 	"github.com/uptrace/bun"
 )
 
-func mockUpSeed(db *bun.DB) error {
+const patientBatchSize = 500
+
+// staffRoles are handed out in turn after the admin of each hospital
+var staffRoles = []enum.Role{enum.ROLE_DOCTOR, enum.ROLE_NURSE, enum.ROLE_REGISTRAR, enum.ROLE_READ_ONLY}
+
+// mockUpSeed generates hospitals with their staff and patients. The same options
+// always produce the same rows, and rows of an earlier run are left untouched.
+func mockUpSeed(opts Options) func(*bun.DB) error {
+	return func(db *bun.DB) error {
+		if opts.Hospitals < 1 {
+			return nil
+		}
+		if opts.Hospitals > len(hospitalLetters) {
+			return fmt.Errorf("at most %d hospitals can be generated", len(hospitalLetters))
+		}
+		ctx := context.Background()
+		format, err := hn.Load()
+		if err != nil {
+			return err
+		}
+		keyring, err := fieldcrypt.Load()
+		if err != nil {
+			return err
+		}
+		fieldcrypt.Use(keyring)
+
+		password := ""
+		if opts.Staff > 0 {
+			hash, err := hashing.HashPassword(opts.Password)
+			if err != nil {
+				return err
+			}
+			password = string(hash)
+		}
 
+		for i := 0; i < opts.Hospitals; i++ {
+			hospital := mockHospital(i)
+			if _, err := db.NewInsert().Model(hospital).On("CONFLICT DO NOTHING").Exec(ctx); err != nil {
+				return err
+			}
+			if err := seedStaff(ctx, db, hospital.Code, password, opts); err != nil {
+				return err
+			}
+			inserted, collided, err := seedPatients(ctx, db, format, hospital.Code, opts)
+			if err != nil {
+				return err
+			}
+			logger.Infof("Seeded %s: %d staff, %d patients added", hospital.Code, opts.Staff, inserted)
+			if collided > 0 {
+				logger.Errf("%s: %d generated patients skipped, their national ID or passport ID is taken", hospital.Code, collided)
+			}
+		}
+		return nil
+	}
+}
+
+// seedStaff creates <hospital>.admin01 and then one account per role in turn,
+// e.g. hospital-a.doctor02, all with the password of the options
+func seedStaff(ctx context.Context, db *bun.DB, hospital, password string, opts Options) error {
+	for i := 0; i < opts.Staff; i++ {
+		role := enum.ROLE_ADMIN
+		if i > 0 {
+			role = staffRoles[(i-1)%len(staffRoles)]
+		}
+		data := &model.Staff{
+			ID:       seedID(opts.Seed, "staff", hospital, i),
+			Username: fmt.Sprintf("%s.%s%02d", hospital, role, i+1),
+			Password: password,
+			Hospital: hospital,
+			Role:     string(role),
+		}
+		if _, err := db.NewInsert().Model(data).On("CONFLICT DO NOTHING").Exec(ctx); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// seedPatients adds the generated patients an earlier run did not. A generated
+// patient whose national ID or passport ID another patient of the hospital
+// already holds is skipped and counted as collided, before hospital numbers are
+// allocated.
+func seedPatients(ctx context.Context, db *bun.DB, format *hn.Format, hospital string, opts Options) (inserted, collided int, err error) {
+	gen := newGenerator(opts.Seed, "patients:"+hospital)
+	for start := 0; start < opts.Patients; start += patientBatchSize {
+		end := min(start+patientBatchSize, opts.Patients)
+		batch := make([]*model.Patient, 0, end-start)
+		ids := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			// always generated, so the random sequence does not depend on earlier runs
+			data := gen.patient(hospital, i)
+			data.ID = seedID(opts.Seed, "patient", hospital, i)
+			batch = append(batch, data)
+			ids = append(ids, data.ID)
+		}
+
+		// rows of an earlier run are skipped before hospital numbers are allocated
+		existing := []string{}
+		err := db.NewSelect().
+			Model((*model.Patient)(nil)).
+			Column("id").
+			Where("id IN (?)", bun.In(ids)).
+			WhereAllWithDeleted().
+			Scan(ctx, &existing)
+		if err != nil {
+			return inserted, collided, err
+		}
+		seen := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			seen[id] = true
+		}
+		missing := batch[:0]
+		for _, data := range batch {
+			if !seen[data.ID] {
+				missing = append(missing, data)
+			}
+		}
+		missing, err = withoutTakenIdentifiers(ctx, db, hospital, missing)
+		if err != nil {
+			return inserted, collided, err
+		}
+		collided += end - start - len(existing) - len(missing)
+		if len(missing) == 0 {
+			continue
+		}
+
+		err = db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			hns, err := format.Allocate(ctx, tx, hospital, len(missing))
+			if err != nil {
+				return err
+			}
+			for i, data := range missing {
+				data.PatientHN = hns[i]
+			}
+			_, err = tx.NewInsert().
+				Model(&missing).
+				Exec(ctx)
+			return err
+		})
+		if err != nil {
+			return inserted, collided, err
+		}
+		inserted += len(missing)
+	}
+	return inserted, collided, nil
+}
+
+// withoutTakenIdentifiers drops the patients whose national ID or passport ID a
+// patient of the hospital, or an earlier one of the list, already holds
+func withoutTakenIdentifiers(ctx context.Context, db bun.IDB, hospital string, patients []*model.Patient) ([]*model.Patient, error) {
+	indexes := make([]string, 0, len(patients))
+	for _, data := range patients {
+		if data.NationalID != "" {
+			indexes = append(indexes, model.PatientBlindIndex("national_id", string(data.NationalID)))
+		}
+		if data.PassportID != "" {
+			indexes = append(indexes, model.PatientBlindIndex("passport_id", string(data.PassportID)))
+		}
+	}
+	if len(indexes) == 0 {
+		return patients, nil
+	}
+	var existing []struct {
+		NationalIDIndex string `bun:"national_id_bidx"`
+		PassportIDIndex string `bun:"passport_id_bidx"`
+	}
+	err := db.NewSelect().
+		Model((*model.Patient)(nil)).
+		Column("national_id_bidx", "passport_id_bidx").
+		WhereAllWithDeleted().
+		Where("hospital = ?", hospital).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("national_id_bidx IN (?)", bun.In(indexes)).
+				WhereOr("passport_id_bidx IN (?)", bun.In(indexes))
+		}).
+		Scan(ctx, &existing)
+	if err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	for _, row := range existing {
+		taken["national_id:"+row.NationalIDIndex] = true
+		taken["passport_id:"+row.PassportIDIndex] = true
+	}
+
+	kept := patients[:0]
+	for _, data := range patients {
+		key := "national_id:" + model.PatientBlindIndex("national_id", string(data.NationalID))
+		if data.NationalID == "" {
+			key = "passport_id:" + model.PatientBlindIndex("passport_id", string(data.PassportID))
+		}
+		if taken[key] {
+			continue
+		}
+		taken[key] = true
+		kept = append(kept, data)
+	}
+	return kept, nil
+}
@@ -0,0 +1,13 @@
+package seeds
+
+import (
+	"app/database/rbac"
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// rbacSeed inserts the default roles and permission matrix, keeping whatever already exists
+func rbacSeed(db *bun.DB) error {
+	return rbac.Sync(context.Background(), db)
+}
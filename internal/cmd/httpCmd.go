@@ -14,6 +14,8 @@ func HttpCmd() *cobra.Command {
 		Short: "Run server on HTTP protocol",
 		Run: func(cmd *cobra.Command, args []string) {
 			r := gin.Default()
+			// let services see the request deadline and cancellation through *gin.Context
+			r.ContextWithFallback = true
 			routes.Router(r)
 			r.Run(":8080") // Start server on port 8080
 		},
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"app/config"
+	"app/database/seeds"
 	"app/internal/logger"
 	"os"
 
@@ -25,33 +26,94 @@ func Migrate() *cobra.Command {
 	}
 	cmd.AddCommand(migrateUp())
 	cmd.AddCommand(migrateDown())
+	cmd.AddCommand(migrateRollback())
+	cmd.AddCommand(migrateStatus())
+	cmd.AddCommand(migrateCreate())
 	cmd.AddCommand(migrateSeed())
 	cmd.AddCommand(migrateRefresh())
 	return cmd
 }
 
 func migrateUp() *cobra.Command {
+	var to string
 	cmd := &cobra.Command{
-		Use:  "up",
-		Args: NotReqArgs,
+		Use:   "up",
+		Short: "Apply the pending migrations",
+		Args:  NotReqArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			db := config.GetDB()
-			if err := modelUp(db); err != nil {
+			if err := modelUp(db, to); err != nil {
 				logger.Errf("%s", err)
 				os.Exit(1)
 			}
 		},
 	}
+	cmd.Flags().StringVar(&to, "to", "", "stop after this migration (timestamp or full name)")
 	return cmd
 }
 
 func migrateDown() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "down",
-		Args: NotReqArgs,
+		Use:   "down",
+		Short: "Roll back every applied migration",
+		Args:  NotReqArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			db := config.GetDB()
+			if err := modelRollback(db, 0); err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+		},
+	}
+	return cmd
+}
+
+func migrateRollback() *cobra.Command {
+	var steps int
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back the last applied migrations",
+		Args:  NotReqArgs,
 		Run: func(cmd *cobra.Command, args []string) {
+			if steps < 1 {
+				logger.Errf("steps must be at least 1")
+				os.Exit(1)
+			}
 			db := config.GetDB()
-			if err := modelDown(db); err != nil {
+			if err := modelRollback(db, steps); err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().IntVar(&steps, "steps", 1, "number of migrations to roll back")
+	return cmd
+}
+
+func migrateStatus() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Args:  NotReqArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			db := config.GetDB()
+			if err := modelStatus(db, cmd.OutOrStdout()); err != nil {
+				logger.Errf("%s", err)
+				os.Exit(1)
+			}
+		},
+	}
+	return cmd
+}
+
+func migrateCreate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create [name]",
+		Short: "Create an empty up/down migration pair",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			db := config.GetDB()
+			if err := modelCreate(db, args[0]); err != nil {
 				logger.Errf("%s", err)
 				os.Exit(1)
 			}
@@ -62,15 +124,16 @@ func migrateDown() *cobra.Command {
 
 func migrateRefresh() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "refresh",
-		Args: NotReqArgs,
+		Use:   "refresh",
+		Short: "Roll back every migration and apply them again",
+		Args:  NotReqArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			db := config.GetDB()
-			if err := modelDown(db); err != nil {
+			if err := modelRollback(db, 0); err != nil {
 				logger.Errf("%s", err)
 				os.Exit(1)
 			}
-			if err := modelUp(db); err != nil {
+			if err := modelUp(db, ""); err != nil {
 				logger.Errf("%s", err)
 				os.Exit(1)
 			}
@@ -80,16 +143,23 @@ func migrateRefresh() *cobra.Command {
 }
 
 func migrateSeed() *cobra.Command {
+	opts := seeds.DefaultOptions()
 	cmd := &cobra.Command{
-		Use:  "seed",
-		Args: NotReqArgs,
+		Use:   "seed",
+		Short: "Insert roles, hospitals and generated staff and patients",
+		Args:  NotReqArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			db := config.GetDB()
-			if err := modelSeed(db); err != nil {
+			if err := modelSeed(db, opts); err != nil {
 				logger.Errf("%s", err)
 				os.Exit(1)
 			}
 		},
 	}
+	cmd.Flags().Int64Var(&opts.Seed, "seed", opts.Seed, "seed of the generated data, the same seed gives the same rows")
+	cmd.Flags().IntVar(&opts.Hospitals, "hospitals", opts.Hospitals, "number of generated hospitals (0 to 26)")
+	cmd.Flags().IntVar(&opts.Staff, "staff", opts.Staff, "staff accounts per hospital, the first is the admin")
+	cmd.Flags().IntVar(&opts.Patients, "patients", opts.Patients, "patients per hospital")
+	cmd.Flags().StringVar(&opts.Password, "password", opts.Password, "password of every generated staff account")
 	return cmd
 }
@@ -1,60 +1,162 @@
 package cmd
 
 import (
-	"context"
 	"app/database/migrations"
 	"app/database/seeds"
 	"app/internal/logger"
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
 
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
 )
 
-func modelUp(db *bun.DB) error {
-	logger.Infof("Executing model up...")
-	for _, mod := range migrations.Models() {
-		if _, err := db.NewCreateTable().Model(mod).Exec(context.Background()); err != nil {
+// newMigrator only records a migration once it succeeded, so a failed one is retried
+func newMigrator(db *bun.DB, ms *migrate.Migrations) *migrate.Migrator {
+	return migrate.NewMigrator(db, ms, migrate.WithMarkAppliedOnSuccess(true))
+}
+
+// withLock runs fn while holding the migration lock, so concurrent deploys do not race
+func withLock(ctx context.Context, migrator *migrate.Migrator, fn func() error) error {
+	if err := migrator.Init(ctx); err != nil {
+		return err
+	}
+	if err := migrator.Lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err := migrator.Unlock(ctx); err != nil {
+			logger.Err(err)
+		}
+	}()
+	return fn()
+}
+
+// modelUp applies the pending migrations, up to and including `to` when it is set
+func modelUp(db *bun.DB, to string) error {
+	ctx := context.Background()
+	ms := migrations.Migrations
+	if to != "" {
+		var err error
+		if ms, err = migrationsUpTo(to); err != nil {
 			return err
 		}
 	}
-	return nil
+	migrator := newMigrator(db, ms)
+	return withLock(ctx, migrator, func() error {
+		logger.Infof("Executing migrations...")
+		group, err := migrator.Migrate(ctx)
+		if err != nil {
+			return err
+		}
+		if group.IsZero() {
+			logger.Infof("No pending migrations")
+			return nil
+		}
+		for _, migration := range group.Migrations {
+			logger.Infof("Migrated %s", migration)
+		}
+		return nil
+	})
 }
 
-func modelDown(db *bun.DB) error {
-	logger.Infof("Executing model down...")
-	for _, mod := range migrations.Models() {
-		if _, err := db.NewDropTable().Model(mod).Exec(context.Background()); err != nil {
+// modelRollback reverts the last `steps` applied migrations, all of them when steps is 0
+func modelRollback(db *bun.DB, steps int) error {
+	ctx := context.Background()
+	migrator := newMigrator(db, migrations.Migrations)
+	return withLock(ctx, migrator, func() error {
+		ms, err := migrator.MigrationsWithStatus(ctx)
+		if err != nil {
 			return err
 		}
+		applied := ms.Applied()
+		if steps > 0 && steps < len(applied) {
+			applied = applied[:steps]
+		}
+		if len(applied) == 0 {
+			logger.Infof("No migrations to roll back")
+			return nil
+		}
+		for i := range applied {
+			migration := &applied[i]
+			if migration.Down != nil {
+				if err := migration.Down(ctx, db, nil); err != nil {
+					return fmt.Errorf("rollback %s: %w", migration, err)
+				}
+			}
+			if err := migrator.MarkUnapplied(ctx, migration); err != nil {
+				return err
+			}
+			logger.Infof("Rolled back %s", migration)
+		}
+		return nil
+	})
+}
+
+// modelStatus prints every migration with whether and when it was applied
+func modelStatus(db *bun.DB, out io.Writer) error {
+	ctx := context.Background()
+	migrator := newMigrator(db, migrations.Migrations)
+	if err := migrator.Init(ctx); err != nil {
+		return err
+	}
+	ms, err := migrator.MigrationsWithStatus(ctx)
+	if err != nil {
+		return err
+	}
+	missing, err := migrator.MissingMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tSTATUS\tGROUP\tMIGRATED AT")
+	for _, migration := range ms {
+		if migration.IsApplied() {
+			fmt.Fprintf(w, "%s\tapplied\t%d\t%s\n", migration, migration.GroupID, migration.MigratedAt.Format("2006-01-02 15:04:05"))
+			continue
+		}
+		fmt.Fprintf(w, "%s\tpending\t-\t-\n", migration)
+	}
+	// applied in the database but no longer shipped with this build
+	for _, migration := range missing {
+		fmt.Fprintf(w, "%s\tmissing\t%d\t%s\n", migration.Name, migration.GroupID, migration.MigratedAt.Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+// modelCreate writes an empty transactional up/down migration pair
+func modelCreate(db *bun.DB, name string) error {
+	migrator := newMigrator(db, migrations.Migrations)
+	files, err := migrator.CreateTxSQLMigrations(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		logger.Infof("Created migration %s", file.Path)
 	}
 	return nil
 }
 
-func modelSeed(db *bun.DB) error {
+// migrationsUpTo returns the migrations up to and including the target, named by
+// its timestamp (20261018000001) or in full (20261018000001_initial_schema)
+func migrationsUpTo(to string) (*migrate.Migrations, error) {
+	ms := migrate.NewMigrations()
+	for _, migration := range migrations.Migrations.Sorted() {
+		ms.Add(migration)
+		if migration.Name == to || migration.String() == to {
+			return ms, nil
+		}
+	}
+	return nil, fmt.Errorf("migration %s not found", to)
+}
+
+func modelSeed(db *bun.DB, opts seeds.Options) error {
 	logger.Infof("Executing model seeding...")
-	if err := seeds.Seeds(db); err != nil {
+	if err := seeds.Seeds(db, opts); err != nil {
 		logger.Err(err)
 	}
 	return nil
 }
-
-// func modelRawBeforeQuery(db *bun.DB) error {
-// 	logger.Infof("Executing pre raw query...")
-// 	for _, mod := range migrations.RawBeforeQueryMigrate() {
-// 		_, err := db.NewRaw(mod).Exec(context.Background())
-// 		if err != nil {
-// 			return err
-// 		}
-// 	}
-// 	return nil
-// }
-
-// func modelRawAfterQuery(db *bun.DB) error {
-// 	logger.Infof("Executing post raw query...")
-// 	for _, mod := range migrations.RawAfterQueryMigrate() {
-// 		_, err := db.NewRaw(mod).Exec(context.Background())
-// 		if err != nil {
-// 			return err
-// 		}
-// 	}
-// 	return nil
-// }